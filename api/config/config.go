@@ -53,5 +53,53 @@ func Init() map[string]string {
 		config[key] = val
 	}
 
+	// Optional keys fall back to defaults at the call site when unset.
+	optionalKeys := []string{
+		// Read by the request limiter in main.go and by the importbatch limiters.
+		"RATE_LIMIT_REQUESTS",
+		"RATE_LIMIT_WINDOW",
+		"IMPORT_RATE_LIMIT_BATCHES",
+		"IMPORT_RATE_LIMIT_WINDOW",
+		"IMPORT_UNDO_RATE_LIMIT_REQUESTS",
+		"IMPORT_UNDO_RATE_LIMIT_WINDOW",
+		"TRASH_RETENTION",
+
+		"WEBAUTHN_RP_ID",
+		"WEBAUTHN_RP_NAME",
+		"WEBAUTHN_RP_ORIGINS",
+
+		"LOGIN_LOCKOUT_THRESHOLD",
+		"LOGIN_IP_LOCKOUT_THRESHOLD",
+		"LOGIN_LOCKOUT_BASE",
+		"LOGIN_LOCKOUT_MAX",
+
+		"ARGON2_MEMORY",
+		"ARGON2_TIME",
+		"ARGON2_THREADS",
+
+		"PASSWORD_MIN_LENGTH",
+		"PASSWORD_MIN_ENTROPY",
+		"PASSWORD_BREACH_CORPUS",
+
+		"JWT_KEYS",
+		"JWT_SIGNING_KID",
+		"JWT_LEGACY_UNTIL",
+
+		"OIDC_GOOGLE_ISSUER",
+		"OIDC_GOOGLE_CLIENT_ID",
+		"OIDC_GOOGLE_CLIENT_SECRET",
+		"OIDC_GOOGLE_REDIRECT_URL",
+		"OIDC_APPLE_ISSUER",
+		"OIDC_APPLE_CLIENT_ID",
+		"OIDC_APPLE_CLIENT_SECRET",
+		"OIDC_APPLE_REDIRECT_URL",
+	}
+
+	for _, key := range optionalKeys {
+		if val := os.Getenv(key); val != "" {
+			config[key] = val
+		}
+	}
+
 	return config
 }
@@ -0,0 +1,41 @@
+package constants
+
+// Actions recorded in the audit log.
+const (
+	AuditLogin           = "login"
+	AuditLoginUnlocked   = "login_unlocked"
+	AuditTokenRefresh    = "token_refresh"
+	AuditLogout          = "logout"
+	AuditLogoutAll       = "logout_all"
+	AuditEmailVerified   = "email_verified"
+	AuditEmailChanged    = "email_changed"
+	AuditPasswordChanged = "password_changed"
+	AuditPasswordReset   = "password_reset"
+	AuditMFAEnabled      = "mfa_enabled"
+	AuditMFADisabled     = "mfa_disabled"
+	AuditKeyBackupCreate = "key_backup_created"
+	AuditKeyBackupRotate = "key_backup_rotated"
+	AuditImportUndone    = "import_undone"
+
+	AuditSessionRevoked     = "session_revoked"
+	AuditAccessTokenCreated = "access_token_created"
+	AuditAccessTokenRevoked = "access_token_revoked"
+	AuditDeletionRequested  = "account_deletion_requested"
+	AuditDeletionCancelled  = "account_deletion_cancelled"
+	AuditAccountPurged      = "account_purged"
+
+	AuditAdminUserSearch         = "admin_user_search"
+	AuditAdminUserViewed         = "admin_user_viewed"
+	AuditAdminVerificationResent = "admin_verification_resent"
+	AuditAdminUserVerified       = "admin_user_verified"
+	AuditAdminUserDisabled       = "admin_user_disabled"
+	AuditAdminUserEnabled        = "admin_user_enabled"
+	AuditAdminDeletionScheduled  = "admin_deletion_scheduled"
+	AuditRoleChanged             = "role_changed"
+)
+
+// Outcomes of an audited action.
+const (
+	AuditSuccess = "success"
+	AuditFailure = "failure"
+)
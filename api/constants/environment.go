@@ -10,10 +10,32 @@ const (
 	ImportRateLimitWindow       = "IMPORT_RATE_LIMIT_WINDOW"
 	ImportUndoRateLimitRequests = "IMPORT_UNDO_RATE_LIMIT_REQUESTS"
 	ImportUndoRateLimitWindow   = "IMPORT_UNDO_RATE_LIMIT_WINDOW"
+	TrashRetention              = "TRASH_RETENTION"
 	APIBaseURL                  = "API_BASE_URL"
 	ResendAPIKey                = "RESEND_API_KEY"
 	EmailFrom                   = "EMAIL_FROM"
 	ResendWebhookSecret         = "RESEND_WEBHOOK_SECRET"
+	WebAuthnRPID                = "WEBAUTHN_RP_ID"
+	WebAuthnRPName              = "WEBAUTHN_RP_NAME"
+	WebAuthnRPOrigins           = "WEBAUTHN_RP_ORIGINS"
+	LoginLockoutThreshold       = "LOGIN_LOCKOUT_THRESHOLD"
+	LoginIPLockoutThreshold     = "LOGIN_IP_LOCKOUT_THRESHOLD"
+	LoginLockoutBase            = "LOGIN_LOCKOUT_BASE"
+	LoginLockoutMax             = "LOGIN_LOCKOUT_MAX"
+	Argon2Memory                = "ARGON2_MEMORY"
+	Argon2Time                  = "ARGON2_TIME"
+	Argon2Threads               = "ARGON2_THREADS"
+	PasswordMinLength           = "PASSWORD_MIN_LENGTH"
+	PasswordMinEntropy          = "PASSWORD_MIN_ENTROPY"
+	PasswordBreachCorpus        = "PASSWORD_BREACH_CORPUS"
+	OIDCGoogleIssuer            = "OIDC_GOOGLE_ISSUER"
+	OIDCGoogleClientID          = "OIDC_GOOGLE_CLIENT_ID"
+	OIDCGoogleClientSecret      = "OIDC_GOOGLE_CLIENT_SECRET"
+	OIDCGoogleRedirectURL       = "OIDC_GOOGLE_REDIRECT_URL"
+	OIDCAppleIssuer             = "OIDC_APPLE_ISSUER"
+	OIDCAppleClientID           = "OIDC_APPLE_CLIENT_ID"
+	OIDCAppleClientSecret       = "OIDC_APPLE_CLIENT_SECRET"
+	OIDCAppleRedirectURL        = "OIDC_APPLE_REDIRECT_URL"
 )
 
 const (
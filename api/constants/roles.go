@@ -0,0 +1,7 @@
+package constants
+
+// Values of users.role.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
@@ -0,0 +1,19 @@
+package constants
+
+// Scopes that can be granted to personal access tokens.
+const (
+	ScopeTransactionsRead  = "transactions:read"
+	ScopeTransactionsWrite = "transactions:write"
+	ScopeCategoriesRead    = "categories:read"
+	ScopeImportWrite       = "import:write"
+	ScopeKeysRead          = "keys:read"
+)
+
+// AccessTokenScopes lists every scope a token may be created with.
+var AccessTokenScopes = []string{
+	ScopeTransactionsRead,
+	ScopeTransactionsWrite,
+	ScopeCategoriesRead,
+	ScopeImportWrite,
+	ScopeKeysRead,
+}
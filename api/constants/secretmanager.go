@@ -11,10 +11,13 @@ const (
 	DbPass       = "PASSWORD"
 )
 const (
-	JWT_SECRET     = "JWT_SECRET"
-	REFRESH_SECRET = "REFRESH_SECRET"
-	JWT_TTL        = "JWT_TTL"
-	REFRESH_TTL    = "REFRESH_TTL"
+	JWT_SECRET       = "JWT_SECRET"
+	REFRESH_SECRET   = "REFRESH_SECRET"
+	JWT_TTL          = "JWT_TTL"
+	REFRESH_TTL      = "REFRESH_TTL"
+	JWT_KEYS         = "JWT_KEYS"
+	JWT_SIGNING_KID  = "JWT_SIGNING_KID"
+	JWT_LEGACY_UNTIL = "JWT_LEGACY_UNTIL"
 )
 
 const (
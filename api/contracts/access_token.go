@@ -0,0 +1,28 @@
+package contracts
+
+import (
+	"context"
+	"time"
+
+	"finlog-api/api/entities"
+)
+
+// AccessTokenRepository stores personal access tokens.
+type AccessTokenRepository interface {
+	CreateToken(ctx context.Context, token *entities.PersonalAccessToken) (int64, error)
+	ListTokens(ctx context.Context, userID int64) ([]entities.PersonalAccessToken, error)
+	CountActiveTokens(ctx context.Context, userID int64, now time.Time) (int, error)
+	FindTokenByHash(ctx context.Context, tokenHash string) (*entities.PersonalAccessToken, error)
+	FindUser(ctx context.Context, userID int64) (*entities.User, error)
+	TouchToken(ctx context.Context, id int64, usedAt, staleBefore time.Time) error
+	RevokeToken(ctx context.Context, userID, id int64, revokedAt time.Time) (bool, error)
+}
+
+// AccessTokenService manages personal access tokens and authenticates requests
+// made with them.
+type AccessTokenService interface {
+	Create(ctx context.Context, userID int64, name string, scopes []string, expiresAt *time.Time) (string, *entities.PersonalAccessToken, error)
+	List(ctx context.Context, userID int64) ([]entities.PersonalAccessToken, error)
+	Revoke(ctx context.Context, userID, id int64) error
+	Authenticate(ctx context.Context, rawToken string) (*entities.PersonalAccessToken, *entities.User, error)
+}
@@ -0,0 +1,27 @@
+package contracts
+
+import (
+	"context"
+	"time"
+
+	"finlog-api/api/entities"
+)
+
+// AccountRepository manages the deletion lifecycle of user accounts.
+type AccountRepository interface {
+	FindUser(ctx context.Context, userID int64) (*entities.User, error)
+	FindByCancelToken(ctx context.Context, tokenHash string) (*entities.User, error)
+	MarkDeletionRequested(ctx context.Context, userID int64, tokenHash string, requestedAt time.Time) (bool, error)
+	CancelDeletion(ctx context.Context, userID int64, tokenHash string) (bool, error)
+	ListDeletionsDue(ctx context.Context, requestedBefore time.Time, limit int) ([]entities.User, error)
+	Purge(ctx context.Context, userID int64, requestedBefore time.Time) (bool, error)
+}
+
+// AccountService handles self-service account deletion.
+type AccountService interface {
+	RequestDeletion(ctx context.Context, userID int64, password, code string) (time.Time, error)
+	ScheduleDeletion(ctx context.Context, userID int64) (time.Time, error)
+	CancelDeletion(ctx context.Context, token string) error
+	PurgeDueDeletions(ctx context.Context) (int, error)
+	RunPurgeWorker(ctx context.Context, interval time.Duration)
+}
@@ -0,0 +1,42 @@
+package contracts
+
+import (
+	"context"
+	"time"
+
+	"finlog-api/api/entities"
+)
+
+// AdminUserDetail is what support sees about one account.
+type AdminUserDetail struct {
+	User         *entities.User              `json:"user"`
+	Emails       []entities.EmailMessage     `json:"emails"`
+	Suppressions []entities.EmailSuppression `json:"suppressions"`
+}
+
+// AdminRepository reads and updates accounts for support staff.
+type AdminRepository interface {
+	SearchUsers(ctx context.Context, emailPrefix string, limit int) ([]entities.User, error)
+	FindUser(ctx context.Context, userID int64) (*entities.User, error)
+	FindUserByEmail(ctx context.Context, email string) (*entities.User, error)
+	ListEmailMessages(ctx context.Context, email string, limit int) ([]entities.EmailMessage, error)
+	ListEmailSuppressions(ctx context.Context, email string) ([]entities.EmailSuppression, error)
+	ForceVerify(ctx context.Context, userID int64) (bool, error)
+	DisableUser(ctx context.Context, userID int64, at time.Time) (bool, error)
+	EnableUser(ctx context.Context, userID int64) (bool, error)
+	SetRole(ctx context.Context, userID int64, role string) error
+}
+
+// AdminService backs the support endpoints. Every action taking an actorID is
+// written to the audit log under that admin.
+type AdminService interface {
+	CurrentRole(ctx context.Context, userID int64) (string, error)
+	SearchUsers(ctx context.Context, actorID int64, email string) ([]entities.User, error)
+	GetUser(ctx context.Context, actorID, userID int64) (*AdminUserDetail, error)
+	ResendVerification(ctx context.Context, actorID, userID int64) error
+	ForceVerify(ctx context.Context, actorID, userID int64) error
+	DisableUser(ctx context.Context, actorID, userID int64) error
+	EnableUser(ctx context.Context, actorID, userID int64) error
+	ScheduleDeletion(ctx context.Context, actorID, userID int64) (time.Time, error)
+	PromoteToAdmin(ctx context.Context, email string) error
+}
@@ -3,6 +3,8 @@ package contracts
 import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/rs/zerolog"
+
+	"finlog-api/api/jwtkeys"
 )
 
 type App struct {
@@ -11,4 +13,5 @@ type App struct {
 	Fiber    *fiber.App
 	Logger   *zerolog.Logger
 	Services *Services
+	JWTKeys  *jwtkeys.Keyset
 }
@@ -0,0 +1,42 @@
+package contracts
+
+import (
+	"context"
+	"time"
+
+	"finlog-api/api/entities"
+)
+
+// AuditFilter narrows an audit log search. Results are newest first; BeforeID
+// continues from the last event of the previous page.
+type AuditFilter struct {
+	UserID    *int64
+	Action    string
+	IPAddress string
+	Since     *time.Time
+	Until     *time.Time
+	BeforeID  int64
+	Limit     int
+}
+
+// AuditRepository appends to and reads the audit log. There is deliberately no
+// way to change or remove an event.
+type AuditRepository interface {
+	CreateEvent(ctx context.Context, event *entities.AuditEvent) error
+	ListEvents(ctx context.Context, filter AuditFilter) ([]entities.AuditEvent, error)
+}
+
+// AuditService records security events and serves them to users and support.
+type AuditService interface {
+	// Record stores an event for userID, taking the client IP and user agent from
+	// ctx. Failures are logged rather than returned so auditing never breaks the
+	// action being audited.
+	Record(ctx context.Context, userID int64, action, outcome, detail string)
+	// RecordAs stores an event performed by actorID on another account, such as a
+	// support action. An actorID of 0 stands for an operator on the command line.
+	RecordAs(ctx context.Context, actorID, userID int64, action, outcome, detail string)
+	// ListForUser and Search return a page of events, newest first, and the
+	// BeforeID of the next page, or 0 on the last page.
+	ListForUser(ctx context.Context, userID, beforeID int64, limit int) ([]entities.AuditEvent, int64, error)
+	Search(ctx context.Context, filter AuditFilter) ([]entities.AuditEvent, int64, error)
+}
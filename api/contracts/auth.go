@@ -14,13 +14,112 @@ type AuthRepository interface {
 	UpdateVerificationToken(ctx context.Context, userID int64, token *string, expiresAt *time.Time) error
 	FindByVerificationToken(ctx context.Context, token string) (*entities.User, error)
 	MarkUserAsVerified(ctx context.Context, userID int64) error
+	UpdatePasswordResetToken(ctx context.Context, userID int64, token *string, expiresAt *time.Time) error
+	FindByPasswordResetToken(ctx context.Context, token string) (*entities.User, error)
+	ResetPassword(ctx context.Context, userID int64, token string, hashedPassword string) (bool, error)
+	UpdatePassword(ctx context.Context, userID int64, hashedPassword string) error
+	SetPendingEmail(ctx context.Context, userID int64, email *string, token *string, expiresAt *time.Time) error
+	ConfirmPendingEmail(ctx context.Context, userID int64, email string) (bool, error)
+}
+
+// RefreshTokenRepository persists hashed refresh tokens grouped by login family.
+type RefreshTokenRepository interface {
+	CreateRefreshToken(ctx context.Context, token *entities.RefreshToken) (int64, error)
+	FindRefreshTokenByHash(ctx context.Context, tokenHash string) (*entities.RefreshToken, error)
+	MarkRefreshTokenRotated(ctx context.Context, id int64) (bool, error)
+	RevokeRefreshTokenFamily(ctx context.Context, userID int64, familyID string) error
+	RevokeUserRefreshTokens(ctx context.Context, userID int64) error
+	RevokeOtherRefreshTokenFamilies(ctx context.Context, userID int64, keepFamilyID string) error
+}
+
+// SessionRepository tracks the device behind each refresh token family.
+type SessionRepository interface {
+	UpsertSession(ctx context.Context, session *entities.Session) error
+	TouchSession(ctx context.Context, id int64, seenAt time.Time, client entities.ClientInfo) error
+	FindSessionByFamily(ctx context.Context, familyID string) (*entities.Session, error)
+	FindSession(ctx context.Context, userID, id int64) (*entities.Session, error)
+	ListActiveSessions(ctx context.Context, userID int64, seenSince time.Time) ([]entities.Session, error)
+}
+
+// LoginThrottleRepository keeps failed-login counters in the database so every
+// container enforces the same limits.
+type LoginThrottleRepository interface {
+	FindLoginThrottle(ctx context.Context, scope, key string) (*entities.LoginThrottle, error)
+	RecordLoginFailure(ctx context.Context, scope, key string, at, resetBefore time.Time) (int, error)
+	LockLogin(ctx context.Context, scope, key string, until time.Time, unlockUserID *int64, unlockToken *string, unlockExpiresAt *time.Time) error
+	ClearLoginThrottle(ctx context.Context, scope, key string) error
+	// UnlockLoginByToken reports the user the unlock token was issued to, or false
+	// when no pending lock matches it.
+	UnlockLoginByToken(ctx context.Context, tokenHash string, now time.Time) (int64, bool, error)
+}
+
+// OIDCRepository stores federated identities and pending OpenID Connect logins.
+type OIDCRepository interface {
+	CreateOIDCState(ctx context.Context, state *entities.OIDCState) error
+	ConsumeOIDCState(ctx context.Context, id, provider string) (*entities.OIDCState, error)
+	FindIdentity(ctx context.Context, provider, subject string) (*entities.UserIdentity, error)
+	CreateIdentity(ctx context.Context, identity *entities.UserIdentity) (int64, error)
+	TouchIdentity(ctx context.Context, id int64, email string, at time.Time) error
+}
+
+// MagicLinkRepository stores emailed sign-in links.
+type MagicLinkRepository interface {
+	CreateMagicLink(ctx context.Context, link *entities.MagicLink) error
+	CountMagicLinksSince(ctx context.Context, userID int64, since time.Time) (int, error)
+	FindMagicLink(ctx context.Context, tokenHash string) (*entities.MagicLink, error)
+	ConsumeMagicLink(ctx context.Context, id int64, now time.Time) (bool, error)
+}
+
+// MFARepository persists TOTP enrollment and hashed recovery codes.
+type MFARepository interface {
+	SetMFASecret(ctx context.Context, userID int64, secret *string) error
+	EnableMFA(ctx context.Context, userID int64, step int64) error
+	DisableMFA(ctx context.Context, userID int64) error
+	UpdateMFALastStep(ctx context.Context, userID int64, step int64) (bool, error)
+	ReplaceRecoveryCodes(ctx context.Context, userID int64, codeHashes []string) error
+	UseRecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error)
+}
+
+// PasskeyRepository persists WebAuthn credentials and pending ceremony state.
+type PasskeyRepository interface {
+	CreatePasskey(ctx context.Context, passkey *entities.Passkey) (int64, error)
+	ListPasskeys(ctx context.Context, userID int64) ([]entities.Passkey, error)
+	FindPasskeyByCredentialID(ctx context.Context, credentialID []byte) (*entities.Passkey, error)
+	UpdatePasskeyUsage(ctx context.Context, id int64, signCount uint32, credential string) error
+	DeletePasskey(ctx context.Context, userID, id int64) (bool, error)
+	CreateWebAuthnSession(ctx context.Context, session *entities.WebAuthnSession) error
+	ConsumeWebAuthnSession(ctx context.Context, id, ceremony string) (*entities.WebAuthnSession, error)
 }
 
 type AuthService interface {
 	Login(ctx context.Context, email, password string) (string, string, *entities.User, error)
 	Register(ctx context.Context, email, password string) (*entities.User, error)
 	Refresh(ctx context.Context, refreshToken string) (string, string, *entities.User, error)
-	Logout(ctx context.Context, userID int64) error
+	Logout(ctx context.Context, userID int64, familyID string) error
+	LogoutAll(ctx context.Context, userID int64) error
 	VerifyEmail(ctx context.Context, token string) (*entities.User, error)
 	ResendVerification(ctx context.Context, email string) error
+	RequestPasswordReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, newPassword string) error
+	ChangePassword(ctx context.Context, userID int64, familyID, currentPassword, newPassword string) error
+	ChangeEmail(ctx context.Context, userID int64, newEmail, password string) error
+	LoginMFA(ctx context.Context, challengeToken, code string) (string, string, *entities.User, error)
+	EnrollMFA(ctx context.Context, userID int64) (string, string, error)
+	ConfirmMFA(ctx context.Context, userID int64, code string) ([]string, error)
+	DisableMFA(ctx context.Context, userID int64, password, code string) error
+	Reauthenticate(ctx context.Context, userID int64, password, code string) error
+	UnlockLogin(ctx context.Context, token string) error
+	BeginOIDCLogin(ctx context.Context, provider string) (string, error)
+	FinishOIDCLogin(ctx context.Context, provider, state, code string) (string, string, *entities.User, error)
+	RequestMagicLink(ctx context.Context, email string) (string, error)
+	ConsumeMagicLink(ctx context.Context, token, deviceToken string, confirmed bool) (string, string, *entities.User, error)
+	BeginPasskeyRegistration(ctx context.Context, userID int64) (string, interface{}, error)
+	FinishPasskeyRegistration(ctx context.Context, userID int64, sessionID, name string, response []byte) (*entities.Passkey, error)
+	BeginPasskeyLogin(ctx context.Context) (string, interface{}, error)
+	FinishPasskeyLogin(ctx context.Context, sessionID string, response []byte) (string, string, *entities.User, error)
+	ListPasskeys(ctx context.Context, userID int64) ([]entities.Passkey, error)
+	DeletePasskey(ctx context.Context, userID, id int64) error
+	ListSessions(ctx context.Context, userID int64, currentFamilyID string) ([]entities.Session, error)
+	RevokeSession(ctx context.Context, userID, sessionID int64) error
+	ValidateSession(ctx context.Context, userID int64, familyID string) error
 }
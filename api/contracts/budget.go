@@ -4,12 +4,13 @@ import (
 	"context"
 
 	"finlog-api/api/entities"
+	"finlog-api/api/period"
 )
 
 type BudgetRepository interface {
-	GetMonthly(ctx context.Context, userID int64, year int, month int) (*entities.Budget, error)
+	Get(ctx context.Context, userID int64, within period.Range) (*entities.Budget, error)
 }
 
 type BudgetService interface {
-	GetMonthly(ctx context.Context, userID int64, year int, month int) (*entities.Budget, error)
+	Get(ctx context.Context, userID int64, within period.Range) (*entities.Budget, error)
 }
@@ -14,5 +14,6 @@ type EmailRepository interface {
 
 type EmailService interface {
 	SendEmail(to string, variables map[string]interface{}) error
+	SendTemplate(to, templateID string, variables map[string]interface{}) error
 	HandleWebhook(ctx context.Context, body request.ResendWebhookPayload, payload []byte) error
 }
\ No newline at end of file
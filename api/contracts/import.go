@@ -8,7 +8,9 @@ import (
 )
 
 type ImportRepository interface {
-	InsertBatch(ctx context.Context, userID int64, items []entities.ImportedTransaction) error
+	// InsertBatch skips items whose UUID is already stored and returns how many
+	// were inserted.
+	InsertBatch(ctx context.Context, userID int64, items []entities.ImportedTransaction) (int, error)
 	ListBatches(ctx context.Context, userID int64) ([]entities.ImportBatch, error)
 	DeleteBatch(ctx context.Context, userID, batchID int64) (int64, error)
 }
@@ -0,0 +1,34 @@
+package contracts
+
+import (
+	"context"
+
+	"finlog-api/api/entities"
+	"finlog-api/api/models/request"
+)
+
+// PreferenceChanges holds the preferences an update sets. Nil fields keep the
+// stored value.
+type PreferenceChanges struct {
+	Locale         *string
+	Currency       *string
+	Timezone       *string
+	FirstDayOfWeek *int
+	CycleStartDay  *int
+}
+
+type ProfileRepository interface {
+	FindUser(ctx context.Context, userID int64) (*entities.User, error)
+	FindPreferences(ctx context.Context, userID int64) (*entities.UserPreferences, error)
+	// SaveProfile applies changes, and the name when not nil, together and
+	// returns the stored preferences. prefs is inserted when the user has no
+	// preferences row yet.
+	SaveProfile(ctx context.Context, userID int64, name *string, prefs *entities.UserPreferences, changes PreferenceChanges) (*entities.UserPreferences, error)
+}
+
+// ProfileService manages the current user's display name and preferences.
+type ProfileService interface {
+	Get(ctx context.Context, userID int64) (*entities.Profile, error)
+	Preferences(ctx context.Context, userID int64) (*entities.UserPreferences, error)
+	Update(ctx context.Context, userID int64, body request.UpdateProfile) (*entities.Profile, error)
+}
@@ -8,4 +8,10 @@ type Services struct {
 	KeyBackup    KeyBackupService
 	Import       ImportService
 	Email        EmailService
+	Account      AccountService
+	AccessTokens AccessTokenService
+	Audit        AuditService
+	Admin        AdminService
+	Profile      ProfileService
+	Sync         SyncService
 }
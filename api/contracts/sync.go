@@ -0,0 +1,38 @@
+package contracts
+
+import (
+	"context"
+
+	"finlog-api/api/entities"
+)
+
+// SyncTombstones lists the ids of records deleted since the client's token.
+type SyncTombstones struct {
+	Transactions []int64 `json:"transactions"`
+	Categories   []int64 `json:"categories"`
+	KeyBackups   []int64 `json:"key_backups"`
+}
+
+// SyncPage holds the current state of every record changed since a token. Token
+// is passed back as since for the next call; HasMore asks for it right away.
+type SyncPage struct {
+	Transactions []entities.Transaction          `json:"transactions"`
+	Categories   []entities.Category             `json:"categories"`
+	KeyBackups   []entities.UserEncryptedDataKey `json:"key_backups"`
+	Deleted      SyncTombstones                  `json:"deleted"`
+	Token        string                          `json:"token"`
+	HasMore      bool                            `json:"has_more"`
+}
+
+type SyncRepository interface {
+	ListChanges(ctx context.Context, userID, afterSeq int64, limit int) ([]entities.Change, error)
+	CurrentSeq(ctx context.Context, userID int64) (int64, error)
+	FindTransactions(ctx context.Context, userID int64, ids []int64) ([]entities.Transaction, error)
+	FindCategories(ctx context.Context, userID int64, ids []int64) ([]entities.Category, error)
+	FindKeyBackups(ctx context.Context, userID int64, ids []int64) ([]entities.UserEncryptedDataKey, error)
+}
+
+// SyncService lets offline-first clients fetch only what changed.
+type SyncService interface {
+	Changes(ctx context.Context, userID int64, since string, limit int) (*SyncPage, error)
+}
@@ -6,11 +6,33 @@ import (
 
 	"finlog-api/api/entities"
 	"finlog-api/api/models/request"
+	"finlog-api/api/period"
 )
 
+// TransactionFilter selects a page of a user's transactions, newest first. Empty
+// fields do not filter; To is exclusive.
+type TransactionFilter struct {
+	From       *time.Time
+	To         *time.Time
+	CategoryID *int64
+	IsExpense  *bool
+	// Trashed pages through deleted transactions instead of live ones.
+	Trashed bool
+	// Cursor is the next_cursor of the previous page.
+	Cursor string
+	Limit  int
+}
+
+// TransactionCursor is the position after which the next page starts.
+type TransactionCursor struct {
+	OccurredAt time.Time
+	ID         int64
+}
+
 type TransactionRepository interface {
-	List(ctx context.Context, userID int64, year int, month int) ([]entities.Transaction, error)
-	ListRecent(ctx context.Context, userID int64, year int, month int, limit int) ([]entities.Transaction, error)
+	List(ctx context.Context, userID int64, within period.Range) ([]entities.Transaction, error)
+	ListRecent(ctx context.Context, userID int64, within period.Range, limit int) ([]entities.Transaction, error)
+	Search(ctx context.Context, userID int64, filter TransactionFilter, after *TransactionCursor) ([]entities.Transaction, error)
 	Create(ctx context.Context, tx *entities.Transaction) (int64, error)
 	Update(ctx context.Context, tx *entities.Transaction) error
 	BulkUpdateNotes(ctx context.Context, userID int64, ids []int64, notes string) error
@@ -18,17 +40,32 @@ type TransactionRepository interface {
 	BulkUpdateDate(ctx context.Context, userID int64, ids []int64, date time.Time) error
 	Delete(ctx context.Context, userID int64, id int64) error
 	BulkDelete(ctx context.Context, userID int64, ids []int64) error
+	Restore(ctx context.Context, userID int64, ids []int64) error
+	// PurgeTrash permanently removes up to limit transactions trashed before the
+	// cutoff, across all users.
+	PurgeTrash(ctx context.Context, before time.Time, limit int) (int64, error)
 	FindByID(ctx context.Context, id, userID int64) (*entities.Transaction, error)
+	FindByUUID(ctx context.Context, uuid string, userID int64) (*entities.Transaction, error)
 }
 
 type TransactionService interface {
-	GetTransactions(ctx context.Context, userID int64, year int, month int) ([]entities.Transaction, error)
-	GetRecentTransactions(ctx context.Context, userID int64, year int, month int) ([]entities.Transaction, error)
-	CreateTransaction(ctx context.Context, userID int64, input request.CreateTransaction) (*entities.Transaction, error)
-	UpdateTransaction(ctx context.Context, userID int64, id int64, input request.CreateTransaction) error
+	GetTransactions(ctx context.Context, userID int64, within period.Range) ([]entities.Transaction, error)
+	GetRecentTransactions(ctx context.Context, userID int64, within period.Range) ([]entities.Transaction, error)
+	SearchTransactions(ctx context.Context, userID int64, filter TransactionFilter) ([]entities.Transaction, string, error)
+	// CreateTransaction reports false when the UUID was already used and the
+	// existing transaction is returned instead.
+	CreateTransaction(ctx context.Context, userID int64, input request.CreateTransaction) (*entities.Transaction, bool, error)
+	// ResolveTransactionID accepts either a transaction id or its UUID.
+	ResolveTransactionID(ctx context.Context, userID int64, ref string) (int64, error)
+	GetTransaction(ctx context.Context, userID int64, id int64) (*entities.Transaction, error)
+	UpdateTransaction(ctx context.Context, userID int64, id int64, input request.CreateTransaction) (*entities.Transaction, error)
 	UpdateNotes(ctx context.Context, userID int64, ids []int64, notes string) error
 	UpdateAmount(ctx context.Context, userID int64, ids []int64, amount int64) error
 	UpdateDate(ctx context.Context, userID int64, ids []int64, date time.Time) error
 	DeleteTransaction(ctx context.Context, userID int64, id int64) error
 	DeleteTransactions(ctx context.Context, userID int64, ids []int64) error
+	RestoreTransaction(ctx context.Context, userID int64, id int64) error
+	RestoreTransactions(ctx context.Context, userID int64, ids []int64) error
+	PurgeTrash(ctx context.Context) (int64, error)
+	RunTrashPurgeWorker(ctx context.Context, interval time.Duration)
 }
@@ -0,0 +1,153 @@
+// Package dbtest runs repository tests against a real MySQL database. Tests are
+// skipped unless FINLOG_TEST_DSN points at a database they may write to, such as
+// "root:secret@tcp(localhost:3306)/finlog_test?parseTime=true&loc=Asia%2FJakarta".
+package dbtest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+
+	"finlog-api/migrate"
+)
+
+const dsnEnv = "FINLOG_TEST_DSN"
+
+// Open connects to the test database and applies every migration.
+func Open(t *testing.T) *sqlx.DB {
+	t.Helper()
+	dsn := os.Getenv(dsnEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", dsnEnv)
+	}
+	db, err := sqlx.Connect("mysql", dsn)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	_, file, _, _ := runtime.Caller(0)
+	root := filepath.Join(filepath.Dir(file), "..", "..", "..")
+	if err := migrate.NewMigrator(db.DB, filepath.Join(root, "migrations")).RunMigrations(); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+// Seed creates users with categories and transactions spread over the past
+// three years, and removes them when the test ends. It returns the user ids.
+func Seed(t *testing.T, db *sqlx.DB, users, transactionsPerUser int) []int64 {
+	t.Helper()
+	run := time.Now().UnixNano()
+	ids := make([]int64, 0, users)
+	t.Cleanup(func() {
+		for _, id := range ids {
+			db.Exec("DELETE FROM transactions WHERE user_id = ?", id)
+			db.Exec("DELETE FROM categories WHERE user_id = ?", id)
+			db.Exec("DELETE FROM users WHERE id = ?", id)
+		}
+	})
+
+	now := time.Now().UTC().Truncate(time.Second)
+	for u := 0; u < users; u++ {
+		res, err := db.Exec(
+			"INSERT INTO users (email, name, role, password, is_verified) VALUES (?, ?, 'user', '', 1)",
+			fmt.Sprintf("seed-%d-%d@example.com", run, u), "seed",
+		)
+		if err != nil {
+			t.Fatalf("seed user: %v", err)
+		}
+		userID, _ := res.LastInsertId()
+		ids = append(ids, userID)
+
+		var categories []int64
+		for c := 0; c < 6; c++ {
+			res, err := db.Exec(
+				"INSERT INTO categories (user_id, name, is_expense) VALUES (?, ?, ?)",
+				userID, fmt.Sprintf("category-%d", c), c%2 == 0,
+			)
+			if err != nil {
+				t.Fatalf("seed category: %v", err)
+			}
+			categoryID, _ := res.LastInsertId()
+			categories = append(categories, categoryID)
+		}
+
+		rows := make([]string, 0, transactionsPerUser)
+		args := make([]interface{}, 0, transactionsPerUser*5)
+		step := 3 * 365 * 24 * time.Hour / time.Duration(max(transactionsPerUser, 1))
+		for i := 0; i < transactionsPerUser; i++ {
+			occurredAt := now.Add(-time.Duration(i) * step)
+			category := i % len(categories)
+			rows = append(rows, "(?, ?, 'c', 'n', 't', ?, ?)")
+			args = append(args, userID, categories[category], occurredAt, category%2 == 0)
+		}
+		query := "INSERT INTO transactions (user_id, category_id, payload_ciphertext, payload_nonce, payload_tag, occurred_at, is_expense) VALUES " +
+			strings.Join(rows, ", ")
+		if _, err := db.Exec(query, args...); err != nil {
+			t.Fatalf("seed transactions: %v", err)
+		}
+	}
+	if _, err := db.Exec("ANALYZE TABLE transactions, categories"); err != nil {
+		t.Fatalf("analyze: %v", err)
+	}
+	return ids
+}
+
+// Plan is one row of EXPLAIN output.
+type Plan struct {
+	Table string
+	Type  string
+	Key   string
+	Rows  int64
+	Extra string
+}
+
+// Explain returns the plan MySQL chooses for query with args, keyed by table
+// name or alias.
+func Explain(t *testing.T, db *sqlx.DB, query string, args ...interface{}) map[string]Plan {
+	t.Helper()
+	rows, err := db.Queryx("EXPLAIN "+query, args...)
+	if err != nil {
+		t.Fatalf("explain: %v", err)
+	}
+	defer rows.Close()
+
+	plans := map[string]Plan{}
+	for rows.Next() {
+		row := map[string]interface{}{}
+		if err := rows.MapScan(row); err != nil {
+			t.Fatalf("scan plan: %v", err)
+		}
+		plan := Plan{
+			Table: text(row["table"]),
+			Type:  text(row["type"]),
+			Key:   text(row["key"]),
+			Extra: text(row["Extra"]),
+		}
+		fmt.Sscan(text(row["rows"]), &plan.Rows)
+		plans[plan.Table] = plan
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("read plan: %v", err)
+	}
+	return plans
+}
+
+func text(value interface{}) string {
+	switch v := value.(type) {
+	case []byte:
+		return string(v)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
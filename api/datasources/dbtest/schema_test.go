@@ -0,0 +1,22 @@
+package dbtest
+
+import (
+	"testing"
+
+	"finlog-api/api/entities"
+)
+
+// TestUserScansAfterMigrations catches a migration that adds a users column
+// without a matching entity field, which breaks every SELECT * on users.
+func TestUserScansAfterMigrations(t *testing.T) {
+	db := Open(t)
+	userID := Seed(t, db, 1, 1)[0]
+
+	var user entities.User
+	if err := db.Get(&user, "SELECT * FROM users WHERE id = ? LIMIT 1", userID); err != nil {
+		t.Fatalf("scan user: %v", err)
+	}
+	if user.ID != userID {
+		t.Fatalf("expected user %d, got %d", userID, user.ID)
+	}
+}
@@ -1,8 +1,10 @@
 package datasources
 
 import (
+	"errors"
 	"os"
 
+	"github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
 	zero "github.com/rs/zerolog/log"
 )
@@ -34,3 +36,9 @@ func PrepareNamed(db *sqlx.DB, query string) *sqlx.NamedStmt {
 	}
 	return s
 }
+
+// IsDuplicateKey reports whether err is a unique key violation.
+func IsDuplicateKey(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1062
+}
@@ -0,0 +1,21 @@
+package entities
+
+import "time"
+
+// PersonalAccessToken lets scripts call the API without a login session. Only the
+// hash of the token is stored; Prefix is kept so users can tell tokens apart.
+// Scopes is a comma-separated list.
+type PersonalAccessToken struct {
+	ID         int64      `db:"id" json:"id"`
+	UserID     int64      `db:"user_id" json:"-"`
+	Name       string     `db:"name" json:"name"`
+	Prefix     string     `db:"token_prefix" json:"prefix"`
+	TokenHash  string     `db:"token_hash" json:"-"`
+	Scopes     string     `db:"scopes" json:"-"`
+	ExpiresAt  *time.Time `db:"expires_at" json:"expires_at"`
+	LastUsedAt *time.Time `db:"last_used_at" json:"last_used_at"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+	RevokedAt  *time.Time `db:"revoked_at" json:"-"`
+
+	ScopeList []string `db:"-" json:"scopes"`
+}
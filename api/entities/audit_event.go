@@ -0,0 +1,19 @@
+package entities
+
+import "time"
+
+// AuditEvent records a security-relevant action. UserID is the account it
+// concerns and ActorID whoever performed it; both are empty for failed logins
+// with an unknown email. Rows are never updated or deleted, and outlive the
+// account so support can still investigate after it is purged.
+type AuditEvent struct {
+	ID        int64     `db:"id" json:"id"`
+	UserID    *int64    `db:"user_id" json:"user_id,omitempty"`
+	ActorID   *int64    `db:"actor_id" json:"actor_id,omitempty"`
+	Action    string    `db:"action" json:"action"`
+	Outcome   string    `db:"outcome" json:"outcome"`
+	Detail    *string   `db:"detail" json:"detail,omitempty"`
+	IPAddress *string   `db:"ip_address" json:"ip_address,omitempty"`
+	UserAgent *string   `db:"user_agent" json:"user_agent,omitempty"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
@@ -7,4 +7,7 @@ type Budget struct {
 	Income      int64      `db:"income" json:"income"`
 	Expense     int64      `db:"expense" json:"expense"`
 	LastUpdated *time.Time `db:"last_updated" json:"last_updated,omitempty"`
+	// PeriodStart and PeriodEnd are the resolved bounds; the end is exclusive.
+	PeriodStart time.Time `db:"-" json:"period_start"`
+	PeriodEnd   time.Time `db:"-" json:"period_end"`
 }
@@ -12,4 +12,5 @@ type Category struct {
 	IsActive  bool      `db:"is_active" json:"isActive"`
 	CreatedAt time.Time `db:"created_at" json:"created_at"`
 	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+	Version   int64     `db:"version" json:"version"`
 }
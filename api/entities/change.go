@@ -0,0 +1,15 @@
+package entities
+
+import "time"
+
+// Change is one entry of a user's change log. Seq numbers are per user, start at
+// 1 and have no gaps.
+type Change struct {
+	ID        int64     `db:"id" json:"-"`
+	UserID    int64     `db:"user_id" json:"-"`
+	Seq       int64     `db:"seq" json:"seq"`
+	Entity    string    `db:"entity" json:"entity"`
+	EntityID  int64     `db:"entity_id" json:"entity_id"`
+	Op        string    `db:"op" json:"op"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
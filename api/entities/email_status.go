@@ -0,0 +1,26 @@
+package entities
+
+import "time"
+
+// EmailMessage is the delivery state of one sent email, kept up to date by the
+// provider's webhooks.
+type EmailMessage struct {
+	ID          int64      `db:"id" json:"id"`
+	ResendID    string     `db:"resend_id" json:"resend_id"`
+	ToEmail     string     `db:"to_email" json:"to_email"`
+	Subject     *string    `db:"subject" json:"subject,omitempty"`
+	Status      string     `db:"status" json:"status"`
+	LastError   *string    `db:"last_error" json:"last_error,omitempty"`
+	LastEventAt *time.Time `db:"last_event_at" json:"last_event_at,omitempty"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+// EmailSuppression blocks sending to an address after a bounce or complaint.
+type EmailSuppression struct {
+	ID        int64     `db:"id" json:"id"`
+	Email     string    `db:"email" json:"email"`
+	Reason    string    `db:"reason" json:"reason"`
+	ResendID  *string   `db:"resend_id" json:"resend_id,omitempty"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
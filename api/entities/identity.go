@@ -0,0 +1,24 @@
+package entities
+
+import "time"
+
+// UserIdentity links a user to an account at an external OpenID Connect provider.
+type UserIdentity struct {
+	ID          int64     `db:"id"`
+	UserID      int64     `db:"user_id"`
+	Provider    string    `db:"provider"`
+	Subject     string    `db:"subject"`
+	Email       string    `db:"email"`
+	CreatedAt   time.Time `db:"created_at"`
+	LastLoginAt time.Time `db:"last_login_at"`
+}
+
+// OIDCState is the server half of a pending OpenID Connect login. The ID is the
+// hash of the state parameter sent to the provider.
+type OIDCState struct {
+	ID           string    `db:"id"`
+	Provider     string    `db:"provider"`
+	CodeVerifier string    `db:"code_verifier"`
+	Nonce        string    `db:"nonce"`
+	ExpiresAt    time.Time `db:"expires_at"`
+}
@@ -10,6 +10,7 @@ type ImportBatch struct {
 }
 
 type ImportedTransaction struct {
+	UUID       string
 	Ciphertext string
 	Nonce      string
 	Tag        string
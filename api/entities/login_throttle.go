@@ -0,0 +1,16 @@
+package entities
+
+import "time"
+
+// LoginThrottle counts recent failed logins for one email or IP address.
+// Email keys are stored hashed so unknown addresses are not retained.
+type LoginThrottle struct {
+	Scope           string     `db:"scope"`
+	ScopeKey        string     `db:"scope_key"`
+	Failures        int        `db:"failures"`
+	LastFailureAt   time.Time  `db:"last_failure_at"`
+	LockedUntil     *time.Time `db:"locked_until"`
+	UnlockToken     *string    `db:"unlock_token"`
+	UnlockExpiresAt *time.Time `db:"unlock_expires_at"`
+	UnlockUserID    *int64     `db:"unlock_user_id"`
+}
@@ -0,0 +1,15 @@
+package entities
+
+import "time"
+
+// MagicLink is an emailed single-use sign-in link. DeviceHash binds it to the
+// client that asked for it; opening it anywhere else needs confirmation.
+type MagicLink struct {
+	ID         int64      `db:"id"`
+	UserID     int64      `db:"user_id"`
+	TokenHash  string     `db:"token_hash"`
+	DeviceHash string     `db:"device_hash"`
+	ExpiresAt  time.Time  `db:"expires_at"`
+	ConsumedAt *time.Time `db:"consumed_at"`
+	CreatedAt  time.Time  `db:"created_at"`
+}
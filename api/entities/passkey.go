@@ -0,0 +1,27 @@
+package entities
+
+import "time"
+
+// Passkey is a registered WebAuthn credential. Credential holds the JSON-encoded
+// library record; SignCount is kept in its own column as the source of truth.
+type Passkey struct {
+	ID           int64      `db:"id" json:"id"`
+	UserID       int64      `db:"user_id" json:"-"`
+	CredentialID []byte     `db:"credential_id" json:"-"`
+	Name         string     `db:"name" json:"name"`
+	Credential   string     `db:"credential" json:"-"`
+	SignCount    uint32     `db:"sign_count" json:"-"`
+	LastUsedAt   *time.Time `db:"last_used_at" json:"last_used_at"`
+	CreatedAt    time.Time  `db:"created_at" json:"created_at"`
+}
+
+// WebAuthnSession is the server half of a pending registration or login ceremony.
+// The ID is the hash of the handle given to the client.
+type WebAuthnSession struct {
+	ID          string    `db:"id"`
+	UserID      *int64    `db:"user_id"`
+	Ceremony    string    `db:"ceremony"`
+	SessionData string    `db:"session_data"`
+	ExpiresAt   time.Time `db:"expires_at"`
+	CreatedAt   time.Time `db:"created_at"`
+}
@@ -0,0 +1,25 @@
+package entities
+
+import "time"
+
+// Session is one login of a user on a device, keyed by the refresh token family
+// issued at login. Revoking the session revokes the family and vice versa.
+type Session struct {
+	ID         int64      `db:"id" json:"id"`
+	UserID     int64      `db:"user_id" json:"-"`
+	FamilyID   string     `db:"family_id" json:"-"`
+	DeviceName string     `db:"device_name" json:"device_name"`
+	UserAgent  string     `db:"user_agent" json:"user_agent"`
+	IPAddress  string     `db:"ip_address" json:"ip_address"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+	LastSeenAt time.Time  `db:"last_seen_at" json:"last_seen_at"`
+	RevokedAt  *time.Time `db:"revoked_at" json:"-"`
+	Current    bool       `db:"-" json:"current"`
+}
+
+// ClientInfo describes the device a request came from.
+type ClientInfo struct {
+	DeviceName string
+	UserAgent  string
+	IPAddress  string
+}
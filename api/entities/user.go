@@ -4,14 +4,24 @@ import "time"
 
 // User represents user.
 type User struct {
-	ID                    int64      `db:"id" json:"id"`
-	Email                 string     `db:"email" json:"email"`
-	Name                  string     `db:"name" json:"name"`
-	Role                  string     `db:"role" json:"role"`
-	Password              string     `db:"password" json:"-"`
-	IsVerified            bool       `db:"is_verified" json:"is_verified"`
-	VerificationToken     *string    `db:"verification_token" json:"-"`
-	VerificationExpiresAt *time.Time `db:"verification_expires_at" json:"-"`
-	CreatedAt             time.Time  `db:"created_at" json:"created_at"`
-	UpdatedAt             time.Time  `db:"updated_at" json:"updated_at"`
+	ID                     int64      `db:"id" json:"id"`
+	Email                  string     `db:"email" json:"email"`
+	PendingEmail           *string    `db:"pending_email" json:"pending_email,omitempty"`
+	Name                   string     `db:"name" json:"name"`
+	Role                   string     `db:"role" json:"role"`
+	Password               string     `db:"password" json:"-"`
+	IsVerified             bool       `db:"is_verified" json:"is_verified"`
+	VerificationToken      *string    `db:"verification_token" json:"-"`
+	VerificationExpiresAt  *time.Time `db:"verification_expires_at" json:"-"`
+	PasswordResetToken     *string    `db:"password_reset_token" json:"-"`
+	PasswordResetExpiresAt *time.Time `db:"password_reset_expires_at" json:"-"`
+	MFAEnabled             bool       `db:"mfa_enabled" json:"mfa_enabled"`
+	MFASecret              *string    `db:"mfa_secret" json:"-"`
+	MFALastStep            *int64     `db:"mfa_last_step" json:"-"`
+	DeletionRequestedAt    *time.Time `db:"deletion_requested_at" json:"deletion_requested_at,omitempty"`
+	DeletionCancelToken    *string    `db:"deletion_cancel_token" json:"-"`
+	DisabledAt             *time.Time `db:"disabled_at" json:"disabled_at,omitempty"`
+	ChangeSeq              int64      `db:"change_seq" json:"-"`
+	CreatedAt              time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt              time.Time  `db:"updated_at" json:"updated_at"`
 }
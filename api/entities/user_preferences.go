@@ -0,0 +1,24 @@
+package entities
+
+import "time"
+
+// UserPreferences configures how the app presents a user's data. Users without a
+// stored row get the defaults.
+type UserPreferences struct {
+	UserID   int64  `db:"user_id" json:"-"`
+	Locale   string `db:"locale" json:"locale"`
+	Currency string `db:"currency" json:"currency"`
+	Timezone string `db:"timezone" json:"timezone"`
+	// FirstDayOfWeek counts from Sunday (0) like time.Weekday.
+	FirstDayOfWeek int `db:"first_day_of_week" json:"first_day_of_week"`
+	// CycleStartDay is the day of the month a budget cycle starts on.
+	CycleStartDay int       `db:"cycle_start_day" json:"cycle_start_day"`
+	CreatedAt     time.Time `db:"created_at" json:"-"`
+	UpdatedAt     time.Time `db:"updated_at" json:"-"`
+}
+
+// Profile is the current user as returned by /me.
+type Profile struct {
+	User
+	Preferences *UserPreferences `json:"preferences"`
+}
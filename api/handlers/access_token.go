@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"finlog-api/api/models/responses"
+	"finlog-api/api/services/accesstoken"
+)
+
+// CreateAccessToken issues a personal access token. The token itself is only
+// included in this response.
+func CreateAccessToken(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(int64)
+	type req struct {
+		Name      string     `json:"name"`
+		Scopes    []string   `json:"scopes"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+	var body req
+	if err := c.BodyParser(&body); err != nil {
+		return responses.BadRequest(err)
+	}
+	raw, token, err := app.Services.AccessTokens.Create(context.Background(), userID, body.Name, body.Scopes, body.ExpiresAt)
+	if err != nil {
+		return mapAccessTokenError(err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"token":        raw,
+		"access_token": token,
+	})
+}
+
+// ListAccessTokens returns the current user's personal access tokens.
+func ListAccessTokens(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(int64)
+	tokens, err := app.Services.AccessTokens.List(context.Background(), userID)
+	if err != nil {
+		return responses.InternalServerError(err)
+	}
+	return c.JSON(tokens)
+}
+
+// RevokeAccessToken revokes one of the current user's personal access tokens.
+func RevokeAccessToken(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(int64)
+	tokenID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return responses.BadRequest(errors.New("invalid token id"))
+	}
+	if err := app.Services.AccessTokens.Revoke(context.Background(), userID, tokenID); err != nil {
+		return mapAccessTokenError(err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func mapAccessTokenError(err error) error {
+	switch {
+	case errors.Is(err, accesstoken.ErrInvalidName()),
+		errors.Is(err, accesstoken.ErrInvalidScope()),
+		errors.Is(err, accesstoken.ErrInvalidExpiry()):
+		return responses.BadRequest(err)
+	case errors.Is(err, accesstoken.ErrTooManyTokens()):
+		return responses.Conflict(err)
+	case errors.Is(err, accesstoken.ErrTokenNotFound()):
+		return responses.NotFound(err)
+	default:
+		return responses.InternalServerError(err)
+	}
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"finlog-api/api/models/responses"
+	"finlog-api/api/services/account"
+	"finlog-api/api/services/auth"
+)
+
+// DeleteAccount schedules the current user's account for deletion. The password,
+// or an MFA code, must be supplied again.
+func DeleteAccount(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(int64)
+	type req struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+	var body req
+	if err := c.BodyParser(&body); err != nil {
+		return responses.BadRequest(err)
+	}
+	purgeAt, err := app.Services.Account.RequestDeletion(context.Background(), userID, body.Password, body.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidCredentials()), errors.Is(err, auth.ErrInvalidMFACode()):
+			return responses.UnAuthorized(err)
+		case errors.Is(err, account.ErrDeletionAlreadyRequested()):
+			return responses.Conflict(err)
+		case errors.Is(err, account.ErrUserNotFound()), errors.Is(err, auth.ErrUserNotFound()):
+			return responses.NotFound(err)
+		default:
+			return responses.InternalServerError(err)
+		}
+	}
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"status":   "success",
+		"message":  "Akun dijadwalkan untuk dihapus",
+		"purge_at": purgeAt,
+	})
+}
+
+// CancelAccountDeletion handles the cancel link from the deletion email.
+func CancelAccountDeletion(c *fiber.Ctx) error {
+	token := c.Query("token")
+	if !linkTokenPattern.MatchString(token) {
+		return responses.BadRequest(errors.New("invalid cancel token"))
+	}
+	if err := app.Services.Account.CancelDeletion(context.Background(), token); err != nil {
+		if errors.Is(err, account.ErrCancelTokenInvalid()) {
+			return responses.BadRequest(err)
+		}
+		return responses.InternalServerError(err)
+	}
+	return sendDeepLinkPage(c, "Penghapusan akun dibatalkan", "finlog://login?deletion_cancelled=true")
+}
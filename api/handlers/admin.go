@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"finlog-api/api/models/responses"
+	"finlog-api/api/services/account"
+	"finlog-api/api/services/admin"
+	"finlog-api/api/services/auth"
+)
+
+// AdminSearchUsers finds accounts by email prefix.
+func AdminSearchUsers(c *fiber.Ctx) error {
+	actorID, _ := c.Locals("user_id").(int64)
+	users, err := app.Services.Admin.SearchUsers(clientContext(c), actorID, c.Query("email"))
+	if err != nil {
+		return mapAdminError(err)
+	}
+	return c.JSON(fiber.Map{"users": users})
+}
+
+// AdminGetUser shows an account with its verification and email delivery status.
+func AdminGetUser(c *fiber.Ctx) error {
+	actorID, _ := c.Locals("user_id").(int64)
+	userID, err := adminUserID(c)
+	if err != nil {
+		return err
+	}
+	detail, err := app.Services.Admin.GetUser(clientContext(c), actorID, userID)
+	if err != nil {
+		return mapAdminError(err)
+	}
+	return c.JSON(detail)
+}
+
+func AdminResendVerification(c *fiber.Ctx) error {
+	return adminAction(c, app.Services.Admin.ResendVerification)
+}
+
+func AdminForceVerify(c *fiber.Ctx) error {
+	return adminAction(c, app.Services.Admin.ForceVerify)
+}
+
+// AdminDisableUser blocks the account and signs out all of its sessions.
+func AdminDisableUser(c *fiber.Ctx) error {
+	return adminAction(c, app.Services.Admin.DisableUser)
+}
+
+func AdminEnableUser(c *fiber.Ctx) error {
+	return adminAction(c, app.Services.Admin.EnableUser)
+}
+
+// AdminScheduleDeletion starts the deletion grace period on the user's behalf.
+func AdminScheduleDeletion(c *fiber.Ctx) error {
+	actorID, _ := c.Locals("user_id").(int64)
+	userID, err := adminUserID(c)
+	if err != nil {
+		return err
+	}
+	purgeAt, err := app.Services.Admin.ScheduleDeletion(clientContext(c), actorID, userID)
+	if err != nil {
+		return mapAdminError(err)
+	}
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"purge_at": purgeAt})
+}
+
+func adminAction(c *fiber.Ctx, action func(ctx context.Context, actorID, userID int64) error) error {
+	actorID, _ := c.Locals("user_id").(int64)
+	userID, err := adminUserID(c)
+	if err != nil {
+		return err
+	}
+	if err := action(clientContext(c), actorID, userID); err != nil {
+		return mapAdminError(err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func adminUserID(c *fiber.Ctx) (int64, error) {
+	userID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil || userID <= 0 {
+		return 0, responses.BadRequest(errors.New("invalid user id"))
+	}
+	return userID, nil
+}
+
+func mapAdminError(err error) error {
+	switch {
+	case errors.Is(err, admin.ErrSearchEmpty()):
+		return responses.BadRequest(err)
+	case errors.Is(err, admin.ErrCannotDisableSelf()):
+		return responses.Forbidden(err)
+	case errors.Is(err, admin.ErrUserNotFound()), errors.Is(err, auth.ErrUserNotFound()), errors.Is(err, account.ErrUserNotFound()):
+		return responses.NotFound(err)
+	case errors.Is(err, admin.ErrAlreadyVerified()),
+		errors.Is(err, admin.ErrAlreadyDisabled()),
+		errors.Is(err, admin.ErrNotDisabled()),
+		errors.Is(err, auth.ErrEmailAlreadyVerified()),
+		errors.Is(err, account.ErrDeletionAlreadyRequested()):
+		return responses.Conflict(err)
+	default:
+		return responses.InternalServerError(err)
+	}
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"finlog-api/api/contracts"
+	"finlog-api/api/entities"
+	"finlog-api/api/models/responses"
+)
+
+// ListAuditLog returns the current user's security events, newest first. Pass the
+// next_before value of a response as before to get the following page.
+func ListAuditLog(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(int64)
+	beforeID, limit, err := auditPage(c)
+	if err != nil {
+		return responses.BadRequest(err)
+	}
+	events, next, err := app.Services.Audit.ListForUser(context.Background(), userID, beforeID, limit)
+	if err != nil {
+		return responses.InternalServerError(err)
+	}
+	return sendAuditPage(c, events, next)
+}
+
+// SearchAuditEvents lets support search every user's events by account, action,
+// client IP and time range.
+func SearchAuditEvents(c *fiber.Ctx) error {
+	beforeID, limit, err := auditPage(c)
+	if err != nil {
+		return responses.BadRequest(err)
+	}
+	filter := contracts.AuditFilter{
+		Action:    c.Query("action"),
+		IPAddress: c.Query("ip"),
+		BeforeID:  beforeID,
+		Limit:     limit,
+	}
+	if raw := c.Query("user_id"); raw != "" {
+		userID, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return responses.BadRequest(errors.New("invalid user_id"))
+		}
+		filter.UserID = &userID
+	}
+	if filter.Since, err = auditTime(c, "since"); err != nil {
+		return responses.BadRequest(err)
+	}
+	if filter.Until, err = auditTime(c, "until"); err != nil {
+		return responses.BadRequest(err)
+	}
+
+	events, next, err := app.Services.Audit.Search(context.Background(), filter)
+	if err != nil {
+		return responses.InternalServerError(err)
+	}
+	return sendAuditPage(c, events, next)
+}
+
+func auditPage(c *fiber.Ctx) (int64, int, error) {
+	var beforeID int64
+	if raw := c.Query("before"); raw != "" {
+		parsed, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || parsed <= 0 {
+			return 0, 0, errors.New("invalid before")
+		}
+		beforeID = parsed
+	}
+	limit := 0
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			return 0, 0, errors.New("invalid limit")
+		}
+		limit = parsed
+	}
+	return beforeID, limit, nil
+}
+
+func auditTime(c *fiber.Ctx, key string) (*time.Time, error) {
+	raw := c.Query(key)
+	if raw == "" {
+		return nil, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil, errors.New("invalid " + key)
+	}
+	parsed = parsed.UTC()
+	return &parsed, nil
+}
+
+// sendAuditPage omits next_before on the last page.
+func sendAuditPage(c *fiber.Ctx, events []entities.AuditEvent, next int64) error {
+	response := fiber.Map{"events": events}
+	if next > 0 {
+		response["next_before"] = next
+	}
+	return c.JSON(response)
+}
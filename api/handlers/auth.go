@@ -4,13 +4,22 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 
+	"finlog-api/api/entities"
+	"finlog-api/api/helpers"
 	"finlog-api/api/models/responses"
 	"finlog-api/api/services/auth"
 )
 
+// linkTokenPattern matches tokens produced by the auth service for email links.
+var linkTokenPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{16,128}$`)
+
 // AuthLogin returns tokens in a flat response for the mobile client.
 func AuthLogin(c *fiber.Ctx) error {
 	type req struct {
@@ -21,22 +30,150 @@ func AuthLogin(c *fiber.Ctx) error {
 	if err := c.BodyParser(&body); err != nil {
 		return responses.BadRequest(err)
 	}
-	access, refresh, user, err := app.Services.Auth.Login(context.Background(), body.Email, body.Password)
+	access, refresh, user, err := app.Services.Auth.Login(clientContext(c), body.Email, body.Password)
 	if err != nil {
+		var mfaErr *auth.MFARequiredError
 		switch {
+		case errors.As(err, &mfaErr):
+			return c.Status(fiber.StatusOK).JSON(fiber.Map{
+				"mfa_required": true,
+				"mfa_token":    mfaErr.ChallengeToken,
+			})
 		case errors.Is(err, auth.ErrEmailNotVerified()):
 			return responses.UnAuthorized(fmt.Errorf("email_not_verified"))
 		default:
-			return responses.UnAuthorized(err)
+			return mapLoginError(c, err)
 		}
 	}
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+	return sendLogin(c, access, refresh, user)
+}
+
+// AuthLoginMFA exchanges the challenge from AuthLogin and a TOTP or recovery code for tokens.
+func AuthLoginMFA(c *fiber.Ctx) error {
+	type req struct {
+		MFAToken string `json:"mfa_token"`
+		Code     string `json:"code"`
+	}
+	var body req
+	if err := c.BodyParser(&body); err != nil {
+		return responses.BadRequest(err)
+	}
+	access, refresh, user, err := app.Services.Auth.LoginMFA(clientContext(c), body.MFAToken, body.Code)
+	if err != nil {
+		return mapLoginError(c, err)
+	}
+	return sendLogin(c, access, refresh, user)
+}
+
+// sendLogin answers every successful sign-in. The user's preferences are included
+// so the app can configure itself on first load.
+func sendLogin(c *fiber.Ctx, access, refresh string, user *entities.User) error {
+	response := fiber.Map{
 		"access_token":  access,
 		"refresh_token": refresh,
 		"email":         user.Email,
+	}
+	prefs, err := app.Services.Profile.Preferences(context.Background(), user.ID)
+	if err != nil {
+		// The tokens are already issued; the app falls back to GET /me.
+		app.Logger.Error().Err(err).Int64("user_id", user.ID).Msg("login_preferences_failed")
+	} else {
+		response["preferences"] = prefs
+	}
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+// mapLoginError answers lockouts with 429 and a Retry-After hint; every other
+// login failure stays a plain 401.
+func mapLoginError(c *fiber.Ctx, err error) error {
+	var lockedErr *auth.LoginLockedError
+	if errors.As(err, &lockedErr) {
+		seconds := int(math.Ceil(lockedErr.RetryAfter.Seconds()))
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(seconds))
+		return responses.TooManyRequests(err)
+	}
+	return responses.UnAuthorized(err)
+}
+
+// UnlockAccount handles the unlock link emailed when an account gets locked.
+func UnlockAccount(c *fiber.Ctx) error {
+	token := c.Query("token")
+	if !linkTokenPattern.MatchString(token) {
+		return responses.BadRequest(errors.New("invalid unlock token"))
+	}
+	if err := app.Services.Auth.UnlockLogin(context.Background(), token); err != nil {
+		if errors.Is(err, auth.ErrUnlockTokenInvalid()) {
+			return responses.BadRequest(err)
+		}
+		return responses.InternalServerError(err)
+	}
+	return sendDeepLinkPage(c, "Akun dibuka kembali", "finlog://login?unlocked=true")
+}
+
+// EnrollMFA starts TOTP enrollment and returns the secret for the authenticator app.
+func EnrollMFA(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(int64)
+	secret, uri, err := app.Services.Auth.EnrollMFA(context.Background(), userID)
+	if err != nil {
+		return mapMFAError(err)
+	}
+	return c.JSON(fiber.Map{
+		"secret":      secret,
+		"otpauth_uri": uri,
 	})
 }
 
+// ConfirmMFA enables TOTP with the first code and returns one-time recovery codes.
+func ConfirmMFA(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(int64)
+	type req struct {
+		Code string `json:"code"`
+	}
+	var body req
+	if err := c.BodyParser(&body); err != nil {
+		return responses.BadRequest(err)
+	}
+	codes, err := app.Services.Auth.ConfirmMFA(clientContext(c), userID, body.Code)
+	if err != nil {
+		return mapMFAError(err)
+	}
+	return c.JSON(fiber.Map{
+		"recovery_codes": codes,
+	})
+}
+
+// DisableMFA turns TOTP off after checking the password and a current code.
+func DisableMFA(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(int64)
+	type req struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+	var body req
+	if err := c.BodyParser(&body); err != nil {
+		return responses.BadRequest(err)
+	}
+	if err := app.Services.Auth.DisableMFA(clientContext(c), userID, body.Password, body.Code); err != nil {
+		return mapMFAError(err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func mapMFAError(err error) error {
+	switch {
+	case errors.Is(err, auth.ErrInvalidCredentials()):
+		return responses.UnAuthorized(err)
+	case errors.Is(err, auth.ErrInvalidMFACode()), errors.Is(err, auth.ErrMFANotEnrolled()):
+		return responses.BadRequest(err)
+	case errors.Is(err, auth.ErrMFAAlreadyEnabled()):
+		return responses.Conflict(err)
+	case errors.Is(err, auth.ErrUserNotFound()):
+		return responses.NotFound(err)
+	default:
+		return responses.InternalServerError(err)
+	}
+}
+
 // Register creates a new user and issues tokens.
 func Register(c *fiber.Ctx) error {
 	type req struct {
@@ -49,7 +186,10 @@ func Register(c *fiber.Ctx) error {
 	}
 	_, err := app.Services.Auth.Register(context.Background(), body.Email, body.Password)
 	if err != nil {
+		var weakErr *auth.WeakPasswordError
 		switch {
+		case errors.As(err, &weakErr):
+			return weakPasswordResponse(weakErr)
 		case errors.Is(err, auth.ErrInvalidCredentials()) || errors.Is(err, auth.ErrInvalidInput()):
 			return responses.BadRequest(err)
 		case errors.Is(err, auth.ErrEmailExists()):
@@ -69,7 +209,7 @@ func VerifyEmail(c *fiber.Ctx) error {
 	if token == "" {
 		return responses.BadRequest(errors.New("verification token is required"))
 	}
-	if _, err := app.Services.Auth.VerifyEmail(context.Background(), token); err != nil {
+	if _, err := app.Services.Auth.VerifyEmail(clientContext(c), token); err != nil {
 		switch {
 		case errors.Is(err, auth.ErrVerificationTokenInvalid()):
 			return responses.BadRequest(err)
@@ -77,6 +217,8 @@ func VerifyEmail(c *fiber.Ctx) error {
 			return responses.BadRequest(err)
 		case errors.Is(err, auth.ErrEmailAlreadyVerified()):
 			// already verified, continue to redirect
+		case errors.Is(err, auth.ErrEmailExists()):
+			return responses.Conflict(err)
 		default:
 			return responses.BadRequest(err)
 		}
@@ -109,6 +251,120 @@ func ResendVerification(c *fiber.Ctx) error {
 	})
 }
 
+// ForgotPassword emails a reset link. The response never reveals whether the email exists.
+func ForgotPassword(c *fiber.Ctx) error {
+	type req struct {
+		Email string `json:"email"`
+	}
+	var body req
+	if err := c.BodyParser(&body); err != nil {
+		return responses.BadRequest(err)
+	}
+	if err := app.Services.Auth.RequestPasswordReset(context.Background(), body.Email); err != nil {
+		return responses.InternalServerError(err)
+	}
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"message": "Jika email terdaftar, tautan reset password telah dikirim",
+	})
+}
+
+// ResetPassword sets a new password using the token from the reset email.
+func ResetPassword(c *fiber.Ctx) error {
+	type req struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	var body req
+	if err := c.BodyParser(&body); err != nil {
+		return responses.BadRequest(err)
+	}
+	if err := app.Services.Auth.ResetPassword(clientContext(c), body.Token, body.Password); err != nil {
+		var weakErr *auth.WeakPasswordError
+		switch {
+		case errors.As(err, &weakErr):
+			return weakPasswordResponse(weakErr)
+		case errors.Is(err, auth.ErrResetTokenInvalid()),
+			errors.Is(err, auth.ErrResetTokenExpired()),
+			errors.Is(err, auth.ErrInvalidInput()):
+			return responses.BadRequest(err)
+		default:
+			return responses.InternalServerError(err)
+		}
+	}
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"message": "Password berhasil diubah",
+	})
+}
+
+// ChangePassword replaces the password of the signed-in user.
+func ChangePassword(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(int64)
+	familyID, _ := c.Locals("token_family").(string)
+	type req struct {
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
+	}
+	var body req
+	if err := c.BodyParser(&body); err != nil {
+		return responses.BadRequest(err)
+	}
+	if err := app.Services.Auth.ChangePassword(clientContext(c), userID, familyID, body.CurrentPassword, body.NewPassword); err != nil {
+		return mapAccountChangeError(err)
+	}
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"message": "Password berhasil diubah",
+	})
+}
+
+// ChangeEmail starts an email change; it completes when the new address is verified.
+func ChangeEmail(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(int64)
+	type req struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+	var body req
+	if err := c.BodyParser(&body); err != nil {
+		return responses.BadRequest(err)
+	}
+	if err := app.Services.Auth.ChangeEmail(clientContext(c), userID, body.Email, body.Password); err != nil {
+		return mapAccountChangeError(err)
+	}
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Verification email sent",
+	})
+}
+
+func mapAccountChangeError(err error) error {
+	var weakErr *auth.WeakPasswordError
+	switch {
+	case errors.As(err, &weakErr):
+		return weakPasswordResponse(weakErr)
+	case errors.Is(err, auth.ErrInvalidCredentials()):
+		return responses.UnAuthorized(err)
+	case errors.Is(err, auth.ErrInvalidInput()):
+		return responses.BadRequest(err)
+	case errors.Is(err, auth.ErrEmailExists()):
+		return responses.Conflict(err)
+	case errors.Is(err, auth.ErrUserNotFound()):
+		return responses.NotFound(err)
+	default:
+		return responses.InternalServerError(err)
+	}
+}
+
+// weakPasswordResponse lists why a new password was rejected so the app can
+// explain it next to the field.
+func weakPasswordResponse(err *auth.WeakPasswordError) error {
+	resp := responses.UnprocessableEntity(err)
+	resp.Data = fiber.Map{"reasons": err.Reasons}
+	return resp
+}
+
 func getVerificationRedirect() string {
 	return "https://api.finlog.asia/activated"
 }
@@ -122,7 +378,7 @@ func Refresh(c *fiber.Ctx) error {
 	if err := c.BodyParser(&body); err != nil {
 		return responses.BadRequest(err)
 	}
-	access, refresh, user, err := app.Services.Auth.Refresh(context.Background(), body.RefreshToken)
+	access, refresh, user, err := app.Services.Auth.Refresh(clientContext(c), body.RefreshToken)
 	if err != nil {
 		return responses.UnAuthorized(err)
 	}
@@ -133,15 +389,65 @@ func Refresh(c *fiber.Ctx) error {
 	})
 }
 
-// Logout ends the session (stateless JWT, no server state is kept).
+// Logout revokes the refresh tokens of the current login.
 func Logout(c *fiber.Ctx) error {
 	userID, _ := c.Locals("user_id").(int64)
-	if err := app.Services.Auth.Logout(context.Background(), userID); err != nil {
+	familyID, _ := c.Locals("token_family").(string)
+	if err := app.Services.Auth.Logout(clientContext(c), userID, familyID); err != nil {
 		return responses.BadRequest(err)
 	}
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
+// LogoutAll revokes the refresh tokens of every login of the current user.
+func LogoutAll(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(int64)
+	if err := app.Services.Auth.LogoutAll(clientContext(c), userID); err != nil {
+		return responses.InternalServerError(err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ListSessions returns the devices the current user is signed in on.
+func ListSessions(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(int64)
+	familyID, _ := c.Locals("token_family").(string)
+	sessions, err := app.Services.Auth.ListSessions(context.Background(), userID, familyID)
+	if err != nil {
+		return responses.InternalServerError(err)
+	}
+	return c.JSON(sessions)
+}
+
+// RevokeSession signs one of the current user's devices out.
+func RevokeSession(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(int64)
+	sessionID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return responses.BadRequest(errors.New("invalid session id"))
+	}
+	if err := app.Services.Auth.RevokeSession(context.Background(), userID, sessionID); err != nil {
+		if errors.Is(err, auth.ErrSessionNotFound()) {
+			return responses.NotFound(err)
+		}
+		return responses.InternalServerError(err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// JWKS publishes the public keys that verify access and refresh tokens so other
+// services can check them without sharing a secret.
+func JWKS(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.Status(fiber.StatusOK).JSON(app.JWTKeys.JWKS())
+}
+
+// clientContext carries the caller's device details to the auth service so new
+// tokens are recorded against the right session.
+func clientContext(c *fiber.Ctx) context.Context {
+	return helpers.WithClientInfo(context.Background(), helpers.ClientInfoFromRequest(c))
+}
+
 func ActivatedHandler(c *fiber.Ctx) error {
 	return c.Type("html").SendString(`
 		<!DOCTYPE html>
@@ -188,4 +494,63 @@ func ActivatedHandler(c *fiber.Ctx) error {
 		</body>
 		</html>
 	`)
-}
\ No newline at end of file
+}
+
+// ResetPasswordPage hands the token from the reset email over to the mobile app.
+func ResetPasswordPage(c *fiber.Ctx) error {
+	token := c.Query("token")
+	if !linkTokenPattern.MatchString(token) {
+		return responses.BadRequest(errors.New("invalid reset token"))
+	}
+	return sendDeepLinkPage(c, "Reset Password", "finlog://reset-password?token="+url.QueryEscape(token))
+}
+
+// sendDeepLinkPage renders a small page that opens the app at target, with a
+// fallback button when the automatic redirect is blocked.
+func sendDeepLinkPage(c *fiber.Ctx, title, target string) error {
+	return c.Type("html").SendString(fmt.Sprintf(`
+		<!DOCTYPE html>
+		<html lang="id">
+		<head>
+		<meta charset="utf-8" />
+		<meta name="viewport" content="width=device-width, initial-scale=1" />
+		<title>FinLog</title>
+
+		<script>
+			window.onload = function () {
+			window.location.href = "%[2]s";
+			setTimeout(function () {
+				document.getElementById("openApp").style.display = "block";
+			}, 1500);
+			};
+		</script>
+
+		<style>
+			body {
+			font-family: system-ui, -apple-system, BlinkMacSystemFont;
+			text-align: center;
+			padding: 40px;
+			}
+			a {
+			display: inline-block;
+			margin-top: 20px;
+			padding: 12px 20px;
+			background: #111;
+			color: #fff;
+			text-decoration: none;
+			border-radius: 8px;
+			}
+		</style>
+		</head>
+
+		<body>
+		<h2>%[1]s</h2>
+		<p>Membuka aplikasi FinLog…</p>
+
+		<a id="openApp" href="%[2]s" style="display:none">
+			Buka Aplikasi FinLog
+		</a>
+		</body>
+		</html>
+	`, title, target))
+}
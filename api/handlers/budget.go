@@ -13,11 +13,11 @@ import (
 // GetBudget returns aggregated income/expense for the period.
 func GetBudget(c *fiber.Ctx) error {
 	userID, _ := c.Locals("user_id").(int64)
-	year, month, err := parsePeriod(c)
+	within, err := parsePeriod(c, userID)
 	if err != nil {
-		return responses.BadRequest(err)
+		return err
 	}
-	data, err := app.Services.Budget.GetMonthly(context.Background(), userID, year, month)
+	data, err := app.Services.Budget.Get(context.Background(), userID, within)
 	if err != nil {
 		if errors.Is(err, budget.ErrInvalidPeriod()) {
 			return responses.BadRequest(err)
@@ -55,10 +55,31 @@ func CreateCategory(c *fiber.Ctx) error {
 			return responses.BadRequest(err)
 		}
 	}
+	setETag(c, cat.Version)
 	return c.Status(fiber.StatusCreated).JSON(cat)
 }
 
-// UpdateCategory updates category attributes.
+// GetCategory returns a category with its version as ETag.
+func GetCategory(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(int64)
+	categoryID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return responses.BadRequest(errors.New("invalid category id"))
+	}
+	cat, err := app.Services.Categories.GetCategory(context.Background(), userID, categoryID)
+	if err != nil {
+		if errors.Is(err, category.ErrCategoryNotFound()) {
+			return responses.NotFound(err)
+		}
+		return responses.BadRequest(err)
+	}
+	setETag(c, cat.Version)
+	return c.JSON(cat)
+}
+
+// UpdateCategory updates category attributes. The write only applies to the
+// version in If-Match or the body; a stale version answers 409 with the stored
+// category.
 func UpdateCategory(c *fiber.Ctx) error {
 	userID, _ := c.Locals("user_id").(int64)
 	categoryID, err := strconv.ParseInt(c.Params("id"), 10, 64)
@@ -69,22 +90,43 @@ func UpdateCategory(c *fiber.Ctx) error {
 		Name      string `json:"name"`
 		IsExpense bool   `json:"isExpense"`
 		IconKey   string `json:"icon"`
+		Version   int64  `json:"version"`
 	}
 	var body req
 	if err := c.BodyParser(&body); err != nil {
 		return responses.BadRequest(err)
 	}
-	if err := app.Services.Categories.UpdateCategory(context.Background(), userID, categoryID, body.Name, body.IsExpense, body.IconKey); err != nil {
+	version, err := expectedVersion(c, body.Version, func() (int64, error) {
+		current, err := app.Services.Categories.GetCategory(context.Background(), userID, categoryID)
+		switch {
+		case errors.Is(err, category.ErrCategoryNotFound()):
+			return 0, responses.NotFound(err)
+		case err != nil:
+			return 0, responses.BadRequest(err)
+		}
+		return current.Version, nil
+	})
+	if err != nil {
+		return err
+	}
+	cat, err := app.Services.Categories.UpdateCategory(context.Background(), userID, categoryID, version, body.Name, body.IsExpense, body.IconKey)
+	if err != nil {
+		var conflict *category.VersionConflictError
 		switch {
+		case errors.As(err, &conflict):
+			return versionConflict(c, err, conflict.Current, conflict.Current.Version)
 		case errors.Is(err, category.ErrCategoryExists()):
 			return responses.Conflict(err)
 		case errors.Is(err, category.ErrCategoryNotFound()):
 			return responses.NotFound(err)
+		case errors.Is(err, category.ErrVersionRequired()):
+			return responses.PreconditionRequired(err)
 		default:
 			return responses.BadRequest(err)
 		}
 	}
-	return c.SendStatus(fiber.StatusOK)
+	setETag(c, cat.Version)
+	return c.JSON(cat)
 }
 
 // DeleteCategory removes a category.
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"finlog-api/api/models/responses"
+)
+
+var errVersionRequired = errors.New("send the version you last saw in If-Match or the version field")
+
+// setETag exposes a record's version so clients can send it back in If-Match.
+func setETag(c *fiber.Ctx, version int64) {
+	c.Set(fiber.HeaderETag, strconv.Quote(strconv.FormatInt(version, 10)))
+}
+
+// expectedVersion returns the version a write is conditional on, taken from
+// If-Match or else from the request body. If-Match: * matches any current
+// version (RFC 9110), so it resolves to the stored version through current
+// unless the body names one.
+func expectedVersion(c *fiber.Ctx, fromBody int64, current func() (int64, error)) (int64, error) {
+	header := strings.TrimSpace(c.Get(fiber.HeaderIfMatch))
+	if header == "" {
+		if fromBody <= 0 {
+			return 0, responses.PreconditionRequired(errVersionRequired)
+		}
+		return fromBody, nil
+	}
+	if header == "*" {
+		if fromBody > 0 {
+			return fromBody, nil
+		}
+		return current()
+	}
+	version, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(header, "W/"), `"`), 10, 64)
+	if err != nil || version <= 0 {
+		return 0, responses.BadRequest(errors.New("invalid If-Match header"))
+	}
+	if fromBody > 0 && fromBody != version {
+		return 0, responses.BadRequest(errors.New("If-Match and version disagree"))
+	}
+	return version, nil
+}
+
+// versionConflict answers 409 with the stored copy so the client can merge.
+func versionConflict(c *fiber.Ctx, err error, current interface{}, version int64) error {
+	setETag(c, version)
+	conflict := responses.Conflict(err)
+	conflict.Data = current
+	return conflict
+}
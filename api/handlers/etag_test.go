@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestExpectedVersion(t *testing.T) {
+	cases := []struct {
+		ifMatch  string
+		fromBody int64
+		want     int64
+		wantErr  bool
+	}{
+		{"", 0, 0, true},
+		{"", 4, 4, false},
+		{`"3"`, 0, 3, false},
+		{`W/"3"`, 3, 3, false},
+		{`"3"`, 4, 0, true},
+		{"abc", 0, 0, true},
+		{"*", 0, 7, false},
+		{"*", 4, 4, false},
+	}
+	for _, tc := range cases {
+		var got int64
+		var gotErr error
+		f := fiber.New()
+		f.Put("/", func(c *fiber.Ctx) error {
+			got, gotErr = expectedVersion(c, tc.fromBody, func() (int64, error) { return 7, nil })
+			return nil
+		})
+		req := httptest.NewRequest(http.MethodPut, "/", nil)
+		if tc.ifMatch != "" {
+			req.Header.Set(fiber.HeaderIfMatch, tc.ifMatch)
+		}
+		if _, err := f.Test(req); err != nil {
+			t.Fatalf("%q: %v", tc.ifMatch, err)
+		}
+		if (gotErr != nil) != tc.wantErr || got != tc.want {
+			t.Errorf("%q with body version %d: expected %d (error %v), got %d, %v", tc.ifMatch, tc.fromBody, tc.want, tc.wantErr, got, gotErr)
+		}
+	}
+}
@@ -33,6 +33,9 @@ func ImportTransactions(c *fiber.Ctx) error {
 		if errors.Is(err, importbatch.ErrRateLimitExceeded) {
 			return fiber.NewError(fiber.StatusTooManyRequests, err.Error())
 		}
+		if errors.Is(err, importbatch.ErrTrashedTransaction) {
+			return responses.Conflict(err)
+		}
 		return responses.InternalServerError(err)
 	}
 
@@ -67,7 +70,7 @@ func UndoImportBatch(c *fiber.Ctx) error {
 		return responses.BadRequest(err)
 	}
 
-	deleted, err := app.Services.Import.UndoBatch(c.Context(), userID, batchID)
+	deleted, err := app.Services.Import.UndoBatch(clientContext(c), userID, batchID)
 	if err != nil {
 		switch {
 		case errors.Is(err, importbatch.ErrInvalidImportInput):
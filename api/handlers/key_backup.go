@@ -20,7 +20,7 @@ func StoreKeyBackup(c *fiber.Ctx) error {
 		return responses.BadRequest(err)
 	}
 
-	key, err := app.Services.KeyBackup.StoreKeyBackup(context.Background(), userID, payload.EncryptedDataKey, payload.Salt)
+	key, err := app.Services.KeyBackup.StoreKeyBackup(clientContext(c), userID, payload.EncryptedDataKey, payload.Salt)
 	if err != nil {
 		return mapKeyBackupError(err)
 	}
@@ -39,7 +39,7 @@ func RotateKeyBackup(c *fiber.Ctx) error {
 		return responses.BadRequest(err)
 	}
 
-	key, err := app.Services.KeyBackup.RotateKey(context.Background(), userID, payload.EncryptedDataKey, payload.Salt)
+	key, err := app.Services.KeyBackup.RotateKey(clientContext(c), userID, payload.EncryptedDataKey, payload.Salt)
 	if err != nil {
 		return mapKeyBackupError(err)
 	}
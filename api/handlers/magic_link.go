@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/url"
+
+	"github.com/gofiber/fiber/v2"
+
+	"finlog-api/api/models/responses"
+	"finlog-api/api/services/auth"
+)
+
+// RequestMagicLink emails a sign-in link. The device token in the response must be
+// sent back when the link is consumed; the client keeps it until then.
+func RequestMagicLink(c *fiber.Ctx) error {
+	type req struct {
+		Email string `json:"email"`
+	}
+	var body req
+	if err := c.BodyParser(&body); err != nil {
+		return responses.BadRequest(err)
+	}
+	deviceToken, err := app.Services.Auth.RequestMagicLink(context.Background(), body.Email)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidInput()) {
+			return responses.BadRequest(err)
+		}
+		return responses.InternalServerError(err)
+	}
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"status":       "success",
+		"message":      "Jika email terdaftar, tautan masuk telah dikirim",
+		"device_token": deviceToken,
+	})
+}
+
+// ConsumeMagicLink exchanges a sign-in link for tokens. When the link is opened on
+// another device the client is asked to confirm the account before signing in.
+func ConsumeMagicLink(c *fiber.Ctx) error {
+	type req struct {
+		Token       string `json:"token"`
+		DeviceToken string `json:"device_token"`
+		Confirm     bool   `json:"confirm"`
+	}
+	var body req
+	if err := c.BodyParser(&body); err != nil {
+		return responses.BadRequest(err)
+	}
+	access, refresh, user, err := app.Services.Auth.ConsumeMagicLink(clientContext(c), body.Token, body.DeviceToken, body.Confirm)
+	if err != nil {
+		var confirmErr *auth.MagicLinkConfirmationError
+		var mfaErr *auth.MFARequiredError
+		switch {
+		case errors.As(err, &confirmErr):
+			return c.Status(fiber.StatusOK).JSON(fiber.Map{
+				"confirmation_required": true,
+				"email":                 confirmErr.Email,
+			})
+		case errors.As(err, &mfaErr):
+			return c.Status(fiber.StatusOK).JSON(fiber.Map{
+				"mfa_required": true,
+				"mfa_token":    mfaErr.ChallengeToken,
+			})
+		case errors.Is(err, auth.ErrMagicLinkInvalid()):
+			return responses.UnAuthorized(err)
+		default:
+			return responses.InternalServerError(err)
+		}
+	}
+	return sendLogin(c, access, refresh, user)
+}
+
+// MagicLinkPage handles the link in the sign-in email and hands the token to the app.
+func MagicLinkPage(c *fiber.Ctx) error {
+	token := c.Query("token")
+	if !linkTokenPattern.MatchString(token) {
+		return responses.BadRequest(errors.New("invalid sign-in token"))
+	}
+	return sendDeepLinkPage(c, "Masuk ke FinLog", "finlog://magic-link?token="+url.QueryEscape(token))
+}
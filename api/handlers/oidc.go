@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/url"
+
+	"github.com/gofiber/fiber/v2"
+
+	"finlog-api/api/models/responses"
+	"finlog-api/api/services/auth"
+)
+
+// BeginOIDCLogin returns the provider URL that starts a "Sign in with" login.
+func BeginOIDCLogin(c *fiber.Ctx) error {
+	authURL, err := app.Services.Auth.BeginOIDCLogin(context.Background(), c.Params("provider"))
+	if err != nil {
+		return mapOIDCError(err)
+	}
+	return c.JSON(fiber.Map{
+		"authorization_url": authURL,
+	})
+}
+
+// OIDCCallback receives the provider redirect, by query or form post, and passes
+// the code and state on to the app, which completes the login with FinishOIDCLogin.
+func OIDCCallback(c *fiber.Ctx) error {
+	provider := c.Params("provider")
+	params := url.Values{"provider": {provider}}
+	if reason := formOrQuery(c, "error"); reason != "" {
+		params.Set("error", reason)
+		return sendDeepLinkPage(c, "Login dibatalkan", "finlog://login/oidc?"+params.Encode())
+	}
+	params.Set("state", formOrQuery(c, "state"))
+	params.Set("code", formOrQuery(c, "code"))
+	return sendDeepLinkPage(c, "Masuk ke FinLog", "finlog://login/oidc?"+params.Encode())
+}
+
+// FinishOIDCLogin exchanges the code for tokens, in the same shape as AuthLogin.
+func FinishOIDCLogin(c *fiber.Ctx) error {
+	type req struct {
+		State string `json:"state"`
+		Code  string `json:"code"`
+	}
+	var body req
+	if err := c.BodyParser(&body); err != nil {
+		return responses.BadRequest(err)
+	}
+	access, refresh, user, err := app.Services.Auth.FinishOIDCLogin(clientContext(c), c.Params("provider"), body.State, body.Code)
+	if err != nil {
+		var mfaErr *auth.MFARequiredError
+		if errors.As(err, &mfaErr) {
+			return c.Status(fiber.StatusOK).JSON(fiber.Map{
+				"mfa_required": true,
+				"mfa_token":    mfaErr.ChallengeToken,
+			})
+		}
+		return mapOIDCError(err)
+	}
+	return sendLogin(c, access, refresh, user)
+}
+
+func formOrQuery(c *fiber.Ctx, key string) string {
+	if value := c.FormValue(key); value != "" {
+		return value
+	}
+	return c.Query(key)
+}
+
+func mapOIDCError(err error) error {
+	switch {
+	case errors.Is(err, auth.ErrOIDCProviderUnknown()):
+		return responses.NotFound(err)
+	case errors.Is(err, auth.ErrOIDCStateInvalid()):
+		return responses.BadRequest(err)
+	case errors.Is(err, auth.ErrOIDCTokenInvalid()),
+		errors.Is(err, auth.ErrOIDCEmailUnverified()),
+		errors.Is(err, auth.ErrOIDCExchangeFailed()),
+		errors.Is(err, auth.ErrAccountPendingDeletion()),
+		errors.Is(err, auth.ErrAccountDisabled()):
+		return responses.UnAuthorized(err)
+	default:
+		return responses.InternalServerError(err)
+	}
+}
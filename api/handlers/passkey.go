@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"finlog-api/api/models/responses"
+	"finlog-api/api/services/auth"
+)
+
+// BeginPasskeyRegistration returns creation options for a new passkey of the current user.
+func BeginPasskeyRegistration(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(int64)
+	sessionID, options, err := app.Services.Auth.BeginPasskeyRegistration(context.Background(), userID)
+	if err != nil {
+		return mapPasskeyError(err)
+	}
+	return c.JSON(fiber.Map{
+		"session_id": sessionID,
+		"options":    options,
+	})
+}
+
+// FinishPasskeyRegistration stores the credential created by the authenticator.
+func FinishPasskeyRegistration(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(int64)
+	type req struct {
+		SessionID  string          `json:"session_id"`
+		Name       string          `json:"name"`
+		Credential json.RawMessage `json:"credential"`
+	}
+	var body req
+	if err := c.BodyParser(&body); err != nil {
+		return responses.BadRequest(err)
+	}
+	passkey, err := app.Services.Auth.FinishPasskeyRegistration(context.Background(), userID, body.SessionID, body.Name, body.Credential)
+	if err != nil {
+		return mapPasskeyError(err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(passkey)
+}
+
+// BeginPasskeyLogin returns assertion options for a passwordless login.
+func BeginPasskeyLogin(c *fiber.Ctx) error {
+	sessionID, options, err := app.Services.Auth.BeginPasskeyLogin(context.Background())
+	if err != nil {
+		return mapPasskeyError(err)
+	}
+	return c.JSON(fiber.Map{
+		"session_id": sessionID,
+		"options":    options,
+	})
+}
+
+// FinishPasskeyLogin verifies the assertion and returns tokens like AuthLogin.
+func FinishPasskeyLogin(c *fiber.Ctx) error {
+	type req struct {
+		SessionID  string          `json:"session_id"`
+		Credential json.RawMessage `json:"credential"`
+	}
+	var body req
+	if err := c.BodyParser(&body); err != nil {
+		return responses.BadRequest(err)
+	}
+	access, refresh, user, err := app.Services.Auth.FinishPasskeyLogin(clientContext(c), body.SessionID, body.Credential)
+	if err != nil {
+		if errors.Is(err, auth.ErrEmailNotVerified()) {
+			return responses.UnAuthorized(errors.New("email_not_verified"))
+		}
+		return mapPasskeyError(err)
+	}
+	return sendLogin(c, access, refresh, user)
+}
+
+// ListPasskeys returns the passkeys registered by the current user.
+func ListPasskeys(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(int64)
+	passkeys, err := app.Services.Auth.ListPasskeys(context.Background(), userID)
+	if err != nil {
+		return responses.InternalServerError(err)
+	}
+	return c.JSON(passkeys)
+}
+
+// DeletePasskey removes one of the current user's passkeys.
+func DeletePasskey(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(int64)
+	passkeyID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return responses.BadRequest(errors.New("invalid passkey id"))
+	}
+	if err := app.Services.Auth.DeletePasskey(context.Background(), userID, passkeyID); err != nil {
+		return mapPasskeyError(err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func mapPasskeyError(err error) error {
+	switch {
+	case errors.Is(err, auth.ErrPasskeysDisabled()):
+		return responses.ServiceUnavailable(err)
+	case errors.Is(err, auth.ErrInvalidCredentials()), errors.Is(err, auth.ErrPasskeyCloneDetected()):
+		return responses.UnAuthorized(err)
+	case errors.Is(err, auth.ErrPasskeySessionInvalid()), errors.Is(err, auth.ErrPasskeyInvalid()):
+		return responses.BadRequest(err)
+	case errors.Is(err, auth.ErrPasskeyNotFound()), errors.Is(err, auth.ErrUserNotFound()):
+		return responses.NotFound(err)
+	default:
+		return responses.InternalServerError(err)
+	}
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"finlog-api/api/models/request"
+	"finlog-api/api/models/responses"
+	"finlog-api/api/services/profile"
+)
+
+// GetProfile returns the current user with their preferences.
+func GetProfile(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(int64)
+	me, err := app.Services.Profile.Get(context.Background(), userID)
+	if err != nil {
+		return mapProfileError(err)
+	}
+	return c.JSON(me)
+}
+
+// UpdateProfile changes the display name and preferences. Omitted fields keep
+// their value. The name in access tokens is only refreshed at the next sign-in.
+func UpdateProfile(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(int64)
+	var body request.UpdateProfile
+	if err := c.BodyParser(&body); err != nil {
+		return responses.BadRequest(err)
+	}
+	me, err := app.Services.Profile.Update(context.Background(), userID, body)
+	if err != nil {
+		return mapProfileError(err)
+	}
+	return c.JSON(me)
+}
+
+func mapProfileError(err error) error {
+	switch {
+	case errors.Is(err, profile.ErrInvalidName()),
+		errors.Is(err, profile.ErrInvalidLocale()),
+		errors.Is(err, profile.ErrInvalidCurrency()),
+		errors.Is(err, profile.ErrInvalidTimezone()),
+		errors.Is(err, profile.ErrInvalidFirstDayOfWeek()),
+		errors.Is(err, profile.ErrInvalidCycleStartDay()):
+		return responses.BadRequest(err)
+	case errors.Is(err, profile.ErrUserNotFound()):
+		return responses.NotFound(err)
+	default:
+		return responses.InternalServerError(err)
+	}
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"finlog-api/api/models/responses"
+	"finlog-api/api/services/changelog"
+)
+
+// GetSync returns every transaction, category and key backup changed since the
+// token from the previous call. Without a token it returns everything.
+func GetSync(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(int64)
+	limit := 0
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			return responses.BadRequest(errors.New("invalid limit"))
+		}
+		limit = parsed
+	}
+
+	page, err := app.Services.Sync.Changes(context.Background(), userID, c.Query("since"), limit)
+	if err != nil {
+		if errors.Is(err, changelog.ErrInvalidToken()) {
+			return responses.BadRequest(err)
+		}
+		return responses.InternalServerError(err)
+	}
+	return c.JSON(page)
+}
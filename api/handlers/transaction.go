@@ -3,45 +3,136 @@ package handlers
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strconv"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 
+	"finlog-api/api/contracts"
 	"finlog-api/api/models/request"
 	"finlog-api/api/models/responses"
+	"finlog-api/api/period"
 	"finlog-api/api/services/transaction"
 )
 
-// GetRecentTransactions returns the latest transactions for a given month.
+// GetRecentTransactions returns the latest transactions for a given period.
 func GetRecentTransactions(c *fiber.Ctx) error {
 	userID, _ := c.Locals("user_id").(int64)
-	year, month, err := parsePeriod(c)
+	within, err := parsePeriod(c, userID)
 	if err != nil {
-		return responses.BadRequest(err)
+		return err
 	}
-	txs, err := app.Services.Transactions.GetRecentTransactions(context.Background(), userID, year, month)
+	txs, err := app.Services.Transactions.GetRecentTransactions(context.Background(), userID, within)
 	if err != nil {
 		return responses.InternalServerError(err)
 	}
 	return c.JSON(txs)
 }
 
-// GetTransactions returns transactions for the given period.
+// GetTransactions returns every transaction in a period, given as year and month
+// or as from and to, in a plain array. Requests with cursor, limit, category_id
+// or is_expense, or without any period, get pages instead; see searchTransactions.
 func GetTransactions(c *fiber.Ctx) error {
 	userID, _ := c.Locals("user_id").(int64)
-	year, month, err := parsePeriod(c)
+	if wantsPage(c) {
+		return searchTransactions(c, userID, false)
+	}
+	within, err := parsePeriod(c, userID)
 	if err != nil {
-		return responses.BadRequest(err)
+		return err
 	}
-	txs, err := app.Services.Transactions.GetTransactions(context.Background(), userID, year, month)
+	txs, err := app.Services.Transactions.GetTransactions(context.Background(), userID, within)
 	if err != nil {
 		return responses.InternalServerError(err)
 	}
 	return c.JSON(txs)
 }
 
-// CreateTransaction stores a new transaction.
+func wantsPage(c *fiber.Ctx) bool {
+	for _, key := range []string{"cursor", "limit", "category_id", "is_expense"} {
+		if c.Query(key) != "" {
+			return true
+		}
+	}
+	for _, key := range []string{"year", "month", "from", "to"} {
+		if c.Query(key) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// searchTransactions returns a page of transactions, newest first, filtered by
+// from/to dates in the user's time zone, category_id and is_expense. The
+// response's next_cursor, passed back as cursor, fetches the following page; it
+// is omitted on the last one.
+func searchTransactions(c *fiber.Ctx, userID int64, trashed bool) error {
+	filter := contracts.TransactionFilter{Cursor: c.Query("cursor"), Trashed: trashed}
+	if c.Query("from") != "" || c.Query("to") != "" {
+		prefs, err := app.Services.Profile.Preferences(context.Background(), userID)
+		if err != nil {
+			return responses.InternalServerError(err)
+		}
+		loc, err := periodLocation(c, prefs.Timezone)
+		if err != nil {
+			return err
+		}
+		if raw := c.Query("from"); raw != "" {
+			from, err := time.Parse(time.DateOnly, raw)
+			if err != nil {
+				return responses.BadRequest(errors.New("invalid from, expected YYYY-MM-DD"))
+			}
+			start := period.Days(from, from, loc).Start
+			filter.From = &start
+		}
+		if raw := c.Query("to"); raw != "" {
+			to, err := time.Parse(time.DateOnly, raw)
+			if err != nil {
+				return responses.BadRequest(errors.New("invalid to, expected YYYY-MM-DD"))
+			}
+			end := period.Days(to, to, loc).End
+			filter.To = &end
+		}
+	}
+	if raw := c.Query("category_id"); raw != "" {
+		categoryID, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || categoryID <= 0 {
+			return responses.BadRequest(errors.New("invalid category_id"))
+		}
+		filter.CategoryID = &categoryID
+	}
+	if raw := c.Query("is_expense"); raw != "" {
+		isExpense, err := strconv.ParseBool(raw)
+		if err != nil {
+			return responses.BadRequest(errors.New("invalid is_expense"))
+		}
+		filter.IsExpense = &isExpense
+	}
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			return responses.BadRequest(errors.New("invalid limit"))
+		}
+		filter.Limit = limit
+	}
+
+	txs, next, err := app.Services.Transactions.SearchTransactions(context.Background(), userID, filter)
+	if err != nil {
+		if errors.Is(err, transaction.ErrInvalidCursor()) {
+			return responses.BadRequest(err)
+		}
+		return responses.InternalServerError(err)
+	}
+	response := fiber.Map{"transactions": txs}
+	if next != "" {
+		response["next_cursor"] = next
+	}
+	return c.JSON(response)
+}
+
+// CreateTransaction stores a new transaction. A replayed uuid answers 200 with
+// the transaction stored the first time.
 func CreateTransaction(c *fiber.Ctx) error {
 	userID, _ := c.Locals("user_id").(int64)
 
@@ -57,21 +148,49 @@ func CreateTransaction(c *fiber.Ctx) error {
 
 	body.OccurredAt = occurredAt
 
-	tx, err := app.Services.Transactions.CreateTransaction(context.Background(), userID, body)
+	tx, created, err := app.Services.Transactions.CreateTransaction(context.Background(), userID, body)
 	if err != nil {
 		return mapTransactionError(err)
 	}
+	setETag(c, tx.Version)
+	if !created {
+		return c.JSON(tx)
+	}
 
 	return c.Status(fiber.StatusCreated).JSON(tx)
 }
 
-// UpdateTransaction updates a transaction by id.
+// GetTransactionTrash pages through deleted transactions with the same filters
+// as GetTransactions. Trashed transactions are purged after the retention period.
+func GetTransactionTrash(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(int64)
+	return searchTransactions(c, userID, true)
+}
+
+// GetTransaction returns a transaction by id or uuid, with its version as ETag.
+func GetTransaction(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(int64)
+	id, err := transactionID(c, userID)
+	if err != nil {
+		return err
+	}
+	tx, err := app.Services.Transactions.GetTransaction(context.Background(), userID, id)
+	if err != nil {
+		return mapTransactionError(err)
+	}
+	setETag(c, tx.Version)
+	return c.JSON(tx)
+}
+
+// UpdateTransaction updates a transaction by id or uuid. The write only applies
+// to the version in If-Match or the body; a stale version answers 409 with the
+// stored transaction.
 func UpdateTransaction(c *fiber.Ctx) error {
 	userID, _ := c.Locals("user_id").(int64)
 
-	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	id, err := transactionID(c, userID)
 	if err != nil {
-		return responses.BadRequest(errors.New("invalid transaction id"))
+		return err
 	}
 
 	body := request.CreateTransaction{}
@@ -85,11 +204,27 @@ func UpdateTransaction(c *fiber.Ctx) error {
 	}
 
 	body.OccurredAt = occurredAt
+	body.Version, err = expectedVersion(c, body.Version, func() (int64, error) {
+		current, err := app.Services.Transactions.GetTransaction(context.Background(), userID, id)
+		if err != nil {
+			return 0, mapTransactionError(err)
+		}
+		return current.Version, nil
+	})
+	if err != nil {
+		return err
+	}
 
-	if err := app.Services.Transactions.UpdateTransaction(context.Background(), userID, id, body); err != nil {
+	tx, err := app.Services.Transactions.UpdateTransaction(context.Background(), userID, id, body)
+	if err != nil {
+		var conflict *transaction.VersionConflictError
+		if errors.As(err, &conflict) {
+			return versionConflict(c, err, conflict.Current, conflict.Current.Version)
+		}
 		return mapTransactionError(err)
 	}
-	return c.SendStatus(fiber.StatusOK)
+	setETag(c, tx.Version)
+	return c.JSON(tx)
 }
 
 // UpdateTransactionNotes updates notes for multiple transactions.
@@ -159,12 +294,12 @@ func UpdateTransactionDate(c *fiber.Ctx) error {
 	return c.SendStatus(fiber.StatusOK)
 }
 
-// DeleteTransaction deletes a transaction by id.
+// DeleteTransaction moves a transaction, by id or uuid, to the trash.
 func DeleteTransaction(c *fiber.Ctx) error {
 	userID, _ := c.Locals("user_id").(int64)
-	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	id, err := transactionID(c, userID)
 	if err != nil {
-		return responses.BadRequest(errors.New("invalid transaction id"))
+		return err
 	}
 	if err := app.Services.Transactions.DeleteTransaction(context.Background(), userID, id); err != nil {
 		return mapTransactionError(err)
@@ -172,7 +307,7 @@ func DeleteTransaction(c *fiber.Ctx) error {
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
-// DeleteTransactions deletes transactions in bulk.
+// DeleteTransactions moves transactions to the trash in bulk.
 func DeleteTransactions(c *fiber.Ctx) error {
 	userID, _ := c.Locals("user_id").(int64)
 	type req struct {
@@ -192,18 +327,123 @@ func DeleteTransactions(c *fiber.Ctx) error {
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
-func parsePeriod(c *fiber.Ctx) (int, int, error) {
+// RestoreTransaction takes a transaction, by id or uuid, out of the trash.
+func RestoreTransaction(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(int64)
+	id, err := transactionID(c, userID)
+	if err != nil {
+		return err
+	}
+	if err := app.Services.Transactions.RestoreTransaction(context.Background(), userID, id); err != nil {
+		return mapTransactionError(err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// RestoreTransactions takes transactions out of the trash in bulk.
+func RestoreTransactions(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(int64)
+	type req struct {
+		TransactionIDs []string `json:"transactionIds"`
+	}
+	var body req
+	if err := c.BodyParser(&body); err != nil {
+		return responses.BadRequest(err)
+	}
+	ids, err := parseIDs(body.TransactionIDs)
+	if err != nil {
+		return responses.BadRequest(err)
+	}
+	if err := app.Services.Transactions.RestoreTransactions(context.Background(), userID, ids); err != nil {
+		return mapTransactionError(err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// maxRangeDays bounds from/to ranges, which are returned unpaged.
+const maxRangeDays = 366
+
+// parsePeriod resolves the requested period in the user's time zone: the tz query
+// parameter when given, otherwise their stored preference. A from/to pair of
+// dates selects those days inclusive. Otherwise year and month select the budget
+// cycle starting on start_day, or on the user's cycle start day; with the default
+// of 1 that is the calendar month.
+func parsePeriod(c *fiber.Ctx, userID int64) (period.Range, error) {
+	prefs, err := app.Services.Profile.Preferences(context.Background(), userID)
+	if err != nil {
+		return period.Range{}, responses.InternalServerError(err)
+	}
+	loc, err := periodLocation(c, prefs.Timezone)
+	if err != nil {
+		return period.Range{}, err
+	}
+	if c.Query("from") != "" || c.Query("to") != "" {
+		return parseDateRange(c, loc)
+	}
+
 	yearStr := c.Query("year")
 	monthStr := c.Query("month")
 	year, err := strconv.Atoi(yearStr)
 	if err != nil || year <= 0 {
-		return 0, 0, errors.New("invalid year")
+		return period.Range{}, responses.BadRequest(errors.New("invalid year"))
 	}
 	month, err := strconv.Atoi(monthStr)
 	if err != nil || month < 1 || month > 12 {
-		return 0, 0, errors.New("invalid month")
+		return period.Range{}, responses.BadRequest(errors.New("invalid month"))
+	}
+	startDay := prefs.CycleStartDay
+	if raw := c.Query("start_day"); raw != "" {
+		startDay, err = strconv.Atoi(raw)
+		if err != nil || startDay < 1 || startDay > 31 {
+			return period.Range{}, responses.BadRequest(errors.New("invalid start_day"))
+		}
+	}
+	return period.Cycle(year, time.Month(month), startDay, loc), nil
+}
+
+func periodLocation(c *fiber.Ctx, preferred string) (*time.Location, error) {
+	if tz := c.Query("tz"); tz != "" {
+		loc, err := period.LoadLocation(tz)
+		if err != nil {
+			return nil, responses.BadRequest(errors.New("invalid tz"))
+		}
+		return loc, nil
+	}
+	loc, err := period.LoadLocation(preferred)
+	if err != nil {
+		return nil, responses.InternalServerError(err)
+	}
+	return loc, nil
+}
+
+func parseDateRange(c *fiber.Ctx, loc *time.Location) (period.Range, error) {
+	from, err := time.Parse(time.DateOnly, c.Query("from"))
+	if err != nil {
+		return period.Range{}, responses.BadRequest(errors.New("invalid from, expected YYYY-MM-DD"))
+	}
+	to, err := time.Parse(time.DateOnly, c.Query("to"))
+	if err != nil {
+		return period.Range{}, responses.BadRequest(errors.New("invalid to, expected YYYY-MM-DD"))
+	}
+	if to.Before(from) {
+		return period.Range{}, responses.BadRequest(errors.New("to must not be before from"))
+	}
+	if to.Sub(from) >= maxRangeDays*24*time.Hour {
+		return period.Range{}, responses.BadRequest(fmt.Errorf("range must not exceed %d days", maxRangeDays))
 	}
-	return year, month, nil
+	return period.Days(from, to, loc), nil
+}
+
+// transactionID resolves the :id route parameter, which may also be a uuid.
+func transactionID(c *fiber.Ctx, userID int64) (int64, error) {
+	id, err := app.Services.Transactions.ResolveTransactionID(context.Background(), userID, c.Params("id"))
+	if err != nil {
+		if errors.Is(err, transaction.ErrInvalidTransaction()) {
+			return 0, responses.BadRequest(errors.New("invalid transaction id"))
+		}
+		return 0, mapTransactionError(err)
+	}
+	return id, nil
 }
 
 func parseIDs(ids []string) ([]int64, error) {
@@ -226,8 +466,14 @@ func mapTransactionError(err error) error {
 		return responses.NotFound(err)
 	case errors.Is(err, transaction.ErrCategoryNotFound()):
 		return responses.BadRequest(err)
+	case errors.Is(err, transaction.ErrInvalidUUID()):
+		return responses.BadRequest(err)
+	case errors.Is(err, transaction.ErrVersionRequired()):
+		return responses.PreconditionRequired(err)
 	case errors.Is(err, transaction.ErrUnsupportedBulk()):
 		return responses.BadRequest(err)
+	case errors.Is(err, transaction.ErrTransactionTrashed()):
+		return responses.Conflict(err)
 	default:
 		return responses.BadRequest(err)
 	}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestWantsPage(t *testing.T) {
+	cases := []struct {
+		query string
+		want  bool
+	}{
+		{"", true},
+		{"year=2024&month=5", false},
+		{"from=2024-05-01&to=2024-05-31", false},
+		{"from=2024-05-01", false},
+		{"from=2024-05-01&to=2024-05-31&limit=20", true},
+		{"year=2024&month=5&cursor=abc", true},
+		{"category_id=3", true},
+		{"from=2024-05-01&is_expense=true", true},
+	}
+	for _, tc := range cases {
+		var got bool
+		f := fiber.New()
+		f.Get("/", func(c *fiber.Ctx) error {
+			got = wantsPage(c)
+			return nil
+		})
+		if _, err := f.Test(httptest.NewRequest(http.MethodGet, "/?"+tc.query, nil)); err != nil {
+			t.Fatalf("%q: %v", tc.query, err)
+		}
+		if got != tc.want {
+			t.Errorf("%q: expected wantsPage %v, got %v", tc.query, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package helpers
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+
+	"finlog-api/api/entities"
+)
+
+const (
+	DeviceNameHeader    = "X-Device-Name"
+	maxDeviceNameLength = 100
+	maxUserAgentLength  = 255
+)
+
+type clientInfoKey struct{}
+
+// ClientInfoFromRequest collects the device details used for session tracking.
+func ClientInfoFromRequest(c *fiber.Ctx) entities.ClientInfo {
+	ip := c.IP()
+	if ips := c.IPs(); len(ips) > 0 {
+		ip = ips[0]
+	}
+	return entities.ClientInfo{
+		DeviceName: truncate(c.Get(DeviceNameHeader), maxDeviceNameLength),
+		UserAgent:  truncate(c.Get(fiber.HeaderUserAgent), maxUserAgentLength),
+		IPAddress:  ip,
+	}
+}
+
+// WithClientInfo attaches client details to ctx for the services.
+func WithClientInfo(ctx context.Context, info entities.ClientInfo) context.Context {
+	return context.WithValue(ctx, clientInfoKey{}, info)
+}
+
+// ClientInfoFrom returns the client details stored by WithClientInfo, if any.
+func ClientInfoFrom(ctx context.Context) entities.ClientInfo {
+	info, _ := ctx.Value(clientInfoKey{}).(entities.ClientInfo)
+	return info
+}
+
+func truncate(value string, max int) string {
+	runes := []rune(value)
+	if len(runes) > max {
+		return string(runes[:max])
+	}
+	return value
+}
@@ -0,0 +1,40 @@
+package helpers
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strings"
+)
+
+// NewUUID returns a random (version 4) UUID in its canonical form.
+func NewUUID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	buf[6] = buf[6]&0x0f | 0x40
+	buf[8] = buf[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", buf[0:4], buf[4:6], buf[6:8], buf[8:10], buf[10:16]), nil
+}
+
+// NormalizeUUID lower-cases a UUID in the 8-4-4-4-12 hex form. It reports false
+// for anything else.
+func NormalizeUUID(value string) (string, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if len(value) != 36 {
+		return "", false
+	}
+	for i, r := range value {
+		switch i {
+		case 8, 13, 18, 23:
+			if r != '-' {
+				return "", false
+			}
+		default:
+			if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'f') {
+				return "", false
+			}
+		}
+	}
+	return value, true
+}
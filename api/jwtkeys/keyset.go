@@ -0,0 +1,267 @@
+// Package jwtkeys holds the keys used to sign and verify the API's JWTs.
+//
+// Keys are configured in JWT_KEYS as a JSON array:
+//
+//	[{"kid":"2026-10","alg":"EdDSA","private_key":"<base64 PKCS#8 DER or PEM>"},
+//	 {"kid":"2026-04","alg":"RS256","public_key":"<base64 PKIX DER or PEM>","retire_at":"2026-11-15T00:00:00Z"}]
+//
+// JWT_SIGNING_KID picks the key new tokens are signed with, defaulting to the
+// first entry that has a private key. Every other key keeps verifying tokens until
+// its retire_at, which gives rotations an overlap window. Without JWT_KEYS tokens
+// stay HS256 with JWT_SECRET and REFRESH_SECRET; once keys are configured those
+// legacy tokens are still accepted until JWT_LEGACY_UNTIL.
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"finlog-api/api/constants"
+)
+
+// Purpose selects the legacy HMAC secret for a token. Asymmetric keys sign every
+// purpose; the typ claim tells the tokens apart.
+type Purpose int
+
+const (
+	PurposeAccess Purpose = iota
+	PurposeRefresh
+)
+
+const (
+	AlgEdDSA = "EdDSA"
+	AlgRS256 = "RS256"
+)
+
+var errInvalidToken = errors.New("invalid token")
+
+func ErrInvalidToken() error { return errInvalidToken }
+
+// Key is one configured signing or verification key.
+type Key struct {
+	ID        string
+	Algorithm string
+	RetireAt  time.Time
+
+	method  jwt.SigningMethod
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+// Keyset signs new tokens with the current key and verifies tokens signed by any
+// key that has not retired yet.
+type Keyset struct {
+	signing     *Key
+	keys        map[string]*Key
+	secrets     map[Purpose][]byte
+	legacyUntil time.Time
+	now         func() time.Time
+}
+
+type keyConfig struct {
+	ID         string `json:"kid"`
+	Algorithm  string `json:"alg"`
+	PrivateKey string `json:"private_key"`
+	PublicKey  string `json:"public_key"`
+	RetireAt   string `json:"retire_at"`
+}
+
+// Load builds the keyset from the application config.
+func Load(config map[string]string) (*Keyset, error) {
+	ks := &Keyset{
+		keys: map[string]*Key{},
+		secrets: map[Purpose][]byte{
+			PurposeAccess:  []byte(config[constants.JWT_SECRET]),
+			PurposeRefresh: []byte(config[constants.REFRESH_SECRET]),
+		},
+		now: time.Now,
+	}
+
+	if raw := strings.TrimSpace(config[constants.JWT_LEGACY_UNTIL]); raw != "" {
+		until, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return nil, fmt.Errorf("JWT_LEGACY_UNTIL: %w", err)
+		}
+		ks.legacyUntil = until
+	}
+
+	raw := strings.TrimSpace(config[constants.JWT_KEYS])
+	if raw == "" {
+		return ks, nil
+	}
+	var entries []keyConfig
+	if err := json.Unmarshal([]byte(raw), &entries); err != nil {
+		return nil, fmt.Errorf("JWT_KEYS: %w", err)
+	}
+	var order []*Key
+	for _, entry := range entries {
+		key, err := parseKey(entry)
+		if err != nil {
+			return nil, fmt.Errorf("JWT_KEYS %q: %w", entry.ID, err)
+		}
+		if _, exists := ks.keys[key.ID]; exists {
+			return nil, fmt.Errorf("JWT_KEYS: duplicate kid %q", key.ID)
+		}
+		ks.keys[key.ID] = key
+		order = append(order, key)
+	}
+
+	if kid := strings.TrimSpace(config[constants.JWT_SIGNING_KID]); kid != "" {
+		key, ok := ks.keys[kid]
+		if !ok || key.private == nil {
+			return nil, fmt.Errorf("JWT_SIGNING_KID %q has no private key in JWT_KEYS", kid)
+		}
+		ks.signing = key
+	} else {
+		for _, key := range order {
+			if key.private != nil {
+				ks.signing = key
+				break
+			}
+		}
+		if ks.signing == nil {
+			return nil, errors.New("JWT_KEYS has no private key to sign with")
+		}
+	}
+	return ks, nil
+}
+
+// Sign returns a signed token for claims, tagged with the signing key's kid.
+func (ks *Keyset) Sign(claims jwt.Claims, purpose Purpose) (string, error) {
+	if ks.signing == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(ks.secrets[purpose])
+	}
+	token := jwt.NewWithClaims(ks.signing.method, claims)
+	token.Header["kid"] = ks.signing.ID
+	return token.SignedString(ks.signing.private)
+}
+
+// Parse verifies raw and returns its claims. Expiry is checked by the jwt library.
+func (ks *Keyset) Parse(raw string, purpose Purpose) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(raw, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); ok {
+			if !ks.acceptsLegacy() {
+				return nil, errInvalidToken
+			}
+			return ks.secrets[purpose], nil
+		}
+		kid, _ := t.Header["kid"].(string)
+		key, ok := ks.keys[kid]
+		if !ok || t.Method.Alg() != key.method.Alg() || ks.retired(key) {
+			return nil, errInvalidToken
+		}
+		return key.public, nil
+	}, jwt.WithValidMethods([]string{AlgEdDSA, AlgRS256, jwt.SigningMethodHS256.Alg()}))
+	if err != nil || !token.Valid {
+		return nil, errInvalidToken
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errInvalidToken
+	}
+	return claims, nil
+}
+
+// acceptsLegacy reports whether HS256 tokens are still valid: always without
+// asymmetric keys, and until JWT_LEGACY_UNTIL after switching to them.
+func (ks *Keyset) acceptsLegacy() bool {
+	if ks.signing == nil {
+		return true
+	}
+	return ks.now().Before(ks.legacyUntil)
+}
+
+func (ks *Keyset) retired(key *Key) bool {
+	return !key.RetireAt.IsZero() && !ks.now().Before(key.RetireAt)
+}
+
+func parseKey(entry keyConfig) (*Key, error) {
+	key := &Key{ID: strings.TrimSpace(entry.ID), Algorithm: entry.Algorithm}
+	if key.ID == "" {
+		return nil, errors.New("kid is required")
+	}
+	switch entry.Algorithm {
+	case AlgEdDSA:
+		key.method = jwt.SigningMethodEdDSA
+	case AlgRS256:
+		key.method = jwt.SigningMethodRS256
+	default:
+		return nil, fmt.Errorf("unsupported alg %q", entry.Algorithm)
+	}
+	if entry.RetireAt != "" {
+		retireAt, err := time.Parse(time.RFC3339, entry.RetireAt)
+		if err != nil {
+			return nil, fmt.Errorf("retire_at: %w", err)
+		}
+		key.RetireAt = retireAt
+	}
+
+	switch {
+	case entry.PrivateKey != "":
+		der, err := decodeDER(entry.PrivateKey)
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := x509.ParsePKCS8PrivateKey(der)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := parsed.(crypto.Signer)
+		if !ok {
+			return nil, errors.New("private key cannot sign")
+		}
+		key.private = signer
+		key.public = signer.Public()
+	case entry.PublicKey != "":
+		der, err := decodeDER(entry.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		public, err := x509.ParsePKIXPublicKey(der)
+		if err != nil {
+			return nil, err
+		}
+		key.public = public
+	default:
+		return nil, errors.New("private_key or public_key is required")
+	}
+
+	switch key.public.(type) {
+	case ed25519.PublicKey:
+		if key.Algorithm != AlgEdDSA {
+			return nil, fmt.Errorf("ed25519 key cannot be used with %s", key.Algorithm)
+		}
+	case *rsa.PublicKey:
+		if key.Algorithm != AlgRS256 {
+			return nil, fmt.Errorf("rsa key cannot be used with %s", key.Algorithm)
+		}
+	default:
+		return nil, errors.New("unsupported key type")
+	}
+	return key, nil
+}
+
+// decodeDER accepts either a PEM block or base64 DER, since PEM newlines are
+// awkward to carry in env files.
+func decodeDER(value string) ([]byte, error) {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "-----BEGIN") {
+		block, _ := pem.Decode([]byte(value))
+		if block == nil {
+			return nil, errors.New("invalid PEM block")
+		}
+		return block.Bytes, nil
+	}
+	return base64.StdEncoding.DecodeString(value)
+}
@@ -0,0 +1,130 @@
+package jwtkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func ed25519Entry(t *testing.T, kid string) (keyConfig, keyConfig) {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	privateDER, _ := x509.MarshalPKCS8PrivateKey(private)
+	publicDER, _ := x509.MarshalPKIXPublicKey(public)
+	return keyConfig{ID: kid, Algorithm: AlgEdDSA, PrivateKey: base64.StdEncoding.EncodeToString(privateDER)},
+		keyConfig{ID: kid, Algorithm: AlgEdDSA, PublicKey: base64.StdEncoding.EncodeToString(publicDER)}
+}
+
+func load(t *testing.T, config map[string]string, entries ...keyConfig) *Keyset {
+	t.Helper()
+	if len(entries) > 0 {
+		raw, _ := json.Marshal(entries)
+		config["JWT_KEYS"] = string(raw)
+	}
+	ks, err := Load(config)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	return ks
+}
+
+func claims() jwt.MapClaims {
+	return jwt.MapClaims{"sub": 1, "typ": "access", "exp": time.Now().Add(time.Hour).Unix()}
+}
+
+func TestSignsWithKeyIDAndVerifies(t *testing.T) {
+	signing, _ := ed25519Entry(t, "2026-10")
+	ks := load(t, map[string]string{}, signing)
+
+	raw, err := ks.Sign(claims(), PurposeAccess)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	token, _, _ := jwt.NewParser().ParseUnverified(raw, jwt.MapClaims{})
+	if token.Header["kid"] != "2026-10" || token.Method.Alg() != AlgEdDSA {
+		t.Fatalf("unexpected header %v", token.Header)
+	}
+	if _, err := ks.Parse(raw, PurposeRefresh); err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+}
+
+func TestRotationKeepsOldKeyUntilRetired(t *testing.T) {
+	oldSigning, oldPublic := ed25519Entry(t, "old")
+	newSigning, _ := ed25519Entry(t, "new")
+	before := load(t, map[string]string{}, oldSigning)
+	issued, _ := before.Sign(claims(), PurposeAccess)
+
+	oldPublic.RetireAt = time.Now().Add(time.Hour).Format(time.RFC3339)
+	after := load(t, map[string]string{}, newSigning, oldPublic)
+	if _, err := after.Parse(issued, PurposeAccess); err != nil {
+		t.Fatalf("token from the previous key should verify during the overlap: %v", err)
+	}
+	if got := after.JWKS().Keys; len(got) != 2 {
+		t.Fatalf("expected both keys published, got %+v", got)
+	}
+
+	after.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if _, err := after.Parse(issued, PurposeAccess); err == nil {
+		t.Fatalf("expected retired key to be rejected")
+	}
+	if got := after.JWKS().Keys; len(got) != 1 || got[0].KeyID != "new" {
+		t.Fatalf("expected only the new key published, got %+v", got)
+	}
+}
+
+func TestLegacyHMACTokensAcceptedUntilCutoff(t *testing.T) {
+	secrets := map[string]string{"JWT_SECRET": "secret", "REFRESH_SECRET": "refresh"}
+	legacy := load(t, secrets)
+	issued, _ := legacy.Sign(claims(), PurposeAccess)
+	if _, err := legacy.Parse(issued, PurposeRefresh); err == nil {
+		t.Fatalf("expected access secret not to verify refresh tokens")
+	}
+
+	signing, _ := ed25519Entry(t, "2026-10")
+	cutover := map[string]string{
+		"JWT_SECRET":       "secret",
+		"REFRESH_SECRET":   "refresh",
+		"JWT_LEGACY_UNTIL": time.Now().Add(time.Hour).Format(time.RFC3339),
+	}
+	ks := load(t, cutover, signing)
+	if _, err := ks.Parse(issued, PurposeAccess); err != nil {
+		t.Fatalf("expected legacy token to verify before the cutoff: %v", err)
+	}
+	ks.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if _, err := ks.Parse(issued, PurposeAccess); err == nil {
+		t.Fatalf("expected legacy token to be rejected after the cutoff")
+	}
+}
+
+func TestRejectsAlgorithmMismatch(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	der, _ := x509.MarshalPKCS8PrivateKey(rsaKey)
+	entry := keyConfig{ID: "rsa", Algorithm: AlgEdDSA, PrivateKey: base64.StdEncoding.EncodeToString(der)}
+	raw, _ := json.Marshal([]keyConfig{entry})
+	if _, err := Load(map[string]string{"JWT_KEYS": string(raw)}); err == nil {
+		t.Fatalf("expected an RSA key declared as EdDSA to be rejected")
+	}
+
+	entry.Algorithm = AlgRS256
+	ks := load(t, map[string]string{}, entry)
+	signed, _ := ks.Sign(claims(), PurposeAccess)
+	token, _, _ := jwt.NewParser().ParseUnverified(signed, jwt.MapClaims{})
+	if token.Method.Alg() != AlgRS256 {
+		t.Fatalf("expected RS256, got %s", token.Method.Alg())
+	}
+	jwk := ks.JWKS().Keys[0]
+	if jwk.KeyType != "RSA" || jwk.E != "AQAB" {
+		t.Fatalf("unexpected jwk %+v", jwk)
+	}
+}
@@ -1,37 +1,54 @@
 package middlewares
 
 import (
-	"fmt"
+	"context"
+	"errors"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
+
+	"finlog-api/api/helpers"
+	"finlog-api/api/jwtkeys"
+	"finlog-api/api/services/accesstoken"
+	"finlog-api/api/services/auth"
 )
 
 // JWT protects private routes and renews access token on each request (sliding expiration).
-func JWT(secret []byte, ttl time.Duration) fiber.Handler {
+// Personal access tokens are accepted too; see RequireScope and RequireSession.
+func JWT(keys *jwtkeys.Keyset, ttl time.Duration) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		authHeader := c.Get("Authorization")
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 {
 			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "missing token"})
 		}
-		token, err := jwt.Parse(parts[1], func(t *jwt.Token) (interface{}, error) {
-			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method")
-			}
-			return secret, nil
-		})
-		if err != nil || !token.Valid {
+		if strings.HasPrefix(parts[1], accesstoken.TokenPrefix) {
+			return authenticateAccessToken(c, parts[1])
+		}
+		claims, err := keys.Parse(parts[1], jwtkeys.PurposeAccess)
+		if err != nil {
 			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "invalid token"})
 		}
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
+		// MFA challenge tokens share the signing key; only access tokens may pass.
+		if typ, _ := claims["typ"].(string); typ != "access" {
 			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "invalid token"})
 		}
-		c.Locals("user_id", int64(claims["sub"].(float64)))
+		userID := int64(claims["sub"].(float64))
+		family, _ := claims["fam"].(string)
+		// Access tokens are renewed on every request, so revocation must be checked here.
+		ctx := helpers.WithClientInfo(context.Background(), helpers.ClientInfoFromRequest(c))
+		if err := app.Services.Auth.ValidateSession(ctx, userID, family); err != nil {
+			if errors.Is(err, auth.ErrSessionRevoked()) {
+				return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "session revoked"})
+			}
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "session check failed"})
+		}
+
+		c.Locals("user_id", userID)
 		if name, ok := claims["name"].(string); ok {
 			c.Locals("user_name", name)
 		}
@@ -41,6 +58,7 @@ func JWT(secret []byte, ttl time.Duration) fiber.Handler {
 		if role, ok := claims["role"].(string); ok {
 			c.Locals("user_role", role)
 		}
+		c.Locals("token_family", family)
 
 		// Renew access token and send back via header for the client to update.
 		newClaims := jwt.MapClaims{
@@ -48,9 +66,11 @@ func JWT(secret []byte, ttl time.Duration) fiber.Handler {
 			"email": claims["email"],
 			"name":  claims["name"],
 			"role":  claims["role"],
+			"fam":   claims["fam"],
+			"typ":   claims["typ"],
 			"exp":   time.Now().Add(ttl).Unix(),
 		}
-		if newToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, newClaims).SignedString(secret); err == nil {
+		if newToken, err := keys.Sign(newClaims, jwtkeys.PurposeAccess); err == nil {
 			c.Set("X-Access-Token", newToken)
 		}
 
@@ -58,15 +78,69 @@ func JWT(secret []byte, ttl time.Duration) fiber.Handler {
 	}
 }
 
-// RequireRole checks role against allowed list.
-func RequireRole(allowed ...string) fiber.Handler {
+// authenticateAccessToken sets the same locals as a session login plus the token's
+// scopes. Access tokens are never renewed.
+func authenticateAccessToken(c *fiber.Ctx, raw string) error {
+	token, user, err := app.Services.AccessTokens.Authenticate(context.Background(), raw)
+	if err != nil {
+		if errors.Is(err, accesstoken.ErrInvalidToken()) {
+			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "invalid token"})
+		}
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "token check failed"})
+	}
+
+	c.Locals("user_id", user.ID)
+	c.Locals("user_name", user.Name)
+	c.Locals("user_email", user.Email)
+	c.Locals("user_role", user.Role)
+	c.Locals("token_family", "")
+	c.Locals("access_token_id", token.ID)
+	c.Locals("token_scopes", token.ScopeList)
+	return c.Next()
+}
+
+// RequireScope lets personal access tokens through only when they carry every
+// listed scope. Session logins have full access and always pass.
+func RequireScope(scopes ...string) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		role, _ := c.Locals("user_role").(string)
-		for _, a := range allowed {
-			if role == a {
-				return c.Next()
+		granted, isToken := c.Locals("token_scopes").([]string)
+		if !isToken {
+			return c.Next()
+		}
+		for _, scope := range scopes {
+			if !slices.Contains(granted, scope) {
+				return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "insufficient scope"})
 			}
 		}
-		return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "forbidden"})
+		return c.Next()
+	}
+}
+
+// RequireSession rejects personal access tokens. Routers install it on the group
+// that holds every route without a RequireScope.
+func RequireSession() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if _, isToken := c.Locals("token_scopes").([]string); isToken {
+			return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "access tokens cannot use this endpoint"})
+		}
+		return c.Next()
+	}
+}
+
+// RequireRole checks the user's current role against the allowed list. The role
+// claim in the token is copied on every renewal, so the database is consulted
+// instead; a demoted or disabled admin loses access on the next request.
+func RequireRole(allowed ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, _ := c.Locals("user_id").(int64)
+		role, err := app.Services.Admin.CurrentRole(context.Background(), userID)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "role check failed"})
+		}
+		if role == "" || !slices.Contains(allowed, role) {
+			return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "forbidden"})
+		}
+		c.Locals("user_role", role)
+		return c.Next()
 	}
 }
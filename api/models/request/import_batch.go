@@ -5,6 +5,7 @@ type ImportBatchRequest struct {
 }
 
 type ImportBatchItem struct {
+	UUID       string `json:"uuid"`
 	Ciphertext string `json:"ciphertext"`
 	Nonce      string `json:"nonce"`
 	Tag        string `json:"tag"`
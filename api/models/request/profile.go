@@ -0,0 +1,11 @@
+package request
+
+// UpdateProfile changes only the fields that are present.
+type UpdateProfile struct {
+	Name           *string `json:"name"`
+	Locale         *string `json:"locale"`
+	Currency       *string `json:"currency"`
+	Timezone       *string `json:"timezone"`
+	FirstDayOfWeek *int    `json:"first_day_of_week"`
+	CycleStartDay  *int    `json:"cycle_start_day"`
+}
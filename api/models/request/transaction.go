@@ -3,6 +3,11 @@ package request
 import "time"
 
 type CreateTransaction struct {
+	// UUID makes a create safe to retry. It is ignored on update.
+	UUID string `json:"uuid"`
+	// Version is the version the client last saw, required on update unless
+	// If-Match carries it.
+	Version    int64     `json:"version"`
 	Ciphertext string    `json:"ciphertext" validate:"required"`
 	Nonce      string    `json:"nonce" validate:"required"`
 	Tag        string    `json:"tag" validate:"required"`
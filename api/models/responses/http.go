@@ -82,3 +82,47 @@ func UnAuthorized(err error) *ErrorResponse {
 func (e *ErrorResponse) Error() string {
 	return e.Debug
 }
+
+func ServiceUnavailable(err error) *ErrorResponse {
+	return &ErrorResponse{
+		Response: Response{
+			Status:  fiber.ErrServiceUnavailable.Code,
+			Data:    nil,
+			Message: err.Error(),
+		},
+		Debug: err.Error(),
+	}
+}
+
+func TooManyRequests(err error) *ErrorResponse {
+	return &ErrorResponse{
+		Response: Response{
+			Status:  fiber.ErrTooManyRequests.Code,
+			Data:    nil,
+			Message: err.Error(),
+		},
+		Debug: err.Error(),
+	}
+}
+
+func UnprocessableEntity(err error) *ErrorResponse {
+	return &ErrorResponse{
+		Response: Response{
+			Status:  fiber.ErrUnprocessableEntity.Code,
+			Data:    nil,
+			Message: err.Error(),
+		},
+		Debug: err.Error(),
+	}
+}
+
+func PreconditionRequired(err error) *ErrorResponse {
+	return &ErrorResponse{
+		Response: Response{
+			Status:  fiber.StatusPreconditionRequired,
+			Data:    nil,
+			Message: err.Error(),
+		},
+		Debug: err.Error(),
+	}
+}
@@ -0,0 +1,89 @@
+// Package period turns the calendar periods users ask for into the instants
+// transactions are filtered by. Boundaries are computed in the user's time zone,
+// so a transaction at 23:30 on the last day of the month stays in that month
+// wherever the server runs.
+package period
+
+import (
+	"errors"
+	"time"
+)
+
+var errInvalidLocation = errors.New("invalid time zone")
+
+func ErrInvalidLocation() error { return errInvalidLocation }
+
+// Range is the half-open interval [Start, End), in UTC.
+type Range struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Valid reports whether the range is non-empty.
+func (r Range) Valid() bool {
+	return r.End.After(r.Start)
+}
+
+// Contains reports whether t falls inside the range.
+func (r Range) Contains(t time.Time) bool {
+	return !t.Before(r.Start) && t.Before(r.End)
+}
+
+// Month returns the calendar month in loc. Months that contain a daylight saving
+// change are an hour shorter or longer than their days suggest.
+func Month(year int, month time.Month, loc *time.Location) Range {
+	return Cycle(year, month, 1, loc)
+}
+
+// Cycle returns the budget cycle that starts on startDay of the given month and
+// runs until the next month's cycle starts, such as 25 January to 24 February
+// for a user paid on the 25th. A start day past the end of a short month falls on
+// its last day.
+func Cycle(year int, month time.Month, startDay int, loc *time.Location) Range {
+	return Range{
+		Start: startOfDay(year, month, clampDay(year, month, startDay), loc),
+		End:   startOfDay(year, month+1, clampDay(year, month+1, startDay), loc),
+	}
+}
+
+// Days returns the days from first to last inclusive. Only the dates of first and
+// last are used; they are read in loc.
+func Days(first, last time.Time, loc *time.Location) Range {
+	firstYear, firstMonth, firstDay := first.Date()
+	lastYear, lastMonth, lastDay := last.Date()
+	return Range{
+		Start: startOfDay(firstYear, firstMonth, firstDay, loc),
+		End:   startOfDay(lastYear, lastMonth, lastDay+1, loc),
+	}
+}
+
+func clampDay(year int, month time.Month, day int) int {
+	lastDay := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	return max(1, min(day, lastDay))
+}
+
+// startOfDay returns local midnight. Where a daylight saving change skips
+// midnight (Paraguay, Chile and others have done so), time.Date lands on the
+// previous day; the day then starts at the transition.
+func startOfDay(year int, month time.Month, day int, loc *time.Location) time.Time {
+	t := time.Date(year, month, day, 0, 0, 0, 0, loc)
+	want := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	gotYear, gotMonth, gotDay := t.Date()
+	if time.Date(gotYear, gotMonth, gotDay, 0, 0, 0, 0, time.UTC).Before(want) {
+		_, t = t.ZoneBounds()
+	}
+	return t.UTC()
+}
+
+// LoadLocation loads an IANA time zone. Unlike time.LoadLocation it rejects the
+// empty name and "Local", which would silently mean the server's zone.
+func LoadLocation(name string) (*time.Location, error) {
+	if name == "" || name == "Local" {
+		return nil, errInvalidLocation
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, errInvalidLocation
+	}
+	return loc, nil
+}
@@ -0,0 +1,122 @@
+package period
+
+import (
+	"testing"
+	"time"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := LoadLocation(name)
+	if err != nil {
+		t.Fatalf("load %s: %v", name, err)
+	}
+	return loc
+}
+
+func TestMonthAcrossTimeZones(t *testing.T) {
+	cases := []struct {
+		zone       string
+		year       int
+		month      time.Month
+		start, end string
+		hours      float64
+	}{
+		{"Asia/Jakarta", 2024, time.January, "2023-12-31T17:00:00Z", "2024-01-31T17:00:00Z", 31 * 24},
+		{"UTC", 2024, time.February, "2024-02-01T00:00:00Z", "2024-03-01T00:00:00Z", 29 * 24},
+		// Spring forward on 10 March: the month is an hour short.
+		{"America/New_York", 2024, time.March, "2024-03-01T05:00:00Z", "2024-04-01T04:00:00Z", 31*24 - 1},
+		// Fall back on 27 October.
+		{"Europe/Berlin", 2024, time.October, "2024-09-30T22:00:00Z", "2024-10-31T23:00:00Z", 31*24 + 1},
+		// Southern hemisphere: daylight saving ends on 7 April.
+		{"Australia/Sydney", 2024, time.April, "2024-03-31T13:00:00Z", "2024-04-30T14:00:00Z", 30*24 + 1},
+		// December rolls over into the next year.
+		{"America/Los_Angeles", 2024, time.December, "2024-12-01T08:00:00Z", "2025-01-01T08:00:00Z", 31 * 24},
+		// Paraguay skipped midnight on 1 October 2023; the month starts at 01:00.
+		{"America/Asuncion", 2023, time.October, "2023-10-01T04:00:00Z", "2023-11-01T03:00:00Z", 31*24 - 1},
+	}
+	for _, tc := range cases {
+		r := Month(tc.year, tc.month, mustLoad(t, tc.zone))
+		if got := r.Start.Format(time.RFC3339); got != tc.start {
+			t.Errorf("%s %d-%02d: start %s, want %s", tc.zone, tc.year, tc.month, got, tc.start)
+		}
+		if got := r.End.Format(time.RFC3339); got != tc.end {
+			t.Errorf("%s %d-%02d: end %s, want %s", tc.zone, tc.year, tc.month, got, tc.end)
+		}
+		if got := r.End.Sub(r.Start).Hours(); got != tc.hours {
+			t.Errorf("%s %d-%02d: %v hours, want %v", tc.zone, tc.year, tc.month, got, tc.hours)
+		}
+	}
+}
+
+func TestMonthBoundariesFollowTheUserZone(t *testing.T) {
+	// 23:30 on 31 January in New York is already February in UTC and Jakarta.
+	ny := mustLoad(t, "America/New_York")
+	late := time.Date(2024, time.January, 31, 23, 30, 0, 0, ny)
+
+	if !Month(2024, time.January, ny).Contains(late) {
+		t.Fatalf("expected the transaction in January for a New York user")
+	}
+	if !Month(2024, time.February, mustLoad(t, "Asia/Jakarta")).Contains(late) {
+		t.Fatalf("expected the same instant in February for a Jakarta user")
+	}
+	if Month(2024, time.February, ny).Contains(late) {
+		t.Fatalf("expected February in New York to exclude it")
+	}
+}
+
+func TestLoadLocationRejectsServerZone(t *testing.T) {
+	for _, name := range []string{"", "Local", "Mars/Olympus"} {
+		if _, err := LoadLocation(name); err != errInvalidLocation {
+			t.Errorf("LoadLocation(%q) = %v, want %v", name, err, errInvalidLocation)
+		}
+	}
+}
+
+func TestCycleAnchoredOnStartDay(t *testing.T) {
+	jakarta := mustLoad(t, "Asia/Jakarta")
+	cases := []struct {
+		year       int
+		month      time.Month
+		startDay   int
+		start, end string
+	}{
+		{2024, time.January, 25, "2024-01-24T17:00:00Z", "2024-02-24T17:00:00Z"},
+		// The December cycle ends in the next year.
+		{2024, time.December, 25, "2024-12-24T17:00:00Z", "2025-01-24T17:00:00Z"},
+		// A start day past the end of February falls on its last day.
+		{2024, time.January, 31, "2024-01-30T17:00:00Z", "2024-02-28T17:00:00Z"},
+		{2023, time.February, 30, "2023-02-27T17:00:00Z", "2023-03-29T17:00:00Z"},
+		{2024, time.March, 1, "2024-02-29T17:00:00Z", "2024-03-31T17:00:00Z"},
+	}
+	for _, tc := range cases {
+		r := Cycle(tc.year, tc.month, tc.startDay, jakarta)
+		if got := r.Start.Format(time.RFC3339); got != tc.start {
+			t.Errorf("%d-%02d from day %d: start %s, want %s", tc.year, tc.month, tc.startDay, got, tc.start)
+		}
+		if got := r.End.Format(time.RFC3339); got != tc.end {
+			t.Errorf("%d-%02d from day %d: end %s, want %s", tc.year, tc.month, tc.startDay, got, tc.end)
+		}
+	}
+
+	// Consecutive cycles leave no gap and do not overlap.
+	for month := time.January; month <= time.December; month++ {
+		if a, b := Cycle(2024, month, 31, jakarta), Cycle(2024, month+1, 31, jakarta); !a.End.Equal(b.Start) {
+			t.Errorf("cycle %d ends at %s but the next starts at %s", month, a.End, b.Start)
+		}
+	}
+}
+
+func TestDaysIsInclusive(t *testing.T) {
+	ny := mustLoad(t, "America/New_York")
+	first := time.Date(2024, time.March, 9, 0, 0, 0, 0, time.UTC)
+	last := time.Date(2024, time.March, 10, 0, 0, 0, 0, time.UTC)
+
+	r := Days(first, last, ny)
+	if r.Start.Format(time.RFC3339) != "2024-03-09T05:00:00Z" || r.End.Format(time.RFC3339) != "2024-03-11T04:00:00Z" {
+		t.Fatalf("unexpected range %s - %s", r.Start, r.End)
+	}
+	if hours := r.End.Sub(r.Start).Hours(); hours != 47 {
+		t.Fatalf("expected two days around the DST change to span 47 hours, got %v", hours)
+	}
+}
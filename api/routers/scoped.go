@@ -0,0 +1,39 @@
+package routers
+
+import (
+	"slices"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// scoped applies its middleware to each route registered through it. An
+// empty-prefix fiber Group would install the middleware with Use on the parent
+// prefix, which also matches the /api/v1 routes registered after /api.
+type scoped struct {
+	router     fiber.Router
+	middleware []fiber.Handler
+}
+
+func (s scoped) with(handlers []fiber.Handler) []fiber.Handler {
+	return append(slices.Clone(s.middleware), handlers...)
+}
+
+func (s scoped) Get(path string, handlers ...fiber.Handler) {
+	s.router.Get(path, s.with(handlers)...)
+}
+
+func (s scoped) Post(path string, handlers ...fiber.Handler) {
+	s.router.Post(path, s.with(handlers)...)
+}
+
+func (s scoped) Put(path string, handlers ...fiber.Handler) {
+	s.router.Put(path, s.with(handlers)...)
+}
+
+func (s scoped) Delete(path string, handlers ...fiber.Handler) {
+	s.router.Delete(path, s.with(handlers)...)
+}
+
+func (s scoped) Group(prefix string, middleware ...fiber.Handler) scoped {
+	return scoped{router: s.router.Group(prefix), middleware: s.with(middleware)}
+}
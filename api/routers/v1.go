@@ -16,7 +16,11 @@ func Init(app *contracts.App) {
 		return c.SendString("ok")
 	})
 
+	app.Fiber.Get("/.well-known/jwks.json", handlers.JWKS)
+
 	app.Fiber.Get("/activated", handlers.ActivatedHandler)
+	app.Fiber.Get("/reset-password", handlers.ResetPasswordPage)
+	app.Fiber.Get("/magic-link", handlers.MagicLinkPage)
 	app.Fiber.Get("/privacy", func(c *fiber.Ctx) error {
 		currentYear := time.Now().Year()
 		lastUpdated := time.Now().Format("02 January 2006")
@@ -211,16 +215,22 @@ func Init(app *contracts.App) {
 		</head>
 		<body>
 		<div class="container">
-			<h1Penghapusan Akun dan Data - FinLog</h1>
+			<h1>Penghapusan Akun dan Data - FinLog</h1>
 			<div class="updated">Last updated: %s</div>
 
 			<p>
-			Pengguna dapat meminta penghapusan akun dan data dengan cara:
+			Pengguna dapat menghapus akun langsung dari aplikasi:
 			</p>
 
-			<p>1. Mengirim email ke faridhaikaal@gmail.com</p>
-			<p>2. Gunakan subjek: Permintaan Penghapusan Akun FinLog</p>
-			<p>3. Sertakan email yang terdaftar di aplikasi</p>
+			<p>1. Buka menu Akun lalu pilih Hapus Akun</p>
+			<p>2. Konfirmasi dengan password atau kode autentikator (MFA)</p>
+			<p>3. Kami mengirim email konfirmasi berisi tautan pembatalan</p>
+
+			<p>
+			Setelah permintaan dikirim, akun langsung dinonaktifkan dan semua sesi
+			login diakhiri. Selama masa tenggang, penghapusan dapat dibatalkan melalui
+			tautan pada email konfirmasi.
+			</p>
 
 			<p>
 			Data yang dihapus:
@@ -228,8 +238,11 @@ func Init(app *contracts.App) {
 
 			<ul>
 			<li>Akun pengguna</li>
-			<li>Data transaksi</li>
-			<li>Data autentikasi</li>
+			<li>Data transaksi dan kategori</li>
+			<li>Riwayat impor</li>
+			<li>Cadangan kunci enkripsi</li>
+			<li>Data autentikasi (sesi, passkey, MFA)</li>
+			<li>Catatan pengiriman email</li>
 			</ul>
 
 			<p>
@@ -237,9 +250,15 @@ func Init(app *contracts.App) {
 			</p>
 
 			<ul>
-			<li>Data dihapus permanen maksimal 30 hari setelah permintaan diterima</li>
+			<li>Data dihapus permanen 30 hari setelah permintaan diterima</li>
 			</ul>
 
+			<p>
+			Jika tidak dapat masuk ke aplikasi, kirim email ke faridhaikaal@gmail.com
+			dengan subjek "Permintaan Penghapusan Akun FinLog" dan sertakan email yang
+			terdaftar.
+			</p>
+
 			<footer>
 			© %d FinLog. All rights reserved.
 			</footer>
@@ -261,41 +280,102 @@ func Init(app *contracts.App) {
 func registerAPIRoutes(app *contracts.App, api fiber.Router) {
 	authGroup := api.Group("/auth")
 	authGroup.Post("/login", handlers.AuthLogin)
+	authGroup.Post("/login/mfa", handlers.AuthLoginMFA)
 	authGroup.Post("/register", handlers.Register)
 	authGroup.Post("/resend-verification", handlers.ResendVerification)
 	authGroup.Get("/verify", handlers.VerifyEmail)
+	authGroup.Get("/unlock", handlers.UnlockAccount)
 	authGroup.Post("/refresh", handlers.Refresh)
+	authGroup.Post("/forgot-password", handlers.ForgotPassword)
+	authGroup.Post("/reset-password", handlers.ResetPassword)
+	authGroup.Post("/magic-link", handlers.RequestMagicLink)
+	authGroup.Post("/magic-link/consume", handlers.ConsumeMagicLink)
+	authGroup.Post("/passkeys/login/begin", handlers.BeginPasskeyLogin)
+	authGroup.Post("/passkeys/login/finish", handlers.FinishPasskeyLogin)
+	authGroup.Post("/oidc/:provider/begin", handlers.BeginOIDCLogin)
+	authGroup.Get("/oidc/:provider/callback", handlers.OIDCCallback)
+	authGroup.Post("/oidc/:provider/callback", handlers.OIDCCallback)
+	authGroup.Post("/oidc/:provider/finish", handlers.FinishOIDCLogin)
+
+	api.Get("/account/deletion/cancel", handlers.CancelAccountDeletion)
 
 	jwtTTL := parseDuration(app.Config[constants.JWT_TTL], time.Hour)
-	protected := api.Group("", middlewares.JWT([]byte(app.Config[constants.JWT_SECRET]), jwtTTL))
-
-	protected.Post("/auth/logout", handlers.Logout)
-
-	protected.Get("/categories", handlers.GetCategories)
-	protected.Post("/categories", handlers.CreateCategory)
-	protected.Put("/categories/:id", handlers.UpdateCategory)
-	protected.Delete("/categories/:id", handlers.DeleteCategory)
-
-	protected.Get("/recent-transactions", handlers.GetRecentTransactions)
-	protected.Get("/transactions", handlers.GetTransactions)
-	protected.Post("/transactions", handlers.CreateTransaction)
-	protected.Post("/transactions/import", handlers.ImportTransactions)
-	protected.Get("/transactions/import/history", handlers.ImportHistory)
-	protected.Delete("/transactions/import/:batch_id", handlers.UndoImportBatch)
-	protected.Put("/transactions/:id", handlers.UpdateTransaction)
-	protected.Put("/transactions/bulk/notes", handlers.UpdateTransactionNotes)
-	protected.Put("/transactions/bulk/amounts", handlers.UpdateTransactionAmount)
-	protected.Put("/transactions/bulk/dates", handlers.UpdateTransactionDate)
-	protected.Delete("/transactions/:id", handlers.DeleteTransaction)
-	protected.Delete("/transactions/bulk/delete", handlers.DeleteTransactions)
-
-	protected.Get("/budget", handlers.GetBudget)
-
-	keyGroup := protected.Group("/keys")
+	protected := scoped{router: api, middleware: []fiber.Handler{middlewares.JWT(app.JWTKeys, jwtTTL)}}
+
+	// Routes usable with personal access tokens; everything on the session group
+	// below rejects them.
+	readTransactions := middlewares.RequireScope(constants.ScopeTransactionsRead)
+	writeTransactions := middlewares.RequireScope(constants.ScopeTransactionsWrite)
+	writeImports := middlewares.RequireScope(constants.ScopeImportWrite)
+
+	protected.Get("/categories", middlewares.RequireScope(constants.ScopeCategoriesRead), handlers.GetCategories)
+	protected.Get("/categories/:id", middlewares.RequireScope(constants.ScopeCategoriesRead), handlers.GetCategory)
+
+	protected.Get("/recent-transactions", readTransactions, handlers.GetRecentTransactions)
+	protected.Get("/transactions", readTransactions, handlers.GetTransactions)
+	protected.Post("/transactions", writeTransactions, handlers.CreateTransaction)
+	protected.Post("/transactions/import", writeImports, handlers.ImportTransactions)
+	protected.Get("/transactions/import/history", readTransactions, handlers.ImportHistory)
+	protected.Delete("/transactions/import/:batch_id", writeImports, handlers.UndoImportBatch)
+	protected.Get("/transactions/trash", readTransactions, handlers.GetTransactionTrash)
+	protected.Get("/transactions/:id", readTransactions, handlers.GetTransaction)
+	protected.Put("/transactions/:id", writeTransactions, handlers.UpdateTransaction)
+	protected.Put("/transactions/bulk/notes", writeTransactions, handlers.UpdateTransactionNotes)
+	protected.Put("/transactions/bulk/amounts", writeTransactions, handlers.UpdateTransactionAmount)
+	protected.Put("/transactions/bulk/dates", writeTransactions, handlers.UpdateTransactionDate)
+	protected.Delete("/transactions/:id", writeTransactions, handlers.DeleteTransaction)
+	protected.Delete("/transactions/bulk/delete", writeTransactions, handlers.DeleteTransactions)
+	protected.Post("/transactions/bulk/restore", writeTransactions, handlers.RestoreTransactions)
+	protected.Post("/transactions/:id/restore", writeTransactions, handlers.RestoreTransaction)
+
+	protected.Get("/keys/backup", middlewares.RequireScope(constants.ScopeKeysRead), handlers.GetActiveKeyBackup)
+	protected.Get("/keys/backup/status", middlewares.RequireScope(constants.ScopeKeysRead), handlers.GetKeyBackupStatus)
+
+	session := protected.Group("", middlewares.RequireSession())
+
+	session.Post("/auth/logout", handlers.Logout)
+	session.Post("/auth/logout-all", handlers.LogoutAll)
+	session.Get("/auth/sessions", handlers.ListSessions)
+	session.Delete("/auth/sessions/:id", handlers.RevokeSession)
+	session.Put("/auth/password", handlers.ChangePassword)
+	session.Put("/auth/email", handlers.ChangeEmail)
+	session.Post("/auth/mfa/enroll", handlers.EnrollMFA)
+	session.Post("/auth/mfa/confirm", handlers.ConfirmMFA)
+	session.Post("/auth/mfa/disable", handlers.DisableMFA)
+	session.Get("/auth/passkeys", handlers.ListPasskeys)
+	session.Post("/auth/passkeys/register/begin", handlers.BeginPasskeyRegistration)
+	session.Post("/auth/passkeys/register/finish", handlers.FinishPasskeyRegistration)
+	session.Delete("/auth/passkeys/:id", handlers.DeletePasskey)
+	session.Get("/auth/tokens", handlers.ListAccessTokens)
+	session.Post("/auth/tokens", handlers.CreateAccessToken)
+	session.Delete("/auth/tokens/:id", handlers.RevokeAccessToken)
+
+	session.Get("/me", handlers.GetProfile)
+	session.Put("/me", handlers.UpdateProfile)
+
+	session.Delete("/account", handlers.DeleteAccount)
+	session.Get("/account/audit-log", handlers.ListAuditLog)
+
+	session.Post("/categories", handlers.CreateCategory)
+	session.Put("/categories/:id", handlers.UpdateCategory)
+	session.Delete("/categories/:id", handlers.DeleteCategory)
+
+	session.Get("/budget", handlers.GetBudget)
+	session.Get("/sync", handlers.GetSync)
+
+	keyGroup := session.Group("/keys")
 	keyGroup.Post("/backup", handlers.StoreKeyBackup)
 	keyGroup.Put("/backup/rotate", handlers.RotateKeyBackup)
-	keyGroup.Get("/backup", handlers.GetActiveKeyBackup)
-	keyGroup.Get("/backup/status", handlers.GetKeyBackupStatus)
+
+	admin := session.Group("/admin", middlewares.RequireRole(constants.RoleAdmin))
+	admin.Get("/audit-events", handlers.SearchAuditEvents)
+	admin.Get("/users", handlers.AdminSearchUsers)
+	admin.Get("/users/:id", handlers.AdminGetUser)
+	admin.Post("/users/:id/resend-verification", handlers.AdminResendVerification)
+	admin.Post("/users/:id/verify", handlers.AdminForceVerify)
+	admin.Post("/users/:id/disable", handlers.AdminDisableUser)
+	admin.Post("/users/:id/enable", handlers.AdminEnableUser)
+	admin.Post("/users/:id/deletion", handlers.AdminScheduleDeletion)
 }
 
 func parseDuration(raw string, fallback time.Duration) time.Duration {
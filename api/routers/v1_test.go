@@ -0,0 +1,72 @@
+package routers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"finlog-api/api/constants"
+	"finlog-api/api/contracts"
+	"finlog-api/api/entities"
+	"finlog-api/api/handlers"
+	"finlog-api/api/middlewares"
+)
+
+type fakeAccessTokens struct {
+	contracts.AccessTokenService
+}
+
+func (fakeAccessTokens) Authenticate(ctx context.Context, rawToken string) (*entities.PersonalAccessToken, *entities.User, error) {
+	token := &entities.PersonalAccessToken{ID: 1, UserID: 7, ScopeList: []string{constants.ScopeCategoriesRead}}
+	return token, &entities.User{ID: 7, Role: constants.RoleUser}, nil
+}
+
+type fakeCategories struct {
+	contracts.CategoryService
+}
+
+func (fakeCategories) ListCategories(ctx context.Context, userID int64, filter contracts.CategoryFilter) ([]entities.Category, error) {
+	return []entities.Category{}, nil
+}
+
+func newTestApp() *contracts.App {
+	app := &contracts.App{
+		Config: map[string]string{},
+		Fiber:  fiber.New(),
+		Services: &contracts.Services{
+			AccessTokens: fakeAccessTokens{},
+			Categories:   fakeCategories{},
+		},
+	}
+	middlewares.Init(app)
+	handlers.Init(app)
+	Init(app)
+	return app
+}
+
+func TestAccessTokenRoutesOnEveryPrefix(t *testing.T) {
+	app := newTestApp()
+	for _, prefix := range []string{"/api", "/api/v1", "/v1"} {
+		cases := []struct {
+			path string
+			want int
+		}{
+			{prefix + "/categories", http.StatusOK},
+			{prefix + "/me", http.StatusForbidden},
+		}
+		for _, tc := range cases {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			req.Header.Set("Authorization", "Bearer flp_test")
+			resp, err := app.Fiber.Test(req)
+			if err != nil {
+				t.Fatalf("GET %s: %v", tc.path, err)
+			}
+			if resp.StatusCode != tc.want {
+				t.Fatalf("GET %s: expected %d, got %d", tc.path, tc.want, resp.StatusCode)
+			}
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package accesstoken
+
+const (
+	createTokenQuery = `
+		INSERT INTO personal_access_tokens (user_id, name, token_prefix, token_hash, scopes, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+
+	listTokensQuery = `
+		SELECT * FROM personal_access_tokens
+		WHERE user_id = ? AND revoked_at IS NULL
+		ORDER BY created_at DESC, id DESC
+	`
+
+	countActiveTokensQuery = `
+		SELECT COUNT(*) FROM personal_access_tokens
+		WHERE user_id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)
+	`
+
+	findTokenByHashQuery = `
+		SELECT * FROM personal_access_tokens WHERE token_hash = ? LIMIT 1
+	`
+
+	findUserQuery = `
+		SELECT * FROM users WHERE id = ? LIMIT 1
+	`
+
+	touchTokenQuery = `
+		UPDATE personal_access_tokens
+		SET last_used_at = ?
+		WHERE id = ? AND (last_used_at IS NULL OR last_used_at < ?)
+	`
+
+	revokeTokenQuery = `
+		UPDATE personal_access_tokens
+		SET revoked_at = ?
+		WHERE id = ? AND user_id = ? AND revoked_at IS NULL
+	`
+)
@@ -0,0 +1,84 @@
+package accesstoken
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"finlog-api/api/contracts"
+	"finlog-api/api/entities"
+)
+
+type repository struct {
+	reader *sqlx.DB
+	writer *sqlx.DB
+}
+
+func initRepository(app *contracts.App) contracts.AccessTokenRepository {
+	return &repository{
+		reader: app.Ds.ReaderDB,
+		writer: app.Ds.WriterDB,
+	}
+}
+
+func (r *repository) CreateToken(ctx context.Context, token *entities.PersonalAccessToken) (int64, error) {
+	res, err := r.writer.ExecContext(ctx, createTokenQuery,
+		token.UserID, token.Name, token.Prefix, token.TokenHash, token.Scopes, token.ExpiresAt, token.CreatedAt)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (r *repository) ListTokens(ctx context.Context, userID int64) ([]entities.PersonalAccessToken, error) {
+	tokens := []entities.PersonalAccessToken{}
+	if err := r.reader.SelectContext(ctx, &tokens, listTokensQuery, userID); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// CountActiveTokens reads from the writer so the per-user limit sees tokens that
+// were just created.
+func (r *repository) CountActiveTokens(ctx context.Context, userID int64, now time.Time) (int, error) {
+	var count int
+	if err := r.writer.GetContext(ctx, &count, countActiveTokensQuery, userID, now); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// FindTokenByHash and FindUser back every token request, so they read from the
+// writer: a revoked token or a disabled user must stop working at once.
+func (r *repository) FindTokenByHash(ctx context.Context, tokenHash string) (*entities.PersonalAccessToken, error) {
+	token := new(entities.PersonalAccessToken)
+	if err := r.writer.GetContext(ctx, token, findTokenByHashQuery, tokenHash); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func (r *repository) FindUser(ctx context.Context, userID int64) (*entities.User, error) {
+	user := new(entities.User)
+	if err := r.writer.GetContext(ctx, user, findUserQuery, userID); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// TouchToken records a use unless one was already recorded after staleBefore,
+// which keeps busy scripts from writing on every request.
+func (r *repository) TouchToken(ctx context.Context, id int64, usedAt, staleBefore time.Time) error {
+	_, err := r.writer.ExecContext(ctx, touchTokenQuery, usedAt, id, staleBefore)
+	return err
+}
+
+func (r *repository) RevokeToken(ctx context.Context, userID, id int64, revokedAt time.Time) (bool, error) {
+	res, err := r.writer.ExecContext(ctx, revokeTokenQuery, revokedAt, id, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, _ := res.RowsAffected()
+	return affected > 0, nil
+}
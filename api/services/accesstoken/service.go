@@ -0,0 +1,221 @@
+package accesstoken
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"finlog-api/api/constants"
+	"finlog-api/api/contracts"
+	"finlog-api/api/entities"
+)
+
+const (
+	// TokenPrefix marks personal access tokens so the auth middleware can tell
+	// them apart from JWTs, and so leaked tokens are easy to scan for.
+	TokenPrefix = "flp_"
+
+	maxTokensPerUser = 25
+	maxNameLength    = 100
+	displayPrefixLen = 12
+	// touchInterval limits how often last_used_at is written for a busy token.
+	touchInterval = time.Minute
+)
+
+var (
+	errInvalidName   = errors.New("token name is required")
+	errInvalidScope  = errors.New("invalid token scope")
+	errInvalidExpiry = errors.New("token expiry must be in the future")
+	errTooManyTokens = errors.New("too many active access tokens")
+	errTokenNotFound = errors.New("access token not found")
+	errInvalidToken  = errors.New("invalid access token")
+)
+
+func ErrInvalidName() error   { return errInvalidName }
+func ErrInvalidScope() error  { return errInvalidScope }
+func ErrInvalidExpiry() error { return errInvalidExpiry }
+func ErrTooManyTokens() error { return errTooManyTokens }
+func ErrTokenNotFound() error { return errTokenNotFound }
+func ErrInvalidToken() error  { return errInvalidToken }
+
+type Service struct {
+	app  *contracts.App
+	repo contracts.AccessTokenRepository
+}
+
+func Init(app *contracts.App) contracts.AccessTokenService {
+	return &Service{
+		app:  app,
+		repo: initRepository(app),
+	}
+}
+
+// Create issues a new token. The raw token is only returned here; afterwards the
+// API knows it by its hash.
+func (s *Service) Create(ctx context.Context, userID int64, name string, scopes []string, expiresAt *time.Time) (string, *entities.PersonalAccessToken, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxNameLength {
+		return "", nil, errInvalidName
+	}
+	scopes, err := normalizeScopes(scopes)
+	if err != nil {
+		return "", nil, err
+	}
+	now := time.Now().UTC()
+	if expiresAt != nil && !expiresAt.After(now) {
+		return "", nil, errInvalidExpiry
+	}
+
+	count, err := s.repo.CountActiveTokens(ctx, userID, now)
+	if err != nil {
+		return "", nil, err
+	}
+	if count >= maxTokensPerUser {
+		return "", nil, errTooManyTokens
+	}
+
+	raw, err := generateToken()
+	if err != nil {
+		return "", nil, err
+	}
+	token := &entities.PersonalAccessToken{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    raw[:displayPrefixLen],
+		TokenHash: hashToken(raw),
+		Scopes:    strings.Join(scopes, ","),
+		ExpiresAt: expiresAt,
+		CreatedAt: now,
+		ScopeList: scopes,
+	}
+	id, err := s.repo.CreateToken(ctx, token)
+	if err != nil {
+		return "", nil, err
+	}
+	token.ID = id
+
+	s.logAction(userID, id, "access_token_created")
+	s.app.Services.Audit.Record(ctx, userID, constants.AuditAccessTokenCreated, constants.AuditSuccess, strconv.FormatInt(id, 10))
+	return raw, token, nil
+}
+
+// List returns the user's tokens that have not been revoked, including expired ones.
+func (s *Service) List(ctx context.Context, userID int64) ([]entities.PersonalAccessToken, error) {
+	tokens, err := s.repo.ListTokens(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range tokens {
+		tokens[i].ScopeList = splitScopes(tokens[i].Scopes)
+	}
+	return tokens, nil
+}
+
+func (s *Service) Revoke(ctx context.Context, userID, id int64) error {
+	revoked, err := s.repo.RevokeToken(ctx, userID, id, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return errTokenNotFound
+	}
+	s.logAction(userID, id, "access_token_revoked")
+	s.app.Services.Audit.Record(ctx, userID, constants.AuditAccessTokenRevoked, constants.AuditSuccess, strconv.FormatInt(id, 10))
+	return nil
+}
+
+// Authenticate resolves a raw token to its owner. Revoked and expired tokens, and
+// tokens of disabled accounts or accounts scheduled for deletion, are rejected.
+func (s *Service) Authenticate(ctx context.Context, rawToken string) (*entities.PersonalAccessToken, *entities.User, error) {
+	if !strings.HasPrefix(rawToken, TokenPrefix) {
+		return nil, nil, errInvalidToken
+	}
+	token, err := s.repo.FindTokenByHash(ctx, hashToken(rawToken))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, errInvalidToken
+		}
+		return nil, nil, err
+	}
+	now := time.Now().UTC()
+	if token.RevokedAt != nil || (token.ExpiresAt != nil && !token.ExpiresAt.After(now)) {
+		return nil, nil, errInvalidToken
+	}
+
+	user, err := s.repo.FindUser(ctx, token.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, errInvalidToken
+		}
+		return nil, nil, err
+	}
+	if user.DeletionRequestedAt != nil || user.DisabledAt != nil {
+		return nil, nil, errInvalidToken
+	}
+
+	if token.LastUsedAt == nil || token.LastUsedAt.Before(now.Add(-touchInterval)) {
+		if err := s.repo.TouchToken(ctx, token.ID, now, now.Add(-touchInterval)); err != nil {
+			s.app.Logger.Error().
+				Err(err).
+				Int64("access_token_id", token.ID).
+				Msg("access_token_touch_failed")
+		}
+	}
+	token.ScopeList = splitScopes(token.Scopes)
+	return token, user, nil
+}
+
+func (s *Service) logAction(userID, tokenID int64, action string) {
+	s.app.Logger.Info().
+		Int64("user_id", userID).
+		Int64("access_token_id", tokenID).
+		Str("action", action).
+		Msg("access token event")
+}
+
+// normalizeScopes rejects unknown scopes and returns the rest sorted and deduplicated.
+func normalizeScopes(scopes []string) ([]string, error) {
+	var out []string
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if !slices.Contains(constants.AccessTokenScopes, scope) {
+			return nil, errInvalidScope
+		}
+		if !slices.Contains(out, scope) {
+			out = append(out, scope)
+		}
+	}
+	if len(out) == 0 {
+		return nil, errInvalidScope
+	}
+	slices.Sort(out)
+	return out, nil
+}
+
+func splitScopes(scopes string) []string {
+	if scopes == "" {
+		return []string{}
+	}
+	return strings.Split(scopes, ",")
+}
+
+func generateToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return TokenPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashToken(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
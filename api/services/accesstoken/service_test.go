@@ -0,0 +1,158 @@
+package accesstoken
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"finlog-api/api/constants"
+	"finlog-api/api/contracts"
+	"finlog-api/api/entities"
+)
+
+type fakeRepo struct {
+	tokens map[int64]*entities.PersonalAccessToken
+	users  map[int64]*entities.User
+	nextID int64
+}
+
+func (f *fakeRepo) CreateToken(ctx context.Context, token *entities.PersonalAccessToken) (int64, error) {
+	f.nextID++
+	copied := *token
+	copied.ID = f.nextID
+	f.tokens[copied.ID] = &copied
+	return copied.ID, nil
+}
+
+func (f *fakeRepo) ListTokens(ctx context.Context, userID int64) ([]entities.PersonalAccessToken, error) {
+	var out []entities.PersonalAccessToken
+	for _, t := range f.tokens {
+		if t.UserID == userID && t.RevokedAt == nil {
+			out = append(out, *t)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeRepo) CountActiveTokens(ctx context.Context, userID int64, now time.Time) (int, error) {
+	tokens, _ := f.ListTokens(ctx, userID)
+	return len(tokens), nil
+}
+
+func (f *fakeRepo) FindTokenByHash(ctx context.Context, tokenHash string) (*entities.PersonalAccessToken, error) {
+	for _, t := range f.tokens {
+		if t.TokenHash == tokenHash {
+			copied := *t
+			return &copied, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeRepo) FindUser(ctx context.Context, userID int64) (*entities.User, error) {
+	if u, ok := f.users[userID]; ok {
+		return u, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeRepo) TouchToken(ctx context.Context, id int64, usedAt, staleBefore time.Time) error {
+	if t, ok := f.tokens[id]; ok && (t.LastUsedAt == nil || t.LastUsedAt.Before(staleBefore)) {
+		t.LastUsedAt = &usedAt
+	}
+	return nil
+}
+
+func (f *fakeRepo) RevokeToken(ctx context.Context, userID, id int64, revokedAt time.Time) (bool, error) {
+	t, ok := f.tokens[id]
+	if !ok || t.UserID != userID || t.RevokedAt != nil {
+		return false, nil
+	}
+	t.RevokedAt = &revokedAt
+	return true, nil
+}
+
+type fakeAudit struct {
+	contracts.AuditService
+	actions []string
+}
+
+func (f *fakeAudit) Record(ctx context.Context, userID int64, action, outcome, detail string) {
+	f.actions = append(f.actions, action)
+}
+
+func newTestService() *Service {
+	logger := zerolog.Nop()
+	return &Service{
+		app: &contracts.App{Logger: &logger, Services: &contracts.Services{Audit: &fakeAudit{}}},
+		repo: &fakeRepo{
+			tokens: map[int64]*entities.PersonalAccessToken{},
+			users:  map[int64]*entities.User{1: {ID: 1, Email: "user@example.com", Role: "user"}},
+		},
+	}
+}
+
+func TestCreateAndAuthenticate(t *testing.T) {
+	svc := newTestService()
+	ctx := context.Background()
+
+	raw, token, err := svc.Create(ctx, 1, "export script", []string{"transactions:read", "transactions:read", "import:write"}, nil)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if !strings.HasPrefix(raw, TokenPrefix) || token.TokenHash == raw {
+		t.Fatalf("expected a prefixed token stored hashed")
+	}
+	if token.Scopes != "import:write,transactions:read" {
+		t.Fatalf("expected sorted, deduplicated scopes, got %q", token.Scopes)
+	}
+
+	authed, user, err := svc.Authenticate(ctx, raw)
+	if err != nil {
+		t.Fatalf("authenticate: %v", err)
+	}
+	if user.ID != 1 || len(authed.ScopeList) != 2 {
+		t.Fatalf("unexpected token %+v for user %+v", authed, user)
+	}
+	if stored, _ := svc.repo.FindTokenByHash(ctx, token.TokenHash); stored.LastUsedAt == nil {
+		t.Fatalf("expected last use to be recorded")
+	}
+}
+
+func TestAuthenticateRejectsRevokedAndExpiredTokens(t *testing.T) {
+	svc := newTestService()
+	ctx := context.Background()
+
+	revoked, token, _ := svc.Create(ctx, 1, "old", []string{"keys:read"}, nil)
+	if err := svc.Revoke(ctx, 1, token.ID); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	if _, _, err := svc.Authenticate(ctx, revoked); !errors.Is(err, errInvalidToken) {
+		t.Fatalf("expected revoked token to be rejected, got %v", err)
+	}
+	audit := svc.app.Services.Audit.(*fakeAudit)
+	if !slices.Equal(audit.actions, []string{constants.AuditAccessTokenCreated, constants.AuditAccessTokenRevoked}) {
+		t.Fatalf("expected create and revoke to be audited, got %v", audit.actions)
+	}
+
+	soon := time.Now().Add(time.Minute)
+	expired, token, _ := svc.Create(ctx, 1, "short lived", []string{"keys:read"}, &soon)
+	past := time.Now().Add(-time.Minute)
+	svc.repo.(*fakeRepo).tokens[token.ID].ExpiresAt = &past
+	if _, _, err := svc.Authenticate(ctx, expired); !errors.Is(err, errInvalidToken) {
+		t.Fatalf("expected expired token to be rejected, got %v", err)
+	}
+}
+
+func TestCreateRejectsUnknownScope(t *testing.T) {
+	svc := newTestService()
+	if _, _, err := svc.Create(context.Background(), 1, "admin", []string{"admin:write"}, nil); !errors.Is(err, errInvalidScope) {
+		t.Fatalf("expected invalid scope, got %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package account
+
+const (
+	findUserQuery = `
+		SELECT * FROM users WHERE id = ? LIMIT 1
+	`
+
+	findByCancelTokenQuery = `
+		SELECT * FROM users WHERE deletion_cancel_token = ? LIMIT 1
+	`
+
+	markDeletionRequestedQuery = `
+		UPDATE users
+		SET deletion_requested_at = ?, deletion_cancel_token = ?
+		WHERE id = ? AND deletion_requested_at IS NULL
+	`
+
+	cancelDeletionQuery = `
+		UPDATE users
+		SET deletion_requested_at = NULL, deletion_cancel_token = NULL
+		WHERE id = ? AND deletion_cancel_token = ?
+	`
+
+	listDeletionsDueQuery = `
+		SELECT * FROM users
+		WHERE deletion_requested_at IS NOT NULL AND deletion_requested_at <= ?
+		ORDER BY deletion_requested_at
+		LIMIT ?
+	`
+
+	lockDueUserQuery = `
+		SELECT email, pending_email FROM users
+		WHERE id = ? AND deletion_requested_at IS NOT NULL AND deletion_requested_at <= ?
+		FOR UPDATE
+	`
+
+	deleteUserTransactionsQuery = `
+		DELETE FROM transactions WHERE user_id = ?
+	`
+
+	deleteUserCategoriesQuery = `
+		DELETE FROM categories WHERE user_id = ?
+	`
+
+	deleteUserImportBatchesQuery = `
+		DELETE FROM import_batches WHERE user_id = ?
+	`
+
+	deleteUserKeyBackupsQuery = `
+		DELETE FROM user_encrypted_data_keys WHERE user_id = ?
+	`
+
+	anonymizeAuditEventsQuery = `
+		UPDATE audit_events SET ip_address = NULL, user_agent = NULL WHERE user_id = ?
+	`
+
+	deleteEmailEventsQuery = `
+		DELETE FROM email_events
+		WHERE to_email IN (?, ?)
+			OR to_email IN (SELECT email FROM user_email_history WHERE user_id = ?)
+	`
+
+	deleteEmailMessagesQuery = `
+		DELETE FROM email_messages
+		WHERE to_email IN (?, ?)
+			OR to_email IN (SELECT email FROM user_email_history WHERE user_id = ?)
+	`
+
+	deleteUserQuery = `
+		DELETE FROM users WHERE id = ?
+	`
+)
@@ -0,0 +1,131 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"finlog-api/api/contracts"
+	"finlog-api/api/entities"
+)
+
+type repository struct {
+	reader *sqlx.DB
+	writer *sqlx.DB
+}
+
+func initRepository(app *contracts.App) contracts.AccountRepository {
+	return &repository{
+		reader: app.Ds.ReaderDB,
+		writer: app.Ds.WriterDB,
+	}
+}
+
+func (r *repository) FindUser(ctx context.Context, userID int64) (*entities.User, error) {
+	user := new(entities.User)
+	if err := r.reader.GetContext(ctx, user, findUserQuery, userID); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (r *repository) FindByCancelToken(ctx context.Context, tokenHash string) (*entities.User, error) {
+	user := new(entities.User)
+	if err := r.reader.GetContext(ctx, user, findByCancelTokenQuery, tokenHash); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// MarkDeletionRequested schedules the account for purging. It reports false when a
+// deletion was already pending.
+func (r *repository) MarkDeletionRequested(ctx context.Context, userID int64, tokenHash string, requestedAt time.Time) (bool, error) {
+	res, err := r.writer.ExecContext(ctx, markDeletionRequestedQuery, requestedAt, tokenHash, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, _ := res.RowsAffected()
+	return affected > 0, nil
+}
+
+func (r *repository) CancelDeletion(ctx context.Context, userID int64, tokenHash string) (bool, error) {
+	res, err := r.writer.ExecContext(ctx, cancelDeletionQuery, userID, tokenHash)
+	if err != nil {
+		return false, err
+	}
+	affected, _ := res.RowsAffected()
+	return affected > 0, nil
+}
+
+func (r *repository) ListDeletionsDue(ctx context.Context, requestedBefore time.Time, limit int) ([]entities.User, error) {
+	var users []entities.User
+	if err := r.reader.SelectContext(ctx, &users, listDeletionsDueQuery, requestedBefore, limit); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// Purge removes every row belonging to the user in one transaction. It locks the
+// user row first and reports false if the deletion was cancelled in the meantime.
+// Auth data such as refresh tokens, sessions and passkeys goes with the user row
+// via ON DELETE CASCADE. Mail sent to any address the user had, including ones in
+// the email history, is deleted; email suppressions are kept so a complained
+// address is never mailed again. Audit events are kept as a record of what happened to the
+// account, without the IP addresses and user agents they were recorded with.
+func (r *repository) Purge(ctx context.Context, userID int64, requestedBefore time.Time) (bool, error) {
+	tx, err := r.writer.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	ok, err := purgeUser(ctx, tx, userID, requestedBefore)
+	if err != nil || !ok {
+		_ = tx.Rollback()
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func purgeUser(ctx context.Context, exec sqlx.ExtContext, userID int64, requestedBefore time.Time) (bool, error) {
+	var emails struct {
+		Email        string  `db:"email"`
+		PendingEmail *string `db:"pending_email"`
+	}
+	if err := sqlx.GetContext(ctx, exec, &emails, lockDueUserQuery, userID, requestedBefore); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	pendingEmail := emails.Email
+	if emails.PendingEmail != nil {
+		pendingEmail = *emails.PendingEmail
+	}
+
+	// Transactions reference categories, so they must go first.
+	for _, query := range []string{
+		deleteUserTransactionsQuery,
+		deleteUserCategoriesQuery,
+		deleteUserImportBatchesQuery,
+		deleteUserKeyBackupsQuery,
+		anonymizeAuditEventsQuery,
+	} {
+		if _, err := exec.ExecContext(ctx, query, userID); err != nil {
+			return false, err
+		}
+	}
+	for _, query := range []string{deleteEmailEventsQuery, deleteEmailMessagesQuery} {
+		if _, err := exec.ExecContext(ctx, query, emails.Email, pendingEmail, userID); err != nil {
+			return false, err
+		}
+	}
+	if _, err := exec.ExecContext(ctx, deleteUserQuery, userID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,105 @@
+package account
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"finlog-api/api/datasources/dbtest"
+)
+
+func TestPurgeLeavesOtherUsersUntouched(t *testing.T) {
+	db := dbtest.Open(t)
+	ids := dbtest.Seed(t, db, 2, 10)
+	purgedID, keptID := ids[0], ids[1]
+	repo := &repository{reader: db, writer: db}
+	ctx := context.Background()
+
+	requestedAt := time.Now().UTC().Add(-gracePeriod - time.Hour)
+	for _, id := range ids {
+		if _, err := db.Exec("UPDATE users SET deletion_requested_at = ? WHERE id = ?", requestedAt, id); err != nil {
+			t.Fatalf("request deletion: %v", err)
+		}
+		if _, err := db.Exec(
+			"INSERT INTO audit_events (user_id, action, outcome, ip_address, user_agent, created_at) VALUES (?, 'login', 'success', '203.0.113.7', 'test', NOW())",
+			id,
+		); err != nil {
+			t.Fatalf("seed audit event: %v", err)
+		}
+	}
+	t.Cleanup(func() {
+		for _, id := range ids {
+			db.Exec("DELETE FROM audit_events WHERE user_id = ?", id)
+		}
+	})
+
+	// Mail to an address the purged user changed away from goes too.
+	var keptEmail string
+	if err := db.Get(&keptEmail, "SELECT email FROM users WHERE id = ?", keptID); err != nil {
+		t.Fatalf("find email: %v", err)
+	}
+	oldEmail := fmt.Sprintf("old-%d@example.com", purgedID)
+	if _, err := db.Exec("INSERT INTO user_email_history (user_id, email) VALUES (?, ?)", purgedID, oldEmail); err != nil {
+		t.Fatalf("seed email history: %v", err)
+	}
+	for _, to := range []string{oldEmail, keptEmail} {
+		if _, err := db.Exec(
+			"INSERT INTO email_messages (resend_id, to_email) VALUES (?, ?)",
+			fmt.Sprintf("test-%d-%s", purgedID, to), to,
+		); err != nil {
+			t.Fatalf("seed email message: %v", err)
+		}
+	}
+	t.Cleanup(func() {
+		db.Exec("DELETE FROM email_messages WHERE to_email IN (?, ?)", oldEmail, keptEmail)
+	})
+
+	if ok, err := repo.Purge(ctx, purgedID, requestedAt.Add(-time.Minute)); err != nil || ok {
+		t.Fatalf("expected no purge before the cutoff, got %v, %v", ok, err)
+	}
+	if ok, err := repo.Purge(ctx, purgedID, time.Now().UTC().Add(-gracePeriod)); err != nil || !ok {
+		t.Fatalf("expected the purge to run, got %v, %v", ok, err)
+	}
+
+	for _, tc := range []struct {
+		to   string
+		want int
+	}{{oldEmail, 0}, {keptEmail, 1}} {
+		var messages int
+		if err := db.Get(&messages, "SELECT COUNT(*) FROM email_messages WHERE to_email = ?", tc.to); err != nil {
+			t.Fatalf("count email messages: %v", err)
+		}
+		if messages != tc.want {
+			t.Fatalf("%s: expected %d email messages, got %d", tc.to, tc.want, messages)
+		}
+	}
+
+	for _, tc := range []struct {
+		userID int64
+		want   int
+	}{{purgedID, 0}, {keptID, 1}} {
+		var users, transactions int
+		if err := db.Get(&users, "SELECT COUNT(*) FROM users WHERE id = ?", tc.userID); err != nil {
+			t.Fatalf("count users: %v", err)
+		}
+		if err := db.Get(&transactions, "SELECT COUNT(*) FROM transactions WHERE user_id = ?", tc.userID); err != nil {
+			t.Fatalf("count transactions: %v", err)
+		}
+		if users != tc.want || transactions != tc.want*10 {
+			t.Fatalf("user %d: expected %d users and %d transactions, got %d and %d", tc.userID, tc.want, tc.want*10, users, transactions)
+		}
+
+		// Audit events outlive the account but lose what identifies the client.
+		var events, identified int
+		if err := db.Get(&events, "SELECT COUNT(*) FROM audit_events WHERE user_id = ?", tc.userID); err != nil {
+			t.Fatalf("count audit events: %v", err)
+		}
+		if err := db.Get(&identified, "SELECT COUNT(*) FROM audit_events WHERE user_id = ? AND (ip_address IS NOT NULL OR user_agent IS NOT NULL)", tc.userID); err != nil {
+			t.Fatalf("count identified audit events: %v", err)
+		}
+		if events != 1 || identified != tc.want {
+			t.Fatalf("user %d: expected 1 audit event, %d with client details, got %d and %d", tc.userID, tc.want, events, identified)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package account
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"finlog-api/api/constants"
+	"finlog-api/api/contracts"
+	"finlog-api/api/entities"
+	"finlog-api/api/services/email"
+)
+
+const (
+	// gracePeriod matches the retention promise on the /account-deletion page.
+	gracePeriod    = 30 * 24 * time.Hour
+	purgeBatchSize = 50
+)
+
+var (
+	errUserNotFound             = errors.New("user not found")
+	errDeletionAlreadyRequested = errors.New("account deletion already requested")
+	errCancelTokenInvalid       = errors.New("invalid deletion cancel token")
+)
+
+func ErrUserNotFound() error             { return errUserNotFound }
+func ErrDeletionAlreadyRequested() error { return errDeletionAlreadyRequested }
+func ErrCancelTokenInvalid() error       { return errCancelTokenInvalid }
+
+type Service struct {
+	app  *contracts.App
+	repo contracts.AccountRepository
+}
+
+func Init(app *contracts.App) contracts.AccountService {
+	return &Service{
+		app:  app,
+		repo: initRepository(app),
+	}
+}
+
+// RequestDeletion schedules the account for purging after the grace period, signs
+// out every session and emails a cancel link. It returns when the purge is due.
+func (s *Service) RequestDeletion(ctx context.Context, userID int64, password, code string) (time.Time, error) {
+	if err := s.app.Services.Auth.Reauthenticate(ctx, userID, password, code); err != nil {
+		s.app.Services.Audit.Record(ctx, userID, constants.AuditDeletionRequested, constants.AuditFailure, "reauthentication_failed")
+		return time.Time{}, err
+	}
+	purgeAt, err := s.ScheduleDeletion(ctx, userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	s.app.Services.Audit.Record(ctx, userID, constants.AuditDeletionRequested, constants.AuditSuccess, "")
+	return purgeAt, nil
+}
+
+// ScheduleDeletion is RequestDeletion without the re-authentication, for support
+// staff acting on a user's behalf.
+func (s *Service) ScheduleDeletion(ctx context.Context, userID int64) (time.Time, error) {
+	user, err := s.repo.FindUser(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return time.Time{}, errUserNotFound
+		}
+		return time.Time{}, err
+	}
+	if user.DeletionRequestedAt != nil {
+		return time.Time{}, errDeletionAlreadyRequested
+	}
+
+	rawToken, err := generateToken()
+	if err != nil {
+		return time.Time{}, err
+	}
+	requestedAt := time.Now().UTC()
+	marked, err := s.repo.MarkDeletionRequested(ctx, userID, hashToken(rawToken), requestedAt)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !marked {
+		return time.Time{}, errDeletionAlreadyRequested
+	}
+	if err := s.app.Services.Auth.LogoutAll(ctx, userID); err != nil {
+		return time.Time{}, err
+	}
+
+	purgeAt := requestedAt.Add(gracePeriod)
+	s.logAction(userID, "account_deletion_requested")
+	go s.sendDeletionEmail(user, rawToken, purgeAt)
+	return purgeAt, nil
+}
+
+// CancelDeletion restores an account scheduled for deletion using the emailed link.
+func (s *Service) CancelDeletion(ctx context.Context, token string) error {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return errCancelTokenInvalid
+	}
+	hashed := hashToken(token)
+	user, err := s.repo.FindByCancelToken(ctx, hashed)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errCancelTokenInvalid
+		}
+		return err
+	}
+	cancelled, err := s.repo.CancelDeletion(ctx, user.ID, hashed)
+	if err != nil {
+		return err
+	}
+	if !cancelled {
+		return errCancelTokenInvalid
+	}
+	s.logAction(user.ID, "account_deletion_cancelled")
+	s.app.Services.Audit.Record(ctx, user.ID, constants.AuditDeletionCancelled, constants.AuditSuccess, "")
+	return nil
+}
+
+// PurgeDueDeletions permanently removes accounts whose grace period has passed.
+// Each account is purged in its own transaction by the repository.
+func (s *Service) PurgeDueDeletions(ctx context.Context) (int, error) {
+	cutoff := time.Now().UTC().Add(-gracePeriod)
+	users, err := s.repo.ListDeletionsDue(ctx, cutoff, purgeBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, user := range users {
+		ok, err := s.repo.Purge(ctx, user.ID, cutoff)
+		if err != nil {
+			return purged, err
+		}
+		if ok {
+			purged++
+			s.logAction(user.ID, "account_purged")
+			s.app.Services.Audit.Record(ctx, user.ID, constants.AuditAccountPurged, constants.AuditSuccess, "")
+		}
+	}
+	return purged, nil
+}
+
+// RunPurgeWorker purges due accounts every interval until ctx is cancelled.
+// Running it on several containers is safe; the purge locks the user row.
+func (s *Service) RunPurgeWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.PurgeDueDeletions(ctx); err != nil {
+			s.app.Logger.Error().
+				Err(err).
+				Msg("account_purge_failed")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Service) sendDeletionEmail(user *entities.User, token string, purgeAt time.Time) {
+	variables := map[string]interface{}{
+		"name":       user.Name,
+		"link":       s.buildCancelURL(token),
+		"purge_date": purgeAt.Format("02 January 2006"),
+	}
+
+	if err := s.app.Services.Email.SendTemplate(user.Email, email.TemplateAccountDeletion, variables); err != nil {
+		s.app.Logger.Error().
+			Err(err).
+			Str("email", user.Email).
+			Msg("account_deletion_email_failed")
+	}
+}
+
+func (s *Service) buildCancelURL(token string) string {
+	baseURL := strings.TrimRight(strings.TrimSpace(s.app.Config[constants.APIBaseURL]), "/")
+	if baseURL == "" {
+		baseURL = "https://api.finlog.app"
+	}
+	return fmt.Sprintf("%s/v1/account/deletion/cancel?token=%s", baseURL, url.QueryEscape(token))
+}
+
+func (s *Service) logAction(userID int64, action string) {
+	s.app.Logger.Info().
+		Int64("user_id", userID).
+		Str("action", action).
+		Msg("account event")
+}
+
+// generateToken returns a link token in the same format the auth service uses.
+func generateToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashToken(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,258 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"finlog-api/api/constants"
+	"finlog-api/api/contracts"
+	"finlog-api/api/entities"
+)
+
+// fakeRepo keeps users and a count of their transactions in memory, applying the
+// same conditions as the queries.
+type fakeRepo struct {
+	users        map[int64]*entities.User
+	transactions map[int64]int
+}
+
+func (f *fakeRepo) FindUser(ctx context.Context, userID int64) (*entities.User, error) {
+	user, ok := f.users[userID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (f *fakeRepo) FindByCancelToken(ctx context.Context, tokenHash string) (*entities.User, error) {
+	for _, user := range f.users {
+		if user.DeletionCancelToken != nil && *user.DeletionCancelToken == tokenHash {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeRepo) MarkDeletionRequested(ctx context.Context, userID int64, tokenHash string, requestedAt time.Time) (bool, error) {
+	user, ok := f.users[userID]
+	if !ok || user.DeletionRequestedAt != nil {
+		return false, nil
+	}
+	user.DeletionRequestedAt = &requestedAt
+	user.DeletionCancelToken = &tokenHash
+	return true, nil
+}
+
+func (f *fakeRepo) CancelDeletion(ctx context.Context, userID int64, tokenHash string) (bool, error) {
+	user, ok := f.users[userID]
+	if !ok || user.DeletionCancelToken == nil || *user.DeletionCancelToken != tokenHash {
+		return false, nil
+	}
+	user.DeletionRequestedAt = nil
+	user.DeletionCancelToken = nil
+	return true, nil
+}
+
+func (f *fakeRepo) ListDeletionsDue(ctx context.Context, requestedBefore time.Time, limit int) ([]entities.User, error) {
+	var due []entities.User
+	for _, user := range f.users {
+		if user.DeletionRequestedAt != nil && !user.DeletionRequestedAt.After(requestedBefore) && len(due) < limit {
+			due = append(due, *user)
+		}
+	}
+	return due, nil
+}
+
+func (f *fakeRepo) Purge(ctx context.Context, userID int64, requestedBefore time.Time) (bool, error) {
+	user, ok := f.users[userID]
+	if !ok || user.DeletionRequestedAt == nil || user.DeletionRequestedAt.After(requestedBefore) {
+		return false, nil
+	}
+	delete(f.users, userID)
+	delete(f.transactions, userID)
+	return true, nil
+}
+
+type fakeAuth struct {
+	contracts.AuthService
+	reauthErr error
+	loggedOut []int64
+}
+
+func (f *fakeAuth) Reauthenticate(ctx context.Context, userID int64, password, code string) error {
+	return f.reauthErr
+}
+
+func (f *fakeAuth) LogoutAll(ctx context.Context, userID int64) error {
+	f.loggedOut = append(f.loggedOut, userID)
+	return nil
+}
+
+type fakeAudit struct {
+	contracts.AuditService
+	actions []string
+}
+
+func (f *fakeAudit) Record(ctx context.Context, userID int64, action, outcome, detail string) {
+	f.actions = append(f.actions, action+":"+outcome)
+}
+
+type fakeEmail struct {
+	contracts.EmailService
+	sent chan map[string]interface{}
+}
+
+func (f *fakeEmail) SendTemplate(to, templateID string, variables map[string]interface{}) error {
+	f.sent <- variables
+	return nil
+}
+
+func newTestService(repo *fakeRepo) (*Service, *fakeAuth, *fakeEmail) {
+	logger := zerolog.Nop()
+	auth := &fakeAuth{}
+	mail := &fakeEmail{sent: make(chan map[string]interface{}, 1)}
+	app := &contracts.App{
+		Config:   map[string]string{},
+		Logger:   &logger,
+		Services: &contracts.Services{Auth: auth, Email: mail, Audit: &fakeAudit{}},
+	}
+	return &Service{app: app, repo: repo}, auth, mail
+}
+
+func auditedActions(svc *Service) []string {
+	return svc.app.Services.Audit.(*fakeAudit).actions
+}
+
+func TestRequestDeletionRequiresReauthentication(t *testing.T) {
+	repo := &fakeRepo{users: map[int64]*entities.User{1: {ID: 1, Email: "a@example.com"}}}
+	svc, auth, _ := newTestService(repo)
+	reauthErr := errors.New("invalid credentials")
+	auth.reauthErr = reauthErr
+
+	if _, err := svc.RequestDeletion(context.Background(), 1, "wrong", ""); !errors.Is(err, reauthErr) {
+		t.Fatalf("expected the re-authentication error, got %v", err)
+	}
+	if repo.users[1].DeletionRequestedAt != nil || len(auth.loggedOut) != 0 {
+		t.Fatalf("expected nothing to change, got %+v and logouts %v", repo.users[1], auth.loggedOut)
+	}
+	if got := auditedActions(svc); !slices.Equal(got, []string{constants.AuditDeletionRequested + ":" + constants.AuditFailure}) {
+		t.Fatalf("expected the failed request to be audited, got %v", got)
+	}
+}
+
+func TestCancelDeletionWithEmailedToken(t *testing.T) {
+	repo := &fakeRepo{users: map[int64]*entities.User{1: {ID: 1, Email: "a@example.com"}}}
+	svc, auth, mail := newTestService(repo)
+	ctx := context.Background()
+
+	purgeAt, err := svc.RequestDeletion(ctx, 1, "secret", "")
+	if err != nil {
+		t.Fatalf("request deletion: %v", err)
+	}
+	if until := time.Until(purgeAt); until < gracePeriod-time.Minute || until > gracePeriod {
+		t.Fatalf("expected the purge after the grace period, got %v", purgeAt)
+	}
+	if len(auth.loggedOut) != 1 || auth.loggedOut[0] != 1 {
+		t.Fatalf("expected every session to be signed out, got %v", auth.loggedOut)
+	}
+	if _, err := svc.RequestDeletion(ctx, 1, "secret", ""); !errors.Is(err, errDeletionAlreadyRequested) {
+		t.Fatalf("expected errDeletionAlreadyRequested, got %v", err)
+	}
+
+	var variables map[string]interface{}
+	select {
+	case variables = <-mail.sent:
+	case <-time.After(time.Second):
+		t.Fatal("expected a deletion email")
+	}
+	link, err := url.Parse(variables["link"].(string))
+	if err != nil {
+		t.Fatalf("parse link: %v", err)
+	}
+	token := link.Query().Get("token")
+	if token == "" || *repo.users[1].DeletionCancelToken == token {
+		t.Fatalf("expected the link to carry the raw token and the repository its hash")
+	}
+
+	if err := svc.CancelDeletion(ctx, "not-the-token"); !errors.Is(err, errCancelTokenInvalid) {
+		t.Fatalf("expected errCancelTokenInvalid for a wrong token, got %v", err)
+	}
+	if err := svc.CancelDeletion(ctx, token); err != nil {
+		t.Fatalf("cancel deletion: %v", err)
+	}
+	if repo.users[1].DeletionRequestedAt != nil {
+		t.Fatalf("expected the deletion to be cancelled, got %+v", repo.users[1])
+	}
+	if err := svc.CancelDeletion(ctx, token); !errors.Is(err, errCancelTokenInvalid) {
+		t.Fatalf("expected the token to work once, got %v", err)
+	}
+	want := []string{
+		constants.AuditDeletionRequested + ":" + constants.AuditSuccess,
+		constants.AuditDeletionCancelled + ":" + constants.AuditSuccess,
+	}
+	if got := auditedActions(svc); !slices.Equal(got, want) {
+		t.Fatalf("expected %v to be audited, got %v", want, got)
+	}
+}
+
+func TestPurgeDueDeletionsWaitsForGracePeriod(t *testing.T) {
+	now := time.Now().UTC()
+	due := now.Add(-gracePeriod - time.Hour)
+	pending := now.Add(-gracePeriod + time.Hour)
+	repo := &fakeRepo{
+		users: map[int64]*entities.User{
+			1: {ID: 1, DeletionRequestedAt: &due},
+			2: {ID: 2, DeletionRequestedAt: &pending},
+			3: {ID: 3},
+		},
+		transactions: map[int64]int{1: 5, 2: 5, 3: 5},
+	}
+	svc, _, _ := newTestService(repo)
+
+	purged, err := svc.PurgeDueDeletions(context.Background())
+	if err != nil || purged != 1 {
+		t.Fatalf("expected 1 purged account, got %d, %v", purged, err)
+	}
+	if _, ok := repo.users[1]; ok {
+		t.Fatalf("expected the due account to be purged")
+	}
+	if got := auditedActions(svc); !slices.Equal(got, []string{constants.AuditAccountPurged + ":" + constants.AuditSuccess}) {
+		t.Fatalf("expected the purge to be audited, got %v", got)
+	}
+	for _, id := range []int64{2, 3} {
+		if _, ok := repo.users[id]; !ok || repo.transactions[id] != 5 {
+			t.Fatalf("expected user %d and its transactions to be untouched", id)
+		}
+	}
+}
+
+func TestRunPurgeWorkerStopsWithContext(t *testing.T) {
+	due := time.Now().UTC().Add(-gracePeriod - time.Hour)
+	repo := &fakeRepo{users: map[int64]*entities.User{1: {ID: 1, DeletionRequestedAt: &due}}}
+	svc, _, _ := newTestService(repo)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		svc.RunPurgeWorker(ctx, time.Hour)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected the worker to stop once the context is cancelled")
+	}
+	if len(repo.users) != 0 {
+		t.Fatalf("expected a purge pass before stopping, got %d users left", len(repo.users))
+	}
+}
@@ -0,0 +1,54 @@
+package admin
+
+const (
+	searchUsersQuery = `
+		SELECT * FROM users
+		WHERE email LIKE ?
+		ORDER BY email
+		LIMIT ?
+	`
+
+	findUserQuery = `
+		SELECT * FROM users WHERE id = ? LIMIT 1
+	`
+
+	findUserByEmailQuery = `
+		SELECT * FROM users WHERE email = ? LIMIT 1
+	`
+
+	listEmailMessagesQuery = `
+		SELECT id, resend_id, to_email, subject, status, last_error, last_event_at, created_at, updated_at
+		FROM email_messages
+		WHERE to_email = ?
+		ORDER BY created_at DESC
+		LIMIT ?
+	`
+
+	listEmailSuppressionsQuery = `
+		SELECT id, email, reason, resend_id, created_at
+		FROM email_suppressions
+		WHERE email = ?
+		ORDER BY created_at DESC
+	`
+
+	// A pending email change shares the verification token, so it is kept then.
+	forceVerifyQuery = `
+		UPDATE users
+		SET is_verified = 1,
+			verification_token = IF(pending_email IS NULL, NULL, verification_token),
+			verification_expires_at = IF(pending_email IS NULL, NULL, verification_expires_at)
+		WHERE id = ? AND is_verified = 0
+	`
+
+	disableUserQuery = `
+		UPDATE users SET disabled_at = ? WHERE id = ? AND disabled_at IS NULL
+	`
+
+	enableUserQuery = `
+		UPDATE users SET disabled_at = NULL WHERE id = ? AND disabled_at IS NOT NULL
+	`
+
+	setRoleQuery = `
+		UPDATE users SET role = ? WHERE id = ?
+	`
+)
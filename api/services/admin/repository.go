@@ -0,0 +1,96 @@
+package admin
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"finlog-api/api/contracts"
+	"finlog-api/api/entities"
+)
+
+type repository struct {
+	reader *sqlx.DB
+	writer *sqlx.DB
+}
+
+func initRepository(app *contracts.App) contracts.AdminRepository {
+	return &repository{
+		reader: app.Ds.ReaderDB,
+		writer: app.Ds.WriterDB,
+	}
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// SearchUsers matches emails starting with emailPrefix, which is taken literally.
+func (r *repository) SearchUsers(ctx context.Context, emailPrefix string, limit int) ([]entities.User, error) {
+	users := []entities.User{}
+	if err := r.reader.SelectContext(ctx, &users, searchUsersQuery, likeEscaper.Replace(emailPrefix)+"%", limit); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *repository) FindUser(ctx context.Context, userID int64) (*entities.User, error) {
+	user := new(entities.User)
+	if err := r.reader.GetContext(ctx, user, findUserQuery, userID); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (r *repository) FindUserByEmail(ctx context.Context, email string) (*entities.User, error) {
+	user := new(entities.User)
+	if err := r.reader.GetContext(ctx, user, findUserByEmailQuery, email); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (r *repository) ListEmailMessages(ctx context.Context, email string, limit int) ([]entities.EmailMessage, error) {
+	messages := []entities.EmailMessage{}
+	if err := r.reader.SelectContext(ctx, &messages, listEmailMessagesQuery, email, limit); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+func (r *repository) ListEmailSuppressions(ctx context.Context, email string) ([]entities.EmailSuppression, error) {
+	suppressions := []entities.EmailSuppression{}
+	if err := r.reader.SelectContext(ctx, &suppressions, listEmailSuppressionsQuery, email); err != nil {
+		return nil, err
+	}
+	return suppressions, nil
+}
+
+// ForceVerify reports false when the account was already verified.
+func (r *repository) ForceVerify(ctx context.Context, userID int64) (bool, error) {
+	return r.update(ctx, forceVerifyQuery, userID)
+}
+
+// DisableUser reports false when the account was already disabled.
+func (r *repository) DisableUser(ctx context.Context, userID int64, at time.Time) (bool, error) {
+	return r.update(ctx, disableUserQuery, at, userID)
+}
+
+// EnableUser reports false when the account was not disabled.
+func (r *repository) EnableUser(ctx context.Context, userID int64) (bool, error) {
+	return r.update(ctx, enableUserQuery, userID)
+}
+
+func (r *repository) SetRole(ctx context.Context, userID int64, role string) error {
+	_, err := r.writer.ExecContext(ctx, setRoleQuery, role, userID)
+	return err
+}
+
+func (r *repository) update(ctx context.Context, query string, args ...any) (bool, error) {
+	res, err := r.writer.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+	affected, _ := res.RowsAffected()
+	return affected > 0, nil
+}
@@ -0,0 +1,226 @@
+package admin
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"finlog-api/api/constants"
+	"finlog-api/api/contracts"
+	"finlog-api/api/entities"
+)
+
+const (
+	searchLimit      = 50
+	emailStatusLimit = 20
+)
+
+var (
+	errUserNotFound      = errors.New("user not found")
+	errSearchEmpty       = errors.New("email search is required")
+	errAlreadyVerified   = errors.New("email already verified")
+	errAlreadyDisabled   = errors.New("account already disabled")
+	errNotDisabled       = errors.New("account is not disabled")
+	errCannotDisableSelf = errors.New("admins cannot disable their own account")
+)
+
+func ErrUserNotFound() error      { return errUserNotFound }
+func ErrSearchEmpty() error       { return errSearchEmpty }
+func ErrAlreadyVerified() error   { return errAlreadyVerified }
+func ErrAlreadyDisabled() error   { return errAlreadyDisabled }
+func ErrNotDisabled() error       { return errNotDisabled }
+func ErrCannotDisableSelf() error { return errCannotDisableSelf }
+
+type Service struct {
+	app  *contracts.App
+	repo contracts.AdminRepository
+}
+
+func Init(app *contracts.App) contracts.AdminService {
+	return &Service{
+		app:  app,
+		repo: initRepository(app),
+	}
+}
+
+// CurrentRole reads the role from the database rather than the token, so a
+// demotion or a disabled account takes effect on the next request. It returns
+// an empty role for disabled or missing accounts.
+func (s *Service) CurrentRole(ctx context.Context, userID int64) (string, error) {
+	user, err := s.repo.FindUser(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+		return "", err
+	}
+	if user.DisabledAt != nil {
+		return "", nil
+	}
+	return user.Role, nil
+}
+
+// SearchUsers lists accounts whose email starts with email.
+func (s *Service) SearchUsers(ctx context.Context, actorID int64, email string) ([]entities.User, error) {
+	email = normalizeEmail(email)
+	if email == "" {
+		return nil, errSearchEmpty
+	}
+	users, err := s.repo.SearchUsers(ctx, email, searchLimit)
+	if err != nil {
+		return nil, err
+	}
+	s.app.Services.Audit.RecordAs(ctx, actorID, 0, constants.AuditAdminUserSearch, constants.AuditSuccess, email)
+	return users, nil
+}
+
+// GetUser returns the account with the delivery status of recent emails sent to
+// its current and pending address, and any suppressions blocking them.
+func (s *Service) GetUser(ctx context.Context, actorID, userID int64) (*contracts.AdminUserDetail, error) {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	detail := &contracts.AdminUserDetail{
+		User:         user,
+		Emails:       []entities.EmailMessage{},
+		Suppressions: []entities.EmailSuppression{},
+	}
+	addresses := []string{user.Email}
+	if user.PendingEmail != nil {
+		addresses = append(addresses, *user.PendingEmail)
+	}
+	for _, address := range addresses {
+		messages, err := s.repo.ListEmailMessages(ctx, address, emailStatusLimit)
+		if err != nil {
+			return nil, err
+		}
+		suppressions, err := s.repo.ListEmailSuppressions(ctx, address)
+		if err != nil {
+			return nil, err
+		}
+		detail.Emails = append(detail.Emails, messages...)
+		detail.Suppressions = append(detail.Suppressions, suppressions...)
+	}
+	s.app.Services.Audit.RecordAs(ctx, actorID, userID, constants.AuditAdminUserViewed, constants.AuditSuccess, "")
+	return detail, nil
+}
+
+// ResendVerification sends the user a fresh verification link.
+func (s *Service) ResendVerification(ctx context.Context, actorID, userID int64) error {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.IsVerified {
+		return errAlreadyVerified
+	}
+	if err := s.app.Services.Auth.ResendVerification(ctx, user.Email); err != nil {
+		return err
+	}
+	s.app.Services.Audit.RecordAs(ctx, actorID, userID, constants.AuditAdminVerificationResent, constants.AuditSuccess, "")
+	return nil
+}
+
+// ForceVerify marks the email verified without the user following the link, for
+// users whose mail never arrives.
+func (s *Service) ForceVerify(ctx context.Context, actorID, userID int64) error {
+	if _, err := s.findUser(ctx, userID); err != nil {
+		return err
+	}
+	verified, err := s.repo.ForceVerify(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !verified {
+		return errAlreadyVerified
+	}
+	s.app.Services.Audit.RecordAs(ctx, actorID, userID, constants.AuditAdminUserVerified, constants.AuditSuccess, "")
+	return nil
+}
+
+// DisableUser blocks sign-in and personal access tokens, and signs out every
+// session. Data is kept; EnableUser reverses it.
+func (s *Service) DisableUser(ctx context.Context, actorID, userID int64) error {
+	if actorID == userID {
+		return errCannotDisableSelf
+	}
+	if _, err := s.findUser(ctx, userID); err != nil {
+		return err
+	}
+	disabled, err := s.repo.DisableUser(ctx, userID, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if !disabled {
+		return errAlreadyDisabled
+	}
+	s.app.Services.Audit.RecordAs(ctx, actorID, userID, constants.AuditAdminUserDisabled, constants.AuditSuccess, "")
+	return s.app.Services.Auth.LogoutAll(ctx, userID)
+}
+
+func (s *Service) EnableUser(ctx context.Context, actorID, userID int64) error {
+	if _, err := s.findUser(ctx, userID); err != nil {
+		return err
+	}
+	enabled, err := s.repo.EnableUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return errNotDisabled
+	}
+	s.app.Services.Audit.RecordAs(ctx, actorID, userID, constants.AuditAdminUserEnabled, constants.AuditSuccess, "")
+	return nil
+}
+
+// ScheduleDeletion starts the same grace period as a self-service deletion, so
+// the user can still cancel from the emailed link.
+func (s *Service) ScheduleDeletion(ctx context.Context, actorID, userID int64) (time.Time, error) {
+	if _, err := s.findUser(ctx, userID); err != nil {
+		return time.Time{}, err
+	}
+	purgeAt, err := s.app.Services.Account.ScheduleDeletion(ctx, userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	s.app.Services.Audit.RecordAs(ctx, actorID, userID, constants.AuditAdminDeletionScheduled, constants.AuditSuccess, "")
+	return purgeAt, nil
+}
+
+// PromoteToAdmin grants the admin role. It is run from the command line, so the
+// event has no actor.
+func (s *Service) PromoteToAdmin(ctx context.Context, email string) error {
+	user, err := s.repo.FindUserByEmail(ctx, normalizeEmail(email))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errUserNotFound
+		}
+		return err
+	}
+	if user.Role == constants.RoleAdmin {
+		return nil
+	}
+	if err := s.repo.SetRole(ctx, user.ID, constants.RoleAdmin); err != nil {
+		return err
+	}
+	s.app.Services.Audit.RecordAs(ctx, 0, user.ID, constants.AuditRoleChanged, constants.AuditSuccess, constants.RoleAdmin)
+	return nil
+}
+
+func (s *Service) findUser(ctx context.Context, userID int64) (*entities.User, error) {
+	user, err := s.repo.FindUser(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errUserNotFound
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+func normalizeEmail(email string) string {
+	return strings.TrimSpace(strings.ToLower(email))
+}
@@ -0,0 +1,176 @@
+package admin
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"finlog-api/api/contracts"
+	"finlog-api/api/entities"
+)
+
+type fakeRepo struct {
+	users map[int64]*entities.User
+}
+
+func (f *fakeRepo) SearchUsers(ctx context.Context, emailPrefix string, limit int) ([]entities.User, error) {
+	return nil, nil
+}
+
+func (f *fakeRepo) FindUser(ctx context.Context, userID int64) (*entities.User, error) {
+	user, ok := f.users[userID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (f *fakeRepo) FindUserByEmail(ctx context.Context, email string) (*entities.User, error) {
+	for _, user := range f.users {
+		if user.Email == email {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeRepo) ListEmailMessages(ctx context.Context, email string, limit int) ([]entities.EmailMessage, error) {
+	return nil, nil
+}
+
+func (f *fakeRepo) ListEmailSuppressions(ctx context.Context, email string) ([]entities.EmailSuppression, error) {
+	return nil, nil
+}
+
+func (f *fakeRepo) ForceVerify(ctx context.Context, userID int64) (bool, error) {
+	user := f.users[userID]
+	if user.IsVerified {
+		return false, nil
+	}
+	user.IsVerified = true
+	return true, nil
+}
+
+func (f *fakeRepo) DisableUser(ctx context.Context, userID int64, at time.Time) (bool, error) {
+	user := f.users[userID]
+	if user.DisabledAt != nil {
+		return false, nil
+	}
+	user.DisabledAt = &at
+	return true, nil
+}
+
+func (f *fakeRepo) EnableUser(ctx context.Context, userID int64) (bool, error) {
+	user := f.users[userID]
+	if user.DisabledAt == nil {
+		return false, nil
+	}
+	user.DisabledAt = nil
+	return true, nil
+}
+
+func (f *fakeRepo) SetRole(ctx context.Context, userID int64, role string) error {
+	f.users[userID].Role = role
+	return nil
+}
+
+type fakeAudit struct {
+	contracts.AuditService
+	actions []string
+}
+
+func (f *fakeAudit) RecordAs(ctx context.Context, actorID, userID int64, action, outcome, detail string) {
+	f.actions = append(f.actions, action)
+}
+
+// fakeAuth only signs users out; the admin service needs nothing else here.
+type fakeAuth struct {
+	contracts.AuthService
+	loggedOut []int64
+}
+
+func (f *fakeAuth) LogoutAll(ctx context.Context, userID int64) error {
+	f.loggedOut = append(f.loggedOut, userID)
+	return nil
+}
+
+func newTestService() (*Service, *fakeAudit, *fakeAuth) {
+	logger := zerolog.Nop()
+	audit := &fakeAudit{}
+	auth := &fakeAuth{}
+	return &Service{
+		app: &contracts.App{
+			Logger:   &logger,
+			Services: &contracts.Services{Audit: audit, Auth: auth},
+		},
+		repo: &fakeRepo{users: map[int64]*entities.User{
+			1: {ID: 1, Email: "admin@example.com", Role: "admin", IsVerified: true},
+			2: {ID: 2, Email: "user@example.com", Role: "user"},
+		}},
+	}, audit, auth
+}
+
+func TestDisableUserRevokesAccessAndRole(t *testing.T) {
+	svc, audit, auth := newTestService()
+	ctx := context.Background()
+
+	if err := svc.DisableUser(ctx, 1, 1); !errors.Is(err, errCannotDisableSelf) {
+		t.Fatalf("expected admins to be kept from disabling themselves, got %v", err)
+	}
+	if err := svc.PromoteToAdmin(ctx, " User@Example.com "); err != nil {
+		t.Fatalf("promote: %v", err)
+	}
+	if role, _ := svc.CurrentRole(ctx, 2); role != "admin" {
+		t.Fatalf("expected the promoted role, got %q", role)
+	}
+
+	if err := svc.DisableUser(ctx, 1, 2); err != nil {
+		t.Fatalf("disable: %v", err)
+	}
+	if len(auth.loggedOut) != 1 || auth.loggedOut[0] != 2 {
+		t.Fatalf("expected every session of the user to be revoked, got %v", auth.loggedOut)
+	}
+	if role, _ := svc.CurrentRole(ctx, 2); role != "" {
+		t.Fatalf("expected a disabled admin to lose the role, got %q", role)
+	}
+	if err := svc.DisableUser(ctx, 1, 2); !errors.Is(err, errAlreadyDisabled) {
+		t.Fatalf("expected a second disable to conflict, got %v", err)
+	}
+	if err := svc.EnableUser(ctx, 1, 2); err != nil {
+		t.Fatalf("enable: %v", err)
+	}
+
+	want := []string{"role_changed", "admin_user_disabled", "admin_user_enabled"}
+	if len(audit.actions) != len(want) {
+		t.Fatalf("expected audit actions %v, got %v", want, audit.actions)
+	}
+	for i := range want {
+		if audit.actions[i] != want[i] {
+			t.Fatalf("expected audit actions %v, got %v", want, audit.actions)
+		}
+	}
+}
+
+func TestForceVerify(t *testing.T) {
+	svc, audit, _ := newTestService()
+	ctx := context.Background()
+
+	if err := svc.ForceVerify(ctx, 1, 2); err != nil {
+		t.Fatalf("force verify: %v", err)
+	}
+	if err := svc.ForceVerify(ctx, 1, 2); !errors.Is(err, errAlreadyVerified) {
+		t.Fatalf("expected an already verified user to conflict, got %v", err)
+	}
+	if err := svc.ForceVerify(ctx, 1, 99); !errors.Is(err, errUserNotFound) {
+		t.Fatalf("expected an unknown user to be reported, got %v", err)
+	}
+	if len(audit.actions) != 1 || audit.actions[0] != "admin_user_verified" {
+		t.Fatalf("expected one audited verification, got %v", audit.actions)
+	}
+}
@@ -0,0 +1,12 @@
+package audit
+
+const (
+	createEventQuery = `
+		INSERT INTO audit_events (user_id, actor_id, action, outcome, detail, ip_address, user_agent, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	listEventsQuery = `
+		SELECT * FROM audit_events
+	`
+)
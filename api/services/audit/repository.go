@@ -0,0 +1,79 @@
+package audit
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+
+	"finlog-api/api/contracts"
+	"finlog-api/api/entities"
+)
+
+type repository struct {
+	reader *sqlx.DB
+	writer *sqlx.DB
+}
+
+func initRepository(app *contracts.App) contracts.AuditRepository {
+	return &repository{
+		reader: app.Ds.ReaderDB,
+		writer: app.Ds.WriterDB,
+	}
+}
+
+func (r *repository) CreateEvent(ctx context.Context, event *entities.AuditEvent) error {
+	_, err := r.writer.ExecContext(ctx, createEventQuery,
+		event.UserID,
+		event.ActorID,
+		event.Action,
+		event.Outcome,
+		event.Detail,
+		event.IPAddress,
+		event.UserAgent,
+		event.CreatedAt,
+	)
+	return err
+}
+
+func (r *repository) ListEvents(ctx context.Context, filter contracts.AuditFilter) ([]entities.AuditEvent, error) {
+	var conditions []string
+	var args []interface{}
+	if filter.UserID != nil {
+		conditions = append(conditions, "user_id = ?")
+		args = append(args, *filter.UserID)
+	}
+	if filter.Action != "" {
+		conditions = append(conditions, "action = ?")
+		args = append(args, filter.Action)
+	}
+	if filter.IPAddress != "" {
+		conditions = append(conditions, "ip_address = ?")
+		args = append(args, filter.IPAddress)
+	}
+	if filter.Since != nil {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, *filter.Since)
+	}
+	if filter.Until != nil {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, *filter.Until)
+	}
+	if filter.BeforeID > 0 {
+		conditions = append(conditions, "id < ?")
+		args = append(args, filter.BeforeID)
+	}
+
+	query := listEventsQuery
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, filter.Limit)
+
+	events := []entities.AuditEvent{}
+	if err := r.reader.SelectContext(ctx, &events, query, args...); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
@@ -0,0 +1,118 @@
+package audit
+
+import (
+	"context"
+	"time"
+
+	"finlog-api/api/contracts"
+	"finlog-api/api/entities"
+	"finlog-api/api/helpers"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+	maxDetailLength = 64
+)
+
+type Service struct {
+	app  *contracts.App
+	repo contracts.AuditRepository
+}
+
+func Init(app *contracts.App) contracts.AuditService {
+	return &Service{
+		app:  app,
+		repo: initRepository(app),
+	}
+}
+
+// Record stores the event and mirrors it to the application log. A userID of 0
+// records an event that could not be tied to an account.
+func (s *Service) Record(ctx context.Context, userID int64, action, outcome, detail string) {
+	s.RecordAs(ctx, userID, userID, action, outcome, detail)
+}
+
+func (s *Service) RecordAs(ctx context.Context, actorID, userID int64, action, outcome, detail string) {
+	client := helpers.ClientInfoFrom(ctx)
+	event := &entities.AuditEvent{
+		Action:    action,
+		Outcome:   outcome,
+		Detail:    optional(truncate(detail, maxDetailLength)),
+		IPAddress: optional(client.IPAddress),
+		UserAgent: optional(client.UserAgent),
+		CreatedAt: time.Now().UTC(),
+	}
+	if userID > 0 {
+		event.UserID = &userID
+	}
+	if actorID > 0 {
+		event.ActorID = &actorID
+	}
+
+	s.app.Logger.Info().
+		Int64("user_id", userID).
+		Int64("actor_id", actorID).
+		Str("action", action).
+		Str("outcome", outcome).
+		Str("detail", detail).
+		Str("ip", client.IPAddress).
+		Msg("audit event")
+
+	// The request context may be cancelled as soon as the response is written;
+	// the event must still be stored.
+	if err := s.repo.CreateEvent(context.WithoutCancel(ctx), event); err != nil {
+		s.app.Logger.Error().
+			Err(err).
+			Int64("user_id", userID).
+			Str("action", action).
+			Msg("audit_event_failed")
+	}
+}
+
+// ListForUser returns the user's own events.
+func (s *Service) ListForUser(ctx context.Context, userID, beforeID int64, limit int) ([]entities.AuditEvent, int64, error) {
+	return s.Search(ctx, contracts.AuditFilter{
+		UserID:   &userID,
+		BeforeID: beforeID,
+		Limit:    limit,
+	})
+}
+
+// Search returns events across all users for support investigations.
+func (s *Service) Search(ctx context.Context, filter contracts.AuditFilter) ([]entities.AuditEvent, int64, error) {
+	size := pageSize(filter.Limit)
+	// One extra row tells whether another page follows.
+	filter.Limit = size + 1
+	events, err := s.repo.ListEvents(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(events) <= size {
+		return events, 0, nil
+	}
+	events = events[:size]
+	return events, events[size-1].ID, nil
+}
+
+func pageSize(limit int) int {
+	if limit <= 0 {
+		return defaultPageSize
+	}
+	return min(limit, maxPageSize)
+}
+
+func optional(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+func truncate(value string, max int) string {
+	runes := []rune(value)
+	if len(runes) > max {
+		return string(runes[:max])
+	}
+	return value
+}
@@ -0,0 +1,85 @@
+package audit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"finlog-api/api/contracts"
+	"finlog-api/api/entities"
+	"finlog-api/api/helpers"
+)
+
+type fakeRepo struct {
+	events []entities.AuditEvent
+}
+
+func (f *fakeRepo) CreateEvent(ctx context.Context, event *entities.AuditEvent) error {
+	copied := *event
+	copied.ID = int64(len(f.events) + 1)
+	f.events = append(f.events, copied)
+	return nil
+}
+
+func (f *fakeRepo) ListEvents(ctx context.Context, filter contracts.AuditFilter) ([]entities.AuditEvent, error) {
+	var out []entities.AuditEvent
+	for i := len(f.events) - 1; i >= 0 && len(out) < filter.Limit; i-- {
+		event := f.events[i]
+		if filter.UserID != nil && (event.UserID == nil || *event.UserID != *filter.UserID) {
+			continue
+		}
+		if filter.BeforeID > 0 && event.ID >= filter.BeforeID {
+			continue
+		}
+		out = append(out, event)
+	}
+	return out, nil
+}
+
+func newTestService() *Service {
+	logger := zerolog.Nop()
+	return &Service{
+		app:  &contracts.App{Logger: &logger},
+		repo: &fakeRepo{},
+	}
+}
+
+func TestRecordCapturesClient(t *testing.T) {
+	svc := newTestService()
+	ctx := helpers.WithClientInfo(context.Background(), entities.ClientInfo{IPAddress: "203.0.113.7", UserAgent: "FinLog/1.0"})
+
+	svc.Record(ctx, 7, "login", "success", "")
+	svc.Record(ctx, 0, "login", "failure", "unknown_account")
+
+	events := svc.repo.(*fakeRepo).events
+	if *events[0].UserID != 7 || *events[0].ActorID != 7 || *events[0].IPAddress != "203.0.113.7" || *events[0].UserAgent != "FinLog/1.0" || events[0].Detail != nil {
+		t.Fatalf("unexpected event %+v", events[0])
+	}
+	if events[1].UserID != nil || *events[1].Detail != "unknown_account" {
+		t.Fatalf("expected an anonymous failure, got %+v", events[1])
+	}
+}
+
+func TestListForUserPages(t *testing.T) {
+	svc := newTestService()
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		svc.Record(ctx, 1, "login", "success", "")
+		svc.Record(ctx, 2, "login", "success", "")
+	}
+
+	first, next, err := svc.ListForUser(ctx, 1, 0, 3)
+	if err != nil || len(first) != 3 || next != first[2].ID {
+		t.Fatalf("unexpected first page %+v, next %d, err %v", first, next, err)
+	}
+	second, next, err := svc.ListForUser(ctx, 1, next, 3)
+	if err != nil || len(second) != 2 || next != 0 {
+		t.Fatalf("unexpected last page %+v, next %d, err %v", second, next, err)
+	}
+	for _, event := range append(first, second...) {
+		if *event.UserID != 1 {
+			t.Fatalf("expected only the user's own events, got %+v", event)
+		}
+	}
+}
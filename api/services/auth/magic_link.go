@@ -0,0 +1,161 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"finlog-api/api/entities"
+	"finlog-api/api/services/email"
+)
+
+const (
+	magicLinkTTL = 15 * time.Minute
+	// At most magicLinkLimit links are sent to one account per magicLinkWindow.
+	magicLinkLimit  = 3
+	magicLinkWindow = 15 * time.Minute
+)
+
+var errMagicLinkInvalid = errors.New("invalid or expired sign-in link")
+
+func ErrMagicLinkInvalid() error { return errMagicLinkInvalid }
+
+// MagicLinkConfirmationError is returned by ConsumeMagicLink when the link is
+// opened on a different device from the one that requested it. Repeating the call
+// with confirmed set signs in as Email.
+type MagicLinkConfirmationError struct {
+	Email string
+}
+
+func (e *MagicLinkConfirmationError) Error() string { return "magic link confirmation required" }
+
+// RequestMagicLink emails a sign-in link and returns the device token the caller
+// must present when consuming it. The response is the same whether or not the
+// account exists or is rate limited, so it cannot be used to probe emails.
+func (s *Service) RequestMagicLink(ctx context.Context, emailAddress string) (string, error) {
+	emailAddress = normalizeEmail(emailAddress)
+	if emailAddress == "" {
+		return "", errInvalidInput
+	}
+	deviceToken, err := generateRandomToken()
+	if err != nil {
+		return "", err
+	}
+
+	user, err := s.repo.FindByEmail(ctx, emailAddress)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return deviceToken, nil
+		}
+		return "", err
+	}
+
+	now := time.Now().UTC()
+	sent, err := s.magicLinks.CountMagicLinksSince(ctx, user.ID, now.Add(-magicLinkWindow))
+	if err != nil {
+		return "", err
+	}
+	if sent >= magicLinkLimit {
+		s.app.Logger.Warn().
+			Int64("user_id", user.ID).
+			Int("sent", sent).
+			Msg("magic_link_rate_limited")
+		return deviceToken, nil
+	}
+
+	rawToken, hashedToken, expiresAt, err := s.prepareToken(magicLinkTTL)
+	if err != nil {
+		return "", err
+	}
+	if err := s.magicLinks.CreateMagicLink(ctx, &entities.MagicLink{
+		UserID:     user.ID,
+		TokenHash:  hashedToken,
+		DeviceHash: hashToken(deviceToken),
+		ExpiresAt:  expiresAt,
+		CreatedAt:  now,
+	}); err != nil {
+		return "", err
+	}
+
+	go s.sendMagicLinkEmail(user, rawToken)
+	return deviceToken, nil
+}
+
+// ConsumeMagicLink exchanges a sign-in link for tokens. Each link works once. A
+// missing or different device token returns MagicLinkConfirmationError until the
+// caller confirms. Opening the link proves ownership of the address, so it also
+// verifies the account.
+func (s *Service) ConsumeMagicLink(ctx context.Context, token, deviceToken string, confirmed bool) (string, string, *entities.User, error) {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return "", "", nil, errMagicLinkInvalid
+	}
+	link, err := s.magicLinks.FindMagicLink(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", "", nil, errMagicLinkInvalid
+		}
+		return "", "", nil, err
+	}
+	now := time.Now().UTC()
+	if link.ConsumedAt != nil || !link.ExpiresAt.After(now) {
+		return "", "", nil, errMagicLinkInvalid
+	}
+	user, err := s.repo.FindByID(ctx, link.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", "", nil, errMagicLinkInvalid
+		}
+		return "", "", nil, err
+	}
+
+	sameDevice := subtle.ConstantTimeCompare([]byte(hashToken(strings.TrimSpace(deviceToken))), []byte(link.DeviceHash)) == 1
+	if !sameDevice && !confirmed {
+		return "", "", nil, &MagicLinkConfirmationError{Email: user.Email}
+	}
+
+	consumed, err := s.magicLinks.ConsumeMagicLink(ctx, link.ID, now)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if !consumed {
+		return "", "", nil, errMagicLinkInvalid
+	}
+	s.app.Logger.Info().
+		Int64("user_id", user.ID).
+		Bool("same_device", sameDevice).
+		Msg("magic_link_consumed")
+
+	if !user.IsVerified {
+		if err := s.repo.MarkUserAsVerified(ctx, user.ID); err != nil {
+			return "", "", nil, err
+		}
+		user.IsVerified = true
+	}
+	if user.MFAEnabled {
+		challenge, err := s.generateMFAChallenge(user)
+		if err != nil {
+			return "", "", nil, err
+		}
+		return "", "", nil, &MFARequiredError{ChallengeToken: challenge}
+	}
+	return s.issueTokens(ctx, user, "")
+}
+
+func (s *Service) sendMagicLinkEmail(user *entities.User, token string) {
+	variables := map[string]interface{}{
+		"name":       user.Name,
+		"link":       s.buildURL("/magic-link", token),
+		"expires_in": int(magicLinkTTL.Minutes()),
+	}
+
+	if err := s.app.Services.Email.SendTemplate(user.Email, email.TemplateMagicLink, variables); err != nil {
+		s.app.Logger.Error().
+			Err(err).
+			Str("email", user.Email).
+			Msg("magic_link_email_failed")
+	}
+}
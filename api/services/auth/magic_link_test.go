@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"finlog-api/api/entities"
+	"finlog-api/api/models/request"
+)
+
+type fakeMagicLinkRepo struct {
+	links []*entities.MagicLink
+}
+
+func (f *fakeMagicLinkRepo) CreateMagicLink(ctx context.Context, link *entities.MagicLink) error {
+	copied := *link
+	copied.ID = int64(len(f.links) + 1)
+	f.links = append(f.links, &copied)
+	return nil
+}
+
+func (f *fakeMagicLinkRepo) CountMagicLinksSince(ctx context.Context, userID int64, since time.Time) (int, error) {
+	count := 0
+	for _, link := range f.links {
+		if link.UserID == userID && !link.CreatedAt.Before(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (f *fakeMagicLinkRepo) FindMagicLink(ctx context.Context, tokenHash string) (*entities.MagicLink, error) {
+	for _, link := range f.links {
+		if link.TokenHash == tokenHash {
+			copied := *link
+			return &copied, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeMagicLinkRepo) ConsumeMagicLink(ctx context.Context, id int64, at time.Time) (bool, error) {
+	for _, link := range f.links {
+		if link.ID == id && link.ConsumedAt == nil {
+			link.ConsumedAt = &at
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// linkMailer captures the link from every email sent.
+type linkMailer struct {
+	links chan string
+}
+
+func (f *linkMailer) SendEmail(to string, variables map[string]interface{}) error {
+	return nil
+}
+
+func (f *linkMailer) SendTemplate(to, templateID string, variables map[string]interface{}) error {
+	f.links <- variables["link"].(string)
+	return nil
+}
+
+func (f *linkMailer) HandleWebhook(ctx context.Context, body request.ResendWebhookPayload, payload []byte) error {
+	return nil
+}
+
+func newMagicLinkTestService(user *entities.User) (*Service, *linkMailer) {
+	svc := newTestService(user)
+	svc.magicLinks = &fakeMagicLinkRepo{}
+	mailer := &linkMailer{links: make(chan string, magicLinkLimit+1)}
+	svc.app.Services.Email = mailer
+	return svc, mailer
+}
+
+func emailedToken(t *testing.T, mailer *linkMailer) string {
+	t.Helper()
+	select {
+	case link := <-mailer.links:
+		parsed, err := url.Parse(link)
+		if err != nil {
+			t.Fatalf("parse link: %v", err)
+		}
+		return parsed.Query().Get("token")
+	case <-time.After(time.Second):
+		t.Fatalf("expected a sign-in email")
+		return ""
+	}
+}
+
+func TestMagicLinkIsBoundToRequestingDevice(t *testing.T) {
+	user := verifiedUser(t)
+	user.IsVerified = false
+	svc, mailer := newMagicLinkTestService(user)
+	ctx := context.Background()
+
+	device, err := svc.RequestMagicLink(ctx, "User@Example.com")
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	token := emailedToken(t, mailer)
+
+	var confirmErr *MagicLinkConfirmationError
+	if _, _, _, err := svc.ConsumeMagicLink(ctx, token, "other-device", false); !errors.As(err, &confirmErr) || confirmErr.Email != user.Email {
+		t.Fatalf("expected confirmation on another device, got %v", err)
+	}
+
+	access, refresh, signedIn, err := svc.ConsumeMagicLink(ctx, token, device, false)
+	if err != nil {
+		t.Fatalf("consume: %v", err)
+	}
+	if access == "" || refresh == "" || signedIn.ID != user.ID || !user.IsVerified {
+		t.Fatalf("expected tokens for a now verified user, got %+v", signedIn)
+	}
+
+	if _, _, _, err := svc.ConsumeMagicLink(ctx, token, device, false); !errors.Is(err, errMagicLinkInvalid) {
+		t.Fatalf("expected link to be single-use, got %v", err)
+	}
+}
+
+func TestMagicLinkRequestsAreRateLimited(t *testing.T) {
+	svc, mailer := newMagicLinkTestService(verifiedUser(t))
+	ctx := context.Background()
+
+	for i := 0; i < magicLinkLimit+1; i++ {
+		device, err := svc.RequestMagicLink(ctx, "user@example.com")
+		if err != nil || device == "" {
+			t.Fatalf("request %d: %q, %v", i, device, err)
+		}
+	}
+	if device, err := svc.RequestMagicLink(ctx, "nobody@example.com"); err != nil || device == "" {
+		t.Fatalf("expected unknown emails to look the same, got %q, %v", device, err)
+	}
+
+	if sent := len(svc.magicLinks.(*fakeMagicLinkRepo).links); sent != magicLinkLimit {
+		t.Fatalf("expected %d links, got %d", magicLinkLimit, sent)
+	}
+	for i := 0; i < magicLinkLimit; i++ {
+		emailedToken(t, mailer)
+	}
+}
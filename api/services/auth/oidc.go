@@ -0,0 +1,517 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"finlog-api/api/constants"
+	"finlog-api/api/entities"
+)
+
+const (
+	oidcProviderGoogle = "google"
+	oidcProviderApple  = "apple"
+
+	oidcStateTTL = 10 * time.Minute
+	// oidcKeysTTL bounds how long provider signing keys are cached; an unknown kid
+	// triggers an earlier refresh, at most once per oidcKeysMinRefresh.
+	oidcKeysTTL        = time.Hour
+	oidcKeysMinRefresh = time.Minute
+)
+
+var (
+	errOIDCProviderUnknown = errors.New("unknown identity provider")
+	errOIDCStateInvalid    = errors.New("invalid or expired login state")
+	errOIDCTokenInvalid    = errors.New("invalid identity token")
+	errOIDCEmailUnverified = errors.New("identity provider did not confirm the email address")
+	errOIDCExchangeFailed  = errors.New("identity provider rejected the login")
+)
+
+func ErrOIDCProviderUnknown() error { return errOIDCProviderUnknown }
+func ErrOIDCStateInvalid() error    { return errOIDCStateInvalid }
+func ErrOIDCTokenInvalid() error    { return errOIDCTokenInvalid }
+func ErrOIDCEmailUnverified() error { return errOIDCEmailUnverified }
+func ErrOIDCExchangeFailed() error  { return errOIDCExchangeFailed }
+
+// oidcProvider is an OpenID Connect provider used with the authorization-code
+// flow and PKCE. Discovery metadata and signing keys are fetched lazily.
+type oidcProvider struct {
+	name         string
+	issuer       string
+	clientID     string
+	clientSecret string
+	redirectURL  string
+	scopes       []string
+	extraParams  url.Values
+	client       *http.Client
+
+	mu            sync.Mutex
+	discovery     *oidcDiscovery
+	keys          map[string]crypto.PublicKey
+	keysFetchedAt time.Time
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcClaims are the ID token claims used to find or create the user.
+type oidcClaims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// newOIDCProviders returns the providers that have a client ID configured. The
+// redirect URL defaults to this API's callback, which hands the code to the app.
+func newOIDCProviders(config map[string]string) map[string]*oidcProvider {
+	baseURL := strings.TrimRight(strings.TrimSpace(config[constants.APIBaseURL]), "/")
+	if baseURL == "" {
+		baseURL = "https://api.finlog.app"
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	providers := map[string]*oidcProvider{}
+	add := func(p *oidcProvider, issuerKey, redirectKey string) {
+		if p.clientID == "" {
+			return
+		}
+		if issuer := strings.TrimSpace(config[issuerKey]); issuer != "" {
+			p.issuer = issuer
+		}
+		p.redirectURL = strings.TrimSpace(config[redirectKey])
+		if p.redirectURL == "" {
+			p.redirectURL = fmt.Sprintf("%s/v1/auth/oidc/%s/callback", baseURL, p.name)
+		}
+		p.client = client
+		providers[p.name] = p
+	}
+
+	add(&oidcProvider{
+		name:         oidcProviderGoogle,
+		issuer:       "https://accounts.google.com",
+		clientID:     strings.TrimSpace(config[constants.OIDCGoogleClientID]),
+		clientSecret: strings.TrimSpace(config[constants.OIDCGoogleClientSecret]),
+		scopes:       []string{"openid", "email", "profile"},
+	}, constants.OIDCGoogleIssuer, constants.OIDCGoogleRedirectURL)
+
+	// Apple only returns the email when it is requested with form_post, and expects
+	// OIDC_APPLE_CLIENT_SECRET to be the signed client-secret JWT it documents.
+	add(&oidcProvider{
+		name:         oidcProviderApple,
+		issuer:       "https://appleid.apple.com",
+		clientID:     strings.TrimSpace(config[constants.OIDCAppleClientID]),
+		clientSecret: strings.TrimSpace(config[constants.OIDCAppleClientSecret]),
+		scopes:       []string{"openid", "email", "name"},
+		extraParams:  url.Values{"response_mode": {"form_post"}},
+	}, constants.OIDCAppleIssuer, constants.OIDCAppleRedirectURL)
+
+	return providers
+}
+
+// BeginOIDCLogin returns the provider URL the app should open. The PKCE verifier
+// and nonce stay on the server, keyed by the hashed state parameter.
+func (s *Service) BeginOIDCLogin(ctx context.Context, provider string) (string, error) {
+	p, ok := s.oidc[provider]
+	if !ok {
+		return "", errOIDCProviderUnknown
+	}
+	discovery, err := p.metadata(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	rawState, hashedState, expiresAt, err := s.prepareToken(oidcStateTTL)
+	if err != nil {
+		return "", err
+	}
+	nonce, err := generateRandomToken()
+	if err != nil {
+		return "", err
+	}
+	verifier, err := generateRandomToken()
+	if err != nil {
+		return "", err
+	}
+	if err := s.oidcRepo.CreateOIDCState(ctx, &entities.OIDCState{
+		ID:           hashedState,
+		Provider:     p.name,
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		ExpiresAt:    expiresAt,
+	}); err != nil {
+		return "", err
+	}
+
+	challenge := sha256.Sum256([]byte(verifier))
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.clientID},
+		"redirect_uri":          {p.redirectURL},
+		"scope":                 {strings.Join(p.scopes, " ")},
+		"state":                 {rawState},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	for key, values := range p.extraParams {
+		params[key] = values
+	}
+	return discovery.AuthorizationEndpoint + "?" + params.Encode(), nil
+}
+
+// FinishOIDCLogin exchanges the authorization code, validates the ID token and
+// signs in the linked user, creating or linking an account by verified email.
+// Accounts with MFA still get an MFARequiredError like Login.
+func (s *Service) FinishOIDCLogin(ctx context.Context, provider, state, code string) (string, string, *entities.User, error) {
+	p, ok := s.oidc[provider]
+	if !ok {
+		return "", "", nil, errOIDCProviderUnknown
+	}
+	state = strings.TrimSpace(state)
+	code = strings.TrimSpace(code)
+	if state == "" || code == "" {
+		return "", "", nil, errOIDCStateInvalid
+	}
+	pending, err := s.oidcRepo.ConsumeOIDCState(ctx, hashToken(state), p.name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", "", nil, errOIDCStateInvalid
+		}
+		return "", "", nil, err
+	}
+	if pending.ExpiresAt.Before(time.Now()) {
+		return "", "", nil, errOIDCStateInvalid
+	}
+
+	idToken, err := p.exchange(ctx, code, pending.CodeVerifier)
+	if err != nil {
+		return "", "", nil, err
+	}
+	claims, err := p.verify(ctx, idToken, pending.Nonce)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	user, err := s.federatedUser(ctx, p.name, claims)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if user.MFAEnabled {
+		challenge, err := s.generateMFAChallenge(user)
+		if err != nil {
+			return "", "", nil, err
+		}
+		return "", "", nil, &MFARequiredError{ChallengeToken: challenge}
+	}
+	return s.issueTokens(ctx, user, "")
+}
+
+// federatedUser returns the user linked to the provider subject. Unknown subjects
+// are linked to the account with the same verified email, or get a new account.
+func (s *Service) federatedUser(ctx context.Context, provider string, claims *oidcClaims) (*entities.User, error) {
+	now := time.Now().UTC()
+	email := normalizeEmail(claims.Email)
+
+	identity, err := s.oidcRepo.FindIdentity(ctx, provider, claims.Subject)
+	if err == nil {
+		user, err := s.repo.FindByID(ctx, identity.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.oidcRepo.TouchIdentity(ctx, identity.ID, email, now); err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	if email == "" || !claims.EmailVerified {
+		return nil, errOIDCEmailUnverified
+	}
+	user, err := s.repo.FindByEmail(ctx, email)
+	switch {
+	case err == nil:
+		if !user.IsVerified {
+			// Whoever registered this unverified account never proved they own the
+			// address, so their password must not keep working once it is linked.
+			if err := s.replaceWithUnusablePassword(ctx, user); err != nil {
+				return nil, err
+			}
+			if err := s.repo.MarkUserAsVerified(ctx, user.ID); err != nil {
+				return nil, err
+			}
+			user.IsVerified = true
+		}
+	case errors.Is(err, sql.ErrNoRows):
+		user, err = s.createFederatedUser(ctx, email, claims.Name)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	if _, err := s.oidcRepo.CreateIdentity(ctx, &entities.UserIdentity{
+		UserID:      user.ID,
+		Provider:    provider,
+		Subject:     claims.Subject,
+		Email:       email,
+		CreatedAt:   now,
+		LastLoginAt: now,
+	}); err != nil {
+		return nil, err
+	}
+	s.app.Logger.Info().
+		Int64("user_id", user.ID).
+		Str("provider", provider).
+		Msg("oidc_identity_linked")
+	return user, nil
+}
+
+// createFederatedUser registers a verified account without a usable password; the
+// user can set one later through the password reset flow.
+func (s *Service) createFederatedUser(ctx context.Context, email, name string) (*entities.User, error) {
+	hashedPassword, err := s.unusablePassword()
+	if err != nil {
+		return nil, err
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = defaultName(email)
+	}
+	user := &entities.User{
+		Email:      email,
+		Name:       name,
+		Role:       constants.RoleUser,
+		Password:   hashedPassword,
+		IsVerified: true,
+	}
+	id, err := s.repo.CreateUser(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	user.ID = id
+	s.seedDefaultCategories(ctx, user.ID)
+	return user, nil
+}
+
+func (s *Service) replaceWithUnusablePassword(ctx context.Context, user *entities.User) error {
+	hashedPassword, err := s.unusablePassword()
+	if err != nil {
+		return err
+	}
+	if err := s.repo.UpdatePassword(ctx, user.ID, hashedPassword); err != nil {
+		return err
+	}
+	user.Password = hashedPassword
+	return nil
+}
+
+// unusablePassword hashes a random secret nobody knows.
+func (s *Service) unusablePassword() (string, error) {
+	secret, err := generateRandomToken()
+	if err != nil {
+		return "", err
+	}
+	return s.passwords.hash(secret)
+}
+
+func (p *oidcProvider) metadata(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	discovery := new(oidcDiscovery)
+	if err := p.getJSON(ctx, strings.TrimRight(p.issuer, "/")+"/.well-known/openid-configuration", discovery); err != nil {
+		return nil, err
+	}
+	if discovery.Issuer != p.issuer || discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, fmt.Errorf("oidc %s: incomplete discovery document", p.name)
+	}
+	p.discovery = discovery
+	return discovery, nil
+}
+
+// exchange redeems the authorization code and returns the raw ID token.
+func (p *oidcProvider) exchange(ctx context.Context, code, verifier string) (string, error) {
+	discovery, err := p.metadata(ctx)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.redirectURL},
+		"client_id":     {p.clientID},
+		"code_verifier": {verifier},
+	}
+	if p.clientSecret != "" {
+		form.Set("client_secret", p.clientSecret)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", errOIDCExchangeFailed
+	}
+	var body struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.IDToken == "" {
+		return "", errOIDCExchangeFailed
+	}
+	return body.IDToken, nil
+}
+
+// verify checks the ID token signature against the provider JWKS, along with its
+// issuer, audience, expiry and nonce.
+func (p *oidcProvider) verify(ctx context.Context, raw, nonce string) (*oidcClaims, error) {
+	token, err := jwt.Parse(raw, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.signingKey(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "ES256"}),
+		jwt.WithIssuer(p.issuer),
+		jwt.WithAudience(p.clientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil || !token.Valid {
+		return nil, errOIDCTokenInvalid
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errOIDCTokenInvalid
+	}
+	if got, _ := claims["nonce"].(string); got == "" || got != nonce {
+		return nil, errOIDCTokenInvalid
+	}
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, errOIDCTokenInvalid
+	}
+
+	result := &oidcClaims{Subject: subject}
+	result.Email, _ = claims["email"].(string)
+	result.Name, _ = claims["name"].(string)
+	// Apple sends email_verified as the string "true".
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		result.EmailVerified = verified
+	case string:
+		result.EmailVerified = verified == "true"
+	}
+	return result, nil
+}
+
+func (p *oidcProvider) signingKey(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stale := time.Since(p.keysFetchedAt) > oidcKeysTTL
+	if key, ok := p.keys[kid]; ok && !stale {
+		return key, nil
+	}
+	if !stale && time.Since(p.keysFetchedAt) < oidcKeysMinRefresh {
+		return nil, errOIDCTokenInvalid
+	}
+
+	if p.discovery == nil {
+		return nil, errOIDCTokenInvalid
+	}
+	var set struct {
+		Keys []struct {
+			KeyID string `json:"kid"`
+			Type  string `json:"kty"`
+			Curve string `json:"crv"`
+			N     string `json:"n"`
+			E     string `json:"e"`
+			X     string `json:"x"`
+			Y     string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := p.getJSON(ctx, p.discovery.JWKSURI, &set); err != nil {
+		return nil, err
+	}
+	keys := map[string]crypto.PublicKey{}
+	for _, k := range set.Keys {
+		switch k.Type {
+		case "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(k.N)
+			e, errE := base64.RawURLEncoding.DecodeString(k.E)
+			if errN != nil || errE != nil {
+				continue
+			}
+			keys[k.KeyID] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case "EC":
+			if k.Curve != "P-256" {
+				continue
+			}
+			x, errX := base64.RawURLEncoding.DecodeString(k.X)
+			y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+			if errX != nil || errY != nil {
+				continue
+			}
+			keys[k.KeyID] = &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		}
+	}
+	p.keys = keys
+	p.keysFetchedAt = time.Now()
+
+	key, ok := keys[kid]
+	if !ok {
+		return nil, errOIDCTokenInvalid
+	}
+	return key, nil
+}
+
+func (p *oidcProvider) getJSON(ctx context.Context, endpoint string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("oidc %s: %s returned %d", p.name, endpoint, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
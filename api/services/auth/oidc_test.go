@@ -0,0 +1,275 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+
+	"finlog-api/api/contracts"
+	"finlog-api/api/entities"
+)
+
+type fakeOIDCRepo struct {
+	states     map[string]*entities.OIDCState
+	identities []*entities.UserIdentity
+}
+
+func (f *fakeOIDCRepo) CreateOIDCState(ctx context.Context, state *entities.OIDCState) error {
+	copied := *state
+	f.states[state.ID] = &copied
+	return nil
+}
+
+func (f *fakeOIDCRepo) ConsumeOIDCState(ctx context.Context, id, provider string) (*entities.OIDCState, error) {
+	state, ok := f.states[id]
+	if !ok || state.Provider != provider {
+		return nil, sql.ErrNoRows
+	}
+	delete(f.states, id)
+	return state, nil
+}
+
+func (f *fakeOIDCRepo) FindIdentity(ctx context.Context, provider, subject string) (*entities.UserIdentity, error) {
+	for _, identity := range f.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			copied := *identity
+			return &copied, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeOIDCRepo) CreateIdentity(ctx context.Context, identity *entities.UserIdentity) (int64, error) {
+	copied := *identity
+	copied.ID = int64(len(f.identities) + 1)
+	f.identities = append(f.identities, &copied)
+	return copied.ID, nil
+}
+
+func (f *fakeOIDCRepo) TouchIdentity(ctx context.Context, id int64, email string, at time.Time) error {
+	return nil
+}
+
+type fakeCategoryRepo struct {
+	created map[int64]int
+}
+
+func (f *fakeCategoryRepo) List(ctx context.Context, userID int64, filter contracts.CategoryFilter) ([]entities.Category, error) {
+	return nil, nil
+}
+
+func (f *fakeCategoryRepo) Create(ctx context.Context, category *entities.Category) (int64, error) {
+	f.created[category.UserID]++
+	return int64(f.created[category.UserID]), nil
+}
+
+func (f *fakeCategoryRepo) Update(ctx context.Context, category *entities.Category) error {
+	return nil
+}
+
+func (f *fakeCategoryRepo) Revive(ctx context.Context, category *entities.Category) error {
+	return nil
+}
+
+func (f *fakeCategoryRepo) Delete(ctx context.Context, id, userID int64) error {
+	return nil
+}
+
+func (f *fakeCategoryRepo) FindByID(ctx context.Context, id, userID int64) (*entities.Category, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeCategoryRepo) FindByName(ctx context.Context, name string, isExpense bool, userID int64) (*entities.Category, error) {
+	return nil, sql.ErrNoRows
+}
+
+// testOIDCProvider is a stand-in OpenID Connect provider. Authorize plays the part
+// of the user consenting in the browser and returns the code for the redirect.
+type testOIDCProvider struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	grants map[string]url.Values
+	claims jwt.MapClaims
+}
+
+func newTestOIDCProvider(t *testing.T) *testOIDCProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	p := &testOIDCProvider{t: t, key: key, grants: map[string]url.Values{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 p.server.URL,
+			"authorization_endpoint": p.server.URL + "/authorize",
+			"token_endpoint":         p.server.URL + "/token",
+			"jwks_uri":               p.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "test-key",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", p.token)
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+func (p *testOIDCProvider) authorize(authURL string) (string, string) {
+	p.t.Helper()
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		p.t.Fatalf("authorization url: %v", err)
+	}
+	params := parsed.Query()
+	if params.Get("code_challenge_method") != "S256" || params.Get("code_challenge") == "" {
+		p.t.Fatalf("expected a PKCE challenge, got %v", params)
+	}
+	code, _ := generateRandomToken()
+	p.grants[code] = params
+	return params.Get("state"), code
+}
+
+func (p *testOIDCProvider) token(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+	grant, ok := p.grants[r.PostForm.Get("code")]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	delete(p.grants, r.PostForm.Get("code"))
+	verifier := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(verifier[:]) != grant.Get("code_challenge") {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"iss":   p.server.URL,
+		"aud":   grant.Get("client_id"),
+		"nonce": grant.Get("nonce"),
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}
+	for k, v := range p.claims {
+		claims[k] = v
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "test-key"
+	signed, err := token.SignedString(p.key)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]string{"id_token": signed, "token_type": "Bearer"})
+}
+
+func newOIDCTestService(t *testing.T, user *entities.User, provider *testOIDCProvider) *Service {
+	t.Helper()
+	svc := newTestService(user)
+	svc.oidcRepo = &fakeOIDCRepo{states: map[string]*entities.OIDCState{}}
+	svc.catRepo = &fakeCategoryRepo{created: map[int64]int{}}
+	svc.oidc = newOIDCProviders(map[string]string{
+		"OIDC_GOOGLE_ISSUER":    provider.server.URL,
+		"OIDC_GOOGLE_CLIENT_ID": "finlog-test",
+	})
+	return svc
+}
+
+func oidcLogin(t *testing.T, svc *Service, provider *testOIDCProvider) (string, *entities.User, error) {
+	t.Helper()
+	authURL, err := svc.BeginOIDCLogin(context.Background(), oidcProviderGoogle)
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	state, code := provider.authorize(authURL)
+	access, _, user, err := svc.FinishOIDCLogin(context.Background(), oidcProviderGoogle, state, code)
+	return access, user, err
+}
+
+func TestOIDCLoginCreatesVerifiedUser(t *testing.T) {
+	provider := newTestOIDCProvider(t)
+	provider.claims = jwt.MapClaims{"sub": "google-123", "email": "New@Example.com", "email_verified": true, "name": "New User"}
+	svc := newOIDCTestService(t, nil, provider)
+
+	access, user, err := oidcLogin(t, svc, provider)
+	if err != nil {
+		t.Fatalf("oidc login: %v", err)
+	}
+	if access == "" || user.Email != "new@example.com" || !user.IsVerified || user.Name != "New User" {
+		t.Fatalf("expected a verified account with tokens, got %+v", user)
+	}
+	if svc.catRepo.(*fakeCategoryRepo).created[user.ID] == 0 {
+		t.Fatalf("expected default categories to be seeded")
+	}
+
+	_, again, err := oidcLogin(t, svc, provider)
+	if err != nil || again.ID != user.ID {
+		t.Fatalf("expected the linked identity to sign in the same user, got %+v, %v", again, err)
+	}
+}
+
+func TestOIDCLoginLinksUnverifiedAccountAndDropsItsPassword(t *testing.T) {
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("squatter1"), bcrypt.DefaultCost)
+	existing := &entities.User{ID: 1, Email: "user@example.com", Name: "user", Role: "user", Password: string(hashed)}
+	provider := newTestOIDCProvider(t)
+	provider.claims = jwt.MapClaims{"sub": "google-456", "email": "user@example.com", "email_verified": "true"}
+	svc := newOIDCTestService(t, existing, provider)
+
+	_, user, err := oidcLogin(t, svc, provider)
+	if err != nil {
+		t.Fatalf("oidc login: %v", err)
+	}
+	if user.ID != existing.ID || !existing.IsVerified {
+		t.Fatalf("expected the existing account to be linked and verified")
+	}
+	if svc.checkPassword(existing, "squatter1") {
+		t.Fatalf("expected the unverified password to stop working")
+	}
+}
+
+func TestOIDCLoginRejectsUnverifiedEmailAndReplayedState(t *testing.T) {
+	provider := newTestOIDCProvider(t)
+	provider.claims = jwt.MapClaims{"sub": "google-789", "email": "user@example.com", "email_verified": false}
+	svc := newOIDCTestService(t, verifiedUser(t), provider)
+
+	if _, _, err := oidcLogin(t, svc, provider); !errors.Is(err, errOIDCEmailUnverified) {
+		t.Fatalf("expected unverified email to be rejected, got %v", err)
+	}
+
+	provider.claims["email_verified"] = true
+	authURL, _ := svc.BeginOIDCLogin(context.Background(), oidcProviderGoogle)
+	state, code := provider.authorize(authURL)
+	if _, _, _, err := svc.FinishOIDCLogin(context.Background(), oidcProviderGoogle, state, code); err != nil {
+		t.Fatalf("oidc login: %v", err)
+	}
+	if _, _, _, err := svc.FinishOIDCLogin(context.Background(), oidcProviderGoogle, state, code); !errors.Is(err, errOIDCStateInvalid) {
+		t.Fatalf("expected replayed state to be rejected, got %v", err)
+	}
+}
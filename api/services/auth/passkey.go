@@ -0,0 +1,373 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+
+	"finlog-api/api/constants"
+	"finlog-api/api/entities"
+)
+
+const (
+	ceremonyRegistration = "registration"
+	ceremonyLogin        = "login"
+	passkeyCeremonyTTL   = 5 * time.Minute
+	passkeyNameMaxLength = 100
+	defaultPasskeyName   = "Passkey"
+	defaultRPDisplayName = "FinLog"
+)
+
+var (
+	errPasskeysDisabled      = errors.New("passkeys are not configured")
+	errPasskeySessionInvalid = errors.New("invalid or expired passkey session")
+	errPasskeyInvalid        = errors.New("invalid passkey response")
+	errPasskeyNotFound       = errors.New("passkey not found")
+	errPasskeyCloneDetected  = errors.New("passkey signature counter mismatch")
+)
+
+func ErrPasskeysDisabled() error      { return errPasskeysDisabled }
+func ErrPasskeySessionInvalid() error { return errPasskeySessionInvalid }
+func ErrPasskeyInvalid() error        { return errPasskeyInvalid }
+func ErrPasskeyNotFound() error       { return errPasskeyNotFound }
+func ErrPasskeyCloneDetected() error  { return errPasskeyCloneDetected }
+
+// newWebAuthn builds the relying party from config. Passkeys stay disabled when
+// WEBAUTHN_RP_ID is unset.
+func newWebAuthn(config map[string]string) (*webauthn.WebAuthn, error) {
+	rpID := strings.TrimSpace(config[constants.WebAuthnRPID])
+	if rpID == "" {
+		return nil, errPasskeysDisabled
+	}
+	displayName := strings.TrimSpace(config[constants.WebAuthnRPName])
+	if displayName == "" {
+		displayName = defaultRPDisplayName
+	}
+	var origins []string
+	for _, origin := range strings.Split(config[constants.WebAuthnRPOrigins], ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	if len(origins) == 0 {
+		origins = []string{"https://" + rpID}
+	}
+
+	return webauthn.New(&webauthn.Config{
+		RPID:          rpID,
+		RPDisplayName: displayName,
+		RPOrigins:     origins,
+		AuthenticatorSelection: protocol.AuthenticatorSelection{
+			ResidentKey:      protocol.ResidentKeyRequirementRequired,
+			UserVerification: protocol.VerificationRequired,
+		},
+	})
+}
+
+// passkeyUser adapts a user and their stored credentials to webauthn.User.
+type passkeyUser struct {
+	user        *entities.User
+	credentials []webauthn.Credential
+}
+
+func (u *passkeyUser) WebAuthnID() []byte                         { return userHandle(u.user.ID) }
+func (u *passkeyUser) WebAuthnName() string                       { return u.user.Email }
+func (u *passkeyUser) WebAuthnDisplayName() string                { return u.user.Name }
+func (u *passkeyUser) WebAuthnCredentials() []webauthn.Credential { return u.credentials }
+
+// userHandle is the opaque WebAuthn user ID. It carries no personal data.
+func userHandle(userID int64) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, uint64(userID))
+	return buf
+}
+
+func userIDFromHandle(handle []byte) (int64, bool) {
+	if len(handle) != 8 {
+		return 0, false
+	}
+	return int64(binary.BigEndian.Uint64(handle)), true
+}
+
+// BeginPasskeyRegistration starts registering a new passkey for a signed-in user.
+// It returns the ceremony handle and the options for navigator.credentials.create.
+func (s *Service) BeginPasskeyRegistration(ctx context.Context, userID int64) (string, interface{}, error) {
+	if s.webauthn == nil {
+		return "", nil, errPasskeysDisabled
+	}
+	user, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil, errUserNotFound
+		}
+		return "", nil, err
+	}
+	account, err := s.loadPasskeyUser(ctx, user)
+	if err != nil {
+		return "", nil, err
+	}
+
+	exclusions := webauthn.Credentials(account.credentials).CredentialDescriptors()
+	options, session, err := s.webauthn.BeginRegistration(account, webauthn.WithExclusions(exclusions))
+	if err != nil {
+		return "", nil, err
+	}
+	sessionID, err := s.saveWebAuthnSession(ctx, &userID, ceremonyRegistration, session)
+	if err != nil {
+		return "", nil, err
+	}
+	return sessionID, options, nil
+}
+
+// FinishPasskeyRegistration verifies the attestation and stores the new credential.
+func (s *Service) FinishPasskeyRegistration(ctx context.Context, userID int64, sessionID, name string, response []byte) (*entities.Passkey, error) {
+	if s.webauthn == nil {
+		return nil, errPasskeysDisabled
+	}
+	session, err := s.consumeWebAuthnSession(ctx, sessionID, ceremonyRegistration)
+	if err != nil {
+		return nil, err
+	}
+	if session.UserID == nil || *session.UserID != userID {
+		return nil, errPasskeySessionInvalid
+	}
+	var data webauthn.SessionData
+	if err := json.Unmarshal([]byte(session.SessionData), &data); err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errUserNotFound
+		}
+		return nil, err
+	}
+	account, err := s.loadPasskeyUser(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(response)
+	if err != nil {
+		return nil, errPasskeyInvalid
+	}
+	credential, err := s.webauthn.CreateCredential(account, data, parsed)
+	if err != nil {
+		s.app.Logger.Warn().
+			Err(err).
+			Int64("user_id", userID).
+			Msg("passkey_registration_rejected")
+		return nil, errPasskeyInvalid
+	}
+	encoded, err := json.Marshal(credential)
+	if err != nil {
+		return nil, err
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = defaultPasskeyName
+	}
+	if len([]rune(name)) > passkeyNameMaxLength {
+		name = string([]rune(name)[:passkeyNameMaxLength])
+	}
+
+	passkey := &entities.Passkey{
+		UserID:       userID,
+		CredentialID: credential.ID,
+		Name:         name,
+		Credential:   string(encoded),
+		SignCount:    credential.Authenticator.SignCount,
+		CreatedAt:    time.Now(),
+	}
+	id, err := s.passkeys.CreatePasskey(ctx, passkey)
+	if err != nil {
+		return nil, err
+	}
+	passkey.ID = id
+	return passkey, nil
+}
+
+// BeginPasskeyLogin starts a passwordless login. The credential picked by the
+// authenticator identifies the user, so no email is needed up front.
+func (s *Service) BeginPasskeyLogin(ctx context.Context) (string, interface{}, error) {
+	if s.webauthn == nil {
+		return "", nil, errPasskeysDisabled
+	}
+	options, session, err := s.webauthn.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+	if err != nil {
+		return "", nil, err
+	}
+	sessionID, err := s.saveWebAuthnSession(ctx, nil, ceremonyLogin, session)
+	if err != nil {
+		return "", nil, err
+	}
+	return sessionID, options, nil
+}
+
+// FinishPasskeyLogin verifies an assertion and issues tokens like Login does.
+// A passkey with user verification counts as both factors, so MFA is not asked for.
+func (s *Service) FinishPasskeyLogin(ctx context.Context, sessionID string, response []byte) (string, string, *entities.User, error) {
+	if s.webauthn == nil {
+		return "", "", nil, errPasskeysDisabled
+	}
+	session, err := s.consumeWebAuthnSession(ctx, sessionID, ceremonyLogin)
+	if err != nil {
+		return "", "", nil, err
+	}
+	var data webauthn.SessionData
+	if err := json.Unmarshal([]byte(session.SessionData), &data); err != nil {
+		return "", "", nil, err
+	}
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(response)
+	if err != nil {
+		return "", "", nil, errInvalidCredentials
+	}
+
+	var (
+		user   *entities.User
+		stored *entities.Passkey
+	)
+	handler := func(rawID, handle []byte) (webauthn.User, error) {
+		id, ok := userIDFromHandle(handle)
+		if !ok {
+			return nil, errInvalidCredentials
+		}
+		passkey, err := s.passkeys.FindPasskeyByCredentialID(ctx, rawID)
+		if err != nil {
+			return nil, err
+		}
+		if passkey.UserID != id {
+			return nil, errInvalidCredentials
+		}
+		found, err := s.repo.FindByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		credential, err := decodePasskey(passkey)
+		if err != nil {
+			return nil, err
+		}
+		user, stored = found, passkey
+		return &passkeyUser{user: found, credentials: []webauthn.Credential{credential}}, nil
+	}
+
+	_, credential, err := s.webauthn.ValidatePasskeyLogin(handler, data, parsed)
+	if err != nil {
+		s.app.Logger.Warn().
+			Err(err).
+			Msg("passkey_login_rejected")
+		return "", "", nil, errInvalidCredentials
+	}
+	if credential.Authenticator.CloneWarning {
+		s.app.Logger.Warn().
+			Int64("user_id", stored.UserID).
+			Int64("passkey_id", stored.ID).
+			Uint32("stored_sign_count", stored.SignCount).
+			Msg("passkey_clone_detected")
+		return "", "", nil, errPasskeyCloneDetected
+	}
+	if !user.IsVerified {
+		return "", "", nil, errEmailNotVerified
+	}
+
+	encoded, err := json.Marshal(credential)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if err := s.passkeys.UpdatePasskeyUsage(ctx, stored.ID, credential.Authenticator.SignCount, string(encoded)); err != nil {
+		return "", "", nil, err
+	}
+	return s.issueTokens(ctx, user, "")
+}
+
+func (s *Service) ListPasskeys(ctx context.Context, userID int64) ([]entities.Passkey, error) {
+	return s.passkeys.ListPasskeys(ctx, userID)
+}
+
+func (s *Service) DeletePasskey(ctx context.Context, userID, id int64) error {
+	deleted, err := s.passkeys.DeletePasskey(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return errPasskeyNotFound
+	}
+	return nil
+}
+
+func (s *Service) loadPasskeyUser(ctx context.Context, user *entities.User) (*passkeyUser, error) {
+	stored, err := s.passkeys.ListPasskeys(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	credentials := make([]webauthn.Credential, 0, len(stored))
+	for i := range stored {
+		credential, err := decodePasskey(&stored[i])
+		if err != nil {
+			return nil, err
+		}
+		credentials = append(credentials, credential)
+	}
+	return &passkeyUser{user: user, credentials: credentials}, nil
+}
+
+// decodePasskey restores the library credential, trusting the sign_count column.
+func decodePasskey(passkey *entities.Passkey) (webauthn.Credential, error) {
+	var credential webauthn.Credential
+	if err := json.Unmarshal([]byte(passkey.Credential), &credential); err != nil {
+		return webauthn.Credential{}, err
+	}
+	credential.Authenticator.SignCount = passkey.SignCount
+	credential.Authenticator.CloneWarning = false
+	return credential, nil
+}
+
+// saveWebAuthnSession stores ceremony state under the hash of a random handle and
+// returns the handle for the client to echo back.
+func (s *Service) saveWebAuthnSession(ctx context.Context, userID *int64, ceremony string, data *webauthn.SessionData) (string, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	raw, hashed, expiresAt, err := s.prepareToken(passkeyCeremonyTTL)
+	if err != nil {
+		return "", err
+	}
+	if err := s.passkeys.CreateWebAuthnSession(ctx, &entities.WebAuthnSession{
+		ID:          hashed,
+		UserID:      userID,
+		Ceremony:    ceremony,
+		SessionData: string(encoded),
+		ExpiresAt:   expiresAt,
+	}); err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+func (s *Service) consumeWebAuthnSession(ctx context.Context, sessionID, ceremony string) (*entities.WebAuthnSession, error) {
+	sessionID = strings.TrimSpace(sessionID)
+	if sessionID == "" {
+		return nil, errPasskeySessionInvalid
+	}
+	session, err := s.passkeys.ConsumeWebAuthnSession(ctx, hashToken(sessionID), ceremony)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errPasskeySessionInvalid
+		}
+		return nil, err
+	}
+	if session.ExpiresAt.Before(time.Now()) {
+		return nil, errPasskeySessionInvalid
+	}
+	return session, nil
+}
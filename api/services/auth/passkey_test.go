@@ -0,0 +1,313 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+
+	"finlog-api/api/entities"
+)
+
+const (
+	testRPID   = "finlog.test"
+	testOrigin = "https://finlog.test"
+)
+
+type fakePasskeyRepo struct {
+	passkeys map[int64]*entities.Passkey
+	sessions map[string]*entities.WebAuthnSession
+	nextID   int64
+}
+
+func newFakePasskeyRepo() *fakePasskeyRepo {
+	return &fakePasskeyRepo{
+		passkeys: map[int64]*entities.Passkey{},
+		sessions: map[string]*entities.WebAuthnSession{},
+	}
+}
+
+func (f *fakePasskeyRepo) CreatePasskey(ctx context.Context, passkey *entities.Passkey) (int64, error) {
+	f.nextID++
+	copied := *passkey
+	copied.ID = f.nextID
+	f.passkeys[copied.ID] = &copied
+	return copied.ID, nil
+}
+
+func (f *fakePasskeyRepo) ListPasskeys(ctx context.Context, userID int64) ([]entities.Passkey, error) {
+	var out []entities.Passkey
+	for _, p := range f.passkeys {
+		if p.UserID == userID {
+			out = append(out, *p)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakePasskeyRepo) FindPasskeyByCredentialID(ctx context.Context, credentialID []byte) (*entities.Passkey, error) {
+	for _, p := range f.passkeys {
+		if bytes.Equal(p.CredentialID, credentialID) {
+			copied := *p
+			return &copied, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakePasskeyRepo) UpdatePasskeyUsage(ctx context.Context, id int64, signCount uint32, credential string) error {
+	if p, ok := f.passkeys[id]; ok {
+		now := time.Now()
+		p.SignCount = signCount
+		p.Credential = credential
+		p.LastUsedAt = &now
+	}
+	return nil
+}
+
+func (f *fakePasskeyRepo) DeletePasskey(ctx context.Context, userID, id int64) (bool, error) {
+	p, ok := f.passkeys[id]
+	if !ok || p.UserID != userID {
+		return false, nil
+	}
+	delete(f.passkeys, id)
+	return true, nil
+}
+
+func (f *fakePasskeyRepo) CreateWebAuthnSession(ctx context.Context, session *entities.WebAuthnSession) error {
+	copied := *session
+	f.sessions[session.ID] = &copied
+	return nil
+}
+
+func (f *fakePasskeyRepo) ConsumeWebAuthnSession(ctx context.Context, id, ceremony string) (*entities.WebAuthnSession, error) {
+	s, ok := f.sessions[id]
+	if !ok || s.Ceremony != ceremony {
+		return nil, sql.ErrNoRows
+	}
+	delete(f.sessions, id)
+	return s, nil
+}
+
+// softAuthenticator is a minimal platform authenticator producing "none"
+// attestations and ES256 assertions.
+type softAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	userHandle   []byte
+	signCount    uint32
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	return &softAuthenticator{key: key, credentialID: id}
+}
+
+func (a *softAuthenticator) authData(flags protocol.AuthenticatorFlags, attested []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(testRPID))
+	counter := make([]byte, 4)
+	binary.BigEndian.PutUint32(counter, a.signCount)
+	data := append(rpIDHash[:], byte(flags))
+	data = append(data, counter...)
+	return append(data, attested...)
+}
+
+func clientData(t *testing.T, ceremony, challenge string) []byte {
+	t.Helper()
+	raw, err := json.Marshal(map[string]string{
+		"type":      ceremony,
+		"challenge": challenge,
+		"origin":    testOrigin,
+	})
+	if err != nil {
+		t.Fatalf("client data: %v", err)
+	}
+	return raw
+}
+
+func (a *softAuthenticator) create(t *testing.T, options *protocol.CredentialCreation) []byte {
+	t.Helper()
+	a.userHandle = options.Response.User.ID.(protocol.URLEncodedBase64)
+
+	publicKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  1,
+		XCoord: a.key.PublicKey.X.FillBytes(make([]byte, 32)),
+		YCoord: a.key.PublicKey.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		t.Fatalf("encode public key: %v", err)
+	}
+	attested := make([]byte, 16) // zero AAGUID
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.credentialID)))
+	attested = append(attested, a.credentialID...)
+	attested = append(attested, publicKey...)
+
+	flags := protocol.FlagUserPresent | protocol.FlagUserVerified | protocol.FlagAttestedCredentialData
+	attestation, err := webauthncbor.Marshal(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": a.authData(flags, attested),
+	})
+	if err != nil {
+		t.Fatalf("encode attestation: %v", err)
+	}
+
+	return a.encode(t, map[string]string{
+		"clientDataJSON":    b64(clientData(t, "webauthn.create", options.Response.Challenge.String())),
+		"attestationObject": b64(attestation),
+	})
+}
+
+func (a *softAuthenticator) get(t *testing.T, options *protocol.CredentialAssertion) []byte {
+	t.Helper()
+	a.signCount++
+	data := a.authData(protocol.FlagUserPresent|protocol.FlagUserVerified, nil)
+	client := clientData(t, "webauthn.get", options.Response.Challenge.String())
+	clientHash := sha256.Sum256(client)
+	digest := sha256.Sum256(append(append([]byte{}, data...), clientHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	return a.encode(t, map[string]string{
+		"clientDataJSON":    b64(client),
+		"authenticatorData": b64(data),
+		"signature":         b64(signature),
+		"userHandle":        b64(a.userHandle),
+	})
+}
+
+func (a *softAuthenticator) encode(t *testing.T, response map[string]string) []byte {
+	t.Helper()
+	raw, err := json.Marshal(map[string]interface{}{
+		"id":       b64(a.credentialID),
+		"rawId":    b64(a.credentialID),
+		"type":     "public-key",
+		"response": response,
+	})
+	if err != nil {
+		t.Fatalf("encode credential: %v", err)
+	}
+	return raw
+}
+
+func b64(v []byte) string {
+	return base64.RawURLEncoding.EncodeToString(v)
+}
+
+func newPasskeyTestService(t *testing.T, user *entities.User) *Service {
+	t.Helper()
+	svc := newTestService(user)
+	svc.app.Config["WEBAUTHN_RP_ID"] = testRPID
+	svc.app.Config["WEBAUTHN_RP_ORIGINS"] = testOrigin
+	wa, err := newWebAuthn(svc.app.Config)
+	if err != nil {
+		t.Fatalf("webauthn config: %v", err)
+	}
+	svc.webauthn = wa
+	svc.passkeys = newFakePasskeyRepo()
+	return svc
+}
+
+func registerPasskey(t *testing.T, svc *Service, userID int64, authenticator *softAuthenticator) {
+	t.Helper()
+	ctx := context.Background()
+	sessionID, options, err := svc.BeginPasskeyRegistration(ctx, userID)
+	if err != nil {
+		t.Fatalf("begin registration: %v", err)
+	}
+	response := authenticator.create(t, options.(*protocol.CredentialCreation))
+	if _, err := svc.FinishPasskeyRegistration(ctx, userID, sessionID, "Pixel", response); err != nil {
+		t.Fatalf("finish registration: %v", err)
+	}
+}
+
+func loginWithPasskey(t *testing.T, svc *Service, authenticator *softAuthenticator) (string, *entities.User, error) {
+	t.Helper()
+	ctx := context.Background()
+	sessionID, options, err := svc.BeginPasskeyLogin(ctx)
+	if err != nil {
+		t.Fatalf("begin login: %v", err)
+	}
+	response := authenticator.get(t, options.(*protocol.CredentialAssertion))
+	access, _, user, err := svc.FinishPasskeyLogin(ctx, sessionID, response)
+	return access, user, err
+}
+
+func TestPasskeyRegistrationAndLogin(t *testing.T) {
+	user := verifiedUser(t)
+	svc := newPasskeyTestService(t, user)
+	authenticator := newSoftAuthenticator(t)
+	registerPasskey(t, svc, user.ID, authenticator)
+
+	access, loggedIn, err := loginWithPasskey(t, svc, authenticator)
+	if err != nil {
+		t.Fatalf("passkey login: %v", err)
+	}
+	if access == "" || loggedIn.ID != user.ID {
+		t.Fatalf("expected tokens for user %d", user.ID)
+	}
+
+	stored, _ := svc.passkeys.ListPasskeys(context.Background(), user.ID)
+	if len(stored) != 1 || stored[0].SignCount != 1 || stored[0].LastUsedAt == nil {
+		t.Fatalf("expected usage to be recorded, got %+v", stored)
+	}
+}
+
+func TestPasskeyLoginRejectsReplayedSession(t *testing.T) {
+	user := verifiedUser(t)
+	svc := newPasskeyTestService(t, user)
+	authenticator := newSoftAuthenticator(t)
+	registerPasskey(t, svc, user.ID, authenticator)
+
+	ctx := context.Background()
+	sessionID, options, _ := svc.BeginPasskeyLogin(ctx)
+	response := authenticator.get(t, options.(*protocol.CredentialAssertion))
+	if _, _, _, err := svc.FinishPasskeyLogin(ctx, sessionID, response); err != nil {
+		t.Fatalf("first login: %v", err)
+	}
+	if _, _, _, err := svc.FinishPasskeyLogin(ctx, sessionID, response); !errors.Is(err, errPasskeySessionInvalid) {
+		t.Fatalf("expected replay to be rejected, got %v", err)
+	}
+}
+
+func TestPasskeyLoginDetectsClonedAuthenticator(t *testing.T) {
+	user := verifiedUser(t)
+	svc := newPasskeyTestService(t, user)
+	authenticator := newSoftAuthenticator(t)
+	registerPasskey(t, svc, user.ID, authenticator)
+
+	authenticator.signCount = 10
+	if _, _, err := loginWithPasskey(t, svc, authenticator); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+
+	clone := *authenticator
+	clone.signCount = 3
+	if _, _, err := loginWithPasskey(t, svc, &clone); !errors.Is(err, errPasskeyCloneDetected) {
+		t.Fatalf("expected clone detection, got %v", err)
+	}
+}
@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+
+	"finlog-api/api/constants"
+	"finlog-api/api/entities"
+)
+
+const (
+	argon2Prefix  = "$argon2id$"
+	argon2SaltLen = 16
+	argon2KeyLen  = 32
+)
+
+var errMalformedHash = errors.New("malformed password hash")
+
+// passwordHasher hashes new passwords with argon2id. Its parameters are written
+// into every hash in PHC form ($argon2id$v=19$m=...,t=...,p=...$salt$key), so they
+// can be raised later without invalidating existing hashes.
+type passwordHasher struct {
+	memory  uint32 // KiB
+	time    uint32
+	threads uint8
+}
+
+func parsePasswordHasher(config map[string]string) passwordHasher {
+	hasher := passwordHasher{
+		memory:  19 * 1024,
+		time:    2,
+		threads: 1,
+	}
+	if raw := config[constants.Argon2Memory]; raw != "" {
+		if parsed, err := strconv.ParseUint(raw, 10, 32); err == nil && parsed >= 8 {
+			hasher.memory = uint32(parsed)
+		}
+	}
+	if raw := config[constants.Argon2Time]; raw != "" {
+		if parsed, err := strconv.ParseUint(raw, 10, 32); err == nil && parsed > 0 {
+			hasher.time = uint32(parsed)
+		}
+	}
+	if raw := config[constants.Argon2Threads]; raw != "" {
+		if parsed, err := strconv.ParseUint(raw, 10, 8); err == nil && parsed > 0 {
+			hasher.threads = uint8(parsed)
+		}
+	}
+	return hasher
+}
+
+func (h passwordHasher) hash(password string) (string, error) {
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.time, h.memory, h.threads, argon2KeyLen)
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2Prefix, argon2.Version, h.memory, h.time, h.threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// verify reports whether password matches encoded, which may be an argon2id or a
+// legacy bcrypt hash. needsRehash is set for matches that were not hashed with the
+// current parameters.
+func (h passwordHasher) verify(encoded, password string) (ok, needsRehash bool) {
+	if !strings.HasPrefix(encoded, argon2Prefix) {
+		if bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password)) != nil {
+			return false, false
+		}
+		return true, true
+	}
+
+	params, salt, key, err := decodeArgon2Hash(encoded)
+	if err != nil {
+		return false, false
+	}
+	candidate := argon2.IDKey([]byte(password), salt, params.time, params.memory, params.threads, uint32(len(key)))
+	if subtle.ConstantTimeCompare(candidate, key) != 1 {
+		return false, false
+	}
+	return true, params != h || len(key) != argon2KeyLen
+}
+
+func decodeArgon2Hash(encoded string) (passwordHasher, []byte, []byte, error) {
+	// "", "argon2id", "v=19", "m=..,t=..,p=..", salt, key
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return passwordHasher{}, nil, nil, errMalformedHash
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return passwordHasher{}, nil, nil, errMalformedHash
+	}
+	var params passwordHasher
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.time, &params.threads); err != nil {
+		return passwordHasher{}, nil, nil, errMalformedHash
+	}
+	if params.memory == 0 || params.time == 0 || params.threads == 0 {
+		return passwordHasher{}, nil, nil, errMalformedHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return passwordHasher{}, nil, nil, errMalformedHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return passwordHasher{}, nil, nil, errMalformedHash
+	}
+	return params, salt, key, nil
+}
+
+func (s *Service) checkPassword(user *entities.User, password string) bool {
+	ok, _ := s.passwords.verify(user.Password, password)
+	return ok
+}
+
+// rehashPassword upgrades the stored hash after a successful login. Failing to do
+// so is logged and retried at the next login rather than failing this one.
+func (s *Service) rehashPassword(ctx context.Context, user *entities.User, password string) {
+	hashed, err := s.passwords.hash(password)
+	if err == nil {
+		err = s.repo.UpdatePassword(ctx, user.ID, hashed)
+	}
+	if err != nil {
+		s.app.Logger.Error().
+			Err(err).
+			Int64("user_id", user.ID).
+			Msg("password_rehash_failed")
+		return
+	}
+	user.Password = hashed
+	s.app.Logger.Info().
+		Int64("user_id", user.ID).
+		Msg("password_rehashed")
+}
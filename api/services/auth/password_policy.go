@@ -0,0 +1,270 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/binary"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"finlog-api/api/constants"
+)
+
+// Reasons reported in WeakPasswordError, for the app to show.
+const (
+	passwordTooShort      = "too_short"
+	passwordTooLong       = "too_long"
+	passwordTooWeak       = "too_weak"
+	passwordContainsEmail = "contains_email"
+	passwordBreached      = "breached"
+)
+
+const (
+	maxPasswordLength = 128
+	// breachPrefixLen is how many hex digits of each SHA-1 in the corpus are kept.
+	breachPrefixLen = 16
+)
+
+// keyboardRows are walked by keyboard patterns such as "qwerty" or "asdf".
+var keyboardRows = []string{"1234567890", "qwertyuiop", "asdfghjkl", "zxcvbnm"}
+
+// WeakPasswordError is returned when a new password does not meet the policy.
+type WeakPasswordError struct {
+	Reasons []string
+}
+
+func (e *WeakPasswordError) Error() string {
+	return "password does not meet the policy: " + strings.Join(e.Reasons, ", ")
+}
+
+// passwordPolicy applies to every password a user sets. Existing passwords are not
+// re-checked at login.
+type passwordPolicy struct {
+	minLength  int
+	minEntropy float64 // bits
+	// breached holds the first 64 bits of known breached SHA-1 hashes, sorted.
+	breached []uint64
+}
+
+// loadPasswordPolicy reads the policy from config. The error is only about the
+// breached-password corpus; the returned policy is usable without it.
+func loadPasswordPolicy(config map[string]string) (passwordPolicy, error) {
+	policy := parsePasswordPolicy(config)
+	if path := config[constants.PasswordBreachCorpus]; path != "" {
+		breached, err := loadBreachCorpus(path)
+		if err != nil {
+			return policy, err
+		}
+		policy.breached = breached
+	}
+	return policy, nil
+}
+
+func parsePasswordPolicy(config map[string]string) passwordPolicy {
+	policy := passwordPolicy{
+		minLength:  8,
+		minEntropy: 36,
+	}
+	if raw := config[constants.PasswordMinLength]; raw != "" {
+		if parsed, err := strconv.Atoi(raw); err == nil && parsed > 0 && parsed <= maxPasswordLength {
+			policy.minLength = parsed
+		}
+	}
+	if raw := config[constants.PasswordMinEntropy]; raw != "" {
+		if parsed, err := strconv.ParseFloat(raw, 64); err == nil && parsed >= 0 {
+			policy.minEntropy = parsed
+		}
+	}
+	return policy
+}
+
+// loadBreachCorpus reads a file of SHA-1 hashes, one per line, in the format of
+// the Have I Been Pwned downloads ("HASH:count"). Lines may be cut down to a
+// prefix of at least breachPrefixLen hex digits to save space.
+func loadBreachCorpus(path string) ([]uint64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var prefixes []uint64
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if hash, _, found := strings.Cut(line, ":"); found {
+			line = hash
+		}
+		if len(line) < breachPrefixLen {
+			continue
+		}
+		prefix, err := strconv.ParseUint(line[:breachPrefixLen], 16, 64)
+		if err != nil {
+			continue
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	slices.Sort(prefixes)
+	return slices.Compact(prefixes), nil
+}
+
+// check returns the reasons password is rejected for the account email, or nil.
+func (p passwordPolicy) check(password, email string) []string {
+	var reasons []string
+	length := len([]rune(password))
+	if length < p.minLength {
+		reasons = append(reasons, passwordTooShort)
+	}
+	if length > maxPasswordLength {
+		reasons = append(reasons, passwordTooLong)
+	}
+	if estimateEntropy(password) < p.minEntropy {
+		reasons = append(reasons, passwordTooWeak)
+	}
+	if derivedFromEmail(password, email) {
+		reasons = append(reasons, passwordContainsEmail)
+	}
+	if p.isBreached(password) {
+		reasons = append(reasons, passwordBreached)
+	}
+	return reasons
+}
+
+func (p passwordPolicy) isBreached(password string) bool {
+	if len(p.breached) == 0 {
+		return false
+	}
+	sum := sha1.Sum([]byte(password))
+	_, found := slices.BinarySearch(p.breached, binary.BigEndian.Uint64(sum[:8]))
+	return found
+}
+
+func (s *Service) checkPasswordPolicy(password, email string) error {
+	if reasons := s.policy.check(password, email); len(reasons) > 0 {
+		return &WeakPasswordError{Reasons: reasons}
+	}
+	return nil
+}
+
+// estimateEntropy is a rough guess in bits, in the spirit of zxcvbn: the size of
+// the character pool times the length, where runs of repeated characters,
+// sequences ("abcd", "4321") and keyboard walks ("qwerty") count for little.
+func estimateEntropy(password string) float64 {
+	runes := []rune(strings.ToLower(password))
+	if len(runes) == 0 {
+		return 0
+	}
+	pool := charsetSize(password)
+
+	effective := 0.0
+	run, lastStep := 1, 0
+	for i := 1; i <= len(runes); i++ {
+		if i < len(runes) {
+			step, ok := patternStep(runes[i-1], runes[i])
+			if ok && (run == 1 || step == lastStep) {
+				run++
+				lastStep = step
+				continue
+			}
+		}
+		effective += runWeight(run)
+		run = 1
+	}
+	return effective * math.Log2(float64(pool))
+}
+
+func runWeight(run int) float64 {
+	if run < 3 {
+		return float64(run)
+	}
+	return 1 + 0.25*float64(run-1)
+}
+
+// patternStep reports whether b follows a as part of a pattern, and which one:
+// 0 for a repeat, ±1 for alphabet or digit order, ±2 for a keyboard row.
+func patternStep(a, b rune) (int, bool) {
+	if a == b {
+		return 0, true
+	}
+	if (unicode.IsLetter(a) && unicode.IsLetter(b)) || (unicode.IsDigit(a) && unicode.IsDigit(b)) {
+		if d := b - a; d == 1 || d == -1 {
+			return int(d), true
+		}
+	}
+	for _, row := range keyboardRows {
+		ia, ib := strings.IndexRune(row, a), strings.IndexRune(row, b)
+		if ia < 0 || ib < 0 {
+			continue
+		}
+		if d := ib - ia; d == 1 || d == -1 {
+			return 2 * d, true
+		}
+	}
+	return 0, false
+}
+
+func charsetSize(password string) int {
+	var lower, upper, digit, symbol, other bool
+	for _, r := range password {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		case r < unicode.MaxASCII:
+			symbol = true
+		default:
+			other = true
+		}
+	}
+	size := 0
+	for _, class := range []struct {
+		present bool
+		size    int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100}} {
+		if class.present {
+			size += class.size
+		}
+	}
+	return max(size, 2)
+}
+
+// derivedFromEmail reports whether the password contains a meaningful part of the
+// email address, such as the name before the @ or the domain.
+func derivedFromEmail(password, email string) bool {
+	password = alphanumeric(password)
+	local, domain, _ := strings.Cut(strings.ToLower(email), "@")
+	local, _, _ = strings.Cut(local, "+")
+
+	parts := strings.FieldsFunc(local, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+	parts = append(parts, alphanumeric(local))
+	if labels := strings.Split(domain, "."); len(labels) > 1 {
+		for _, label := range labels[:len(labels)-1] {
+			parts = append(parts, alphanumeric(label))
+		}
+	}
+	for _, part := range parts {
+		if len(part) >= 4 && strings.Contains(password, part) {
+			return true
+		}
+	}
+	return false
+}
+
+func alphanumeric(value string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, value)
+}
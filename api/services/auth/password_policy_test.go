@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPasswordPolicyReasons(t *testing.T) {
+	policy := parsePasswordPolicy(nil)
+	cases := []struct {
+		password string
+		want     []string
+	}{
+		{"Tr0ub4dor&3", nil},
+		{"correct horse battery", nil},
+		{"k9#Lm", []string{passwordTooShort, passwordTooWeak}},
+		{"12345678", []string{passwordTooWeak}},
+		{"qwertyuiop", []string{passwordTooWeak}},
+		{"aaaaaaaaaaaa", []string{passwordTooWeak}},
+		{"budi.santoso!2024", []string{passwordContainsEmail}},
+		{"Finlog-Rocks-99", []string{passwordContainsEmail}},
+		{strings.Repeat("x7#Q", 33), []string{passwordTooLong}},
+	}
+	for _, tc := range cases {
+		got := policy.check(tc.password, "budi.santoso@finlog.asia")
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("check(%q) = %v, want %v", tc.password, got, tc.want)
+		}
+	}
+}
+
+func TestPasswordPolicyRejectsBreachedPasswords(t *testing.T) {
+	sum := sha1.Sum([]byte("Tr0ub4dor&3"))
+	full := strings.ToUpper(hex.EncodeToString(sum[:]))
+	corpus := full + ":3730471\n" + "not-a-hash\n" + full[:breachPrefixLen] + "\n"
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	if err := os.WriteFile(path, []byte(corpus), 0o600); err != nil {
+		t.Fatalf("write corpus: %v", err)
+	}
+
+	policy, err := loadPasswordPolicy(map[string]string{"PASSWORD_BREACH_CORPUS": path})
+	if err != nil {
+		t.Fatalf("load policy: %v", err)
+	}
+	if len(policy.breached) != 1 {
+		t.Fatalf("expected one deduplicated entry, got %d", len(policy.breached))
+	}
+	if got := policy.check("Tr0ub4dor&3", "user@example.com"); !slices.Equal(got, []string{passwordBreached}) {
+		t.Fatalf("expected breached password to be rejected, got %v", got)
+	}
+	if got := policy.check("correct horse battery", "user@example.com"); got != nil {
+		t.Fatalf("expected other passwords to pass, got %v", got)
+	}
+
+	if _, err := loadPasswordPolicy(map[string]string{"PASSWORD_BREACH_CORPUS": filepath.Join(t.TempDir(), "missing")}); err == nil {
+		t.Fatalf("expected a missing corpus to be reported")
+	}
+}
+
+func TestChangePasswordEnforcesPolicy(t *testing.T) {
+	svc := newTestService(verifiedUser(t))
+
+	var weakErr *WeakPasswordError
+	err := svc.ChangePassword(context.Background(), 1, "", "secret123", "user1234")
+	if !errors.As(err, &weakErr) || !slices.Contains(weakErr.Reasons, passwordContainsEmail) {
+		t.Fatalf("expected the policy to reject an email-derived password, got %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestPasswordHasherRoundTrip(t *testing.T) {
+	hashed, err := testPasswordHasher.hash("secret123")
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+	if !strings.HasPrefix(hashed, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Fatalf("expected parameters encoded in the hash, got %q", hashed)
+	}
+	if ok, rehash := testPasswordHasher.verify(hashed, "secret123"); !ok || rehash {
+		t.Fatalf("expected a current hash to verify without rehash, got %v, %v", ok, rehash)
+	}
+	if ok, _ := testPasswordHasher.verify(hashed, "secret124"); ok {
+		t.Fatalf("expected a wrong password to be rejected")
+	}
+
+	stronger := passwordHasher{memory: 128, time: 2, threads: 1}
+	if ok, rehash := stronger.verify(hashed, "secret123"); !ok || !rehash {
+		t.Fatalf("expected outdated parameters to verify and ask for a rehash, got %v, %v", ok, rehash)
+	}
+	if ok, _ := testPasswordHasher.verify("$argon2id$v=19$m=64$bad", "secret123"); ok {
+		t.Fatalf("expected a malformed hash to be rejected")
+	}
+}
+
+func TestLoginMigratesBcryptHashToArgon2(t *testing.T) {
+	user := verifiedUser(t)
+	svc := newTestService(user)
+
+	if _, _, _, err := svc.Login(context.Background(), "user@example.com", "secret123"); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	migrated := user.Password
+	if !strings.HasPrefix(migrated, argon2Prefix) {
+		t.Fatalf("expected the bcrypt hash to be replaced, got %q", migrated)
+	}
+
+	if _, _, _, err := svc.Login(context.Background(), "user@example.com", "secret123"); err != nil {
+		t.Fatalf("login with migrated hash: %v", err)
+	}
+	if user.Password != migrated {
+		t.Fatalf("expected a current hash to be kept")
+	}
+}
@@ -29,4 +29,299 @@ const (
 		SET is_verified = 1, verification_token = NULL, verification_expires_at = NULL
 		WHERE id = ?
 	`
+
+	findByPasswordResetToken = `
+		SELECT * FROM users WHERE password_reset_token = ? LIMIT 1
+	`
+
+	updatePasswordResetToken = `
+		UPDATE users
+		SET password_reset_token = ?, password_reset_expires_at = ?
+		WHERE id = ?
+	`
+
+	resetPassword = `
+		UPDATE users
+		SET password = ?, password_reset_token = NULL, password_reset_expires_at = NULL
+		WHERE id = ? AND password_reset_token = ?
+	`
+
+	updatePassword = `
+		UPDATE users
+		SET password = ?
+		WHERE id = ?
+	`
+
+	setPendingEmail = `
+		UPDATE users
+		SET pending_email = ?, verification_token = ?, verification_expires_at = ?
+		WHERE id = ?
+	`
+
+	recordPendingEmail = `
+		INSERT IGNORE INTO user_email_history (user_id, email)
+		SELECT id, pending_email FROM users WHERE id = ? AND pending_email IS NOT NULL
+	`
+
+	recordReplacedEmail = `
+		INSERT IGNORE INTO user_email_history (user_id, email)
+		SELECT id, email FROM users WHERE id = ? AND pending_email = ?
+	`
+
+	confirmPendingEmail = `
+		UPDATE users
+		SET email = pending_email, pending_email = NULL, verification_token = NULL, verification_expires_at = NULL
+		WHERE id = ? AND pending_email = ?
+	`
+
+	insertRefreshToken = `
+		INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at)
+		VALUES (?, ?, ?, ?)
+	`
+
+	findRefreshTokenByHash = `
+		SELECT id, user_id, family_id, token_hash, expires_at, rotated_at, revoked_at, created_at
+		FROM refresh_tokens
+		WHERE token_hash = ?
+		LIMIT 1
+	`
+
+	markRefreshTokenRotated = `
+		UPDATE refresh_tokens
+		SET rotated_at = NOW()
+		WHERE id = ? AND rotated_at IS NULL AND revoked_at IS NULL
+	`
+
+	revokeRefreshTokenFamily = `
+		UPDATE refresh_tokens
+		SET revoked_at = NOW()
+		WHERE user_id = ? AND family_id = ? AND revoked_at IS NULL
+	`
+
+	revokeUserRefreshTokens = `
+		UPDATE refresh_tokens
+		SET revoked_at = NOW()
+		WHERE user_id = ? AND revoked_at IS NULL
+	`
+
+	revokeOtherRefreshTokenFamilies = `
+		UPDATE refresh_tokens
+		SET revoked_at = NOW()
+		WHERE user_id = ? AND family_id <> ? AND revoked_at IS NULL
+	`
+
+	setMFASecret = `
+		UPDATE users
+		SET mfa_secret = ?, mfa_enabled = 0, mfa_last_step = NULL
+		WHERE id = ?
+	`
+
+	enableMFA = `
+		UPDATE users
+		SET mfa_enabled = 1, mfa_last_step = ?
+		WHERE id = ? AND mfa_secret IS NOT NULL
+	`
+
+	disableMFA = `
+		UPDATE users
+		SET mfa_enabled = 0, mfa_secret = NULL, mfa_last_step = NULL
+		WHERE id = ?
+	`
+
+	updateMFALastStep = `
+		UPDATE users
+		SET mfa_last_step = ?
+		WHERE id = ? AND (mfa_last_step IS NULL OR mfa_last_step < ?)
+	`
+
+	deleteRecoveryCodes = `
+		DELETE FROM mfa_recovery_codes WHERE user_id = ?
+	`
+
+	insertRecoveryCode = `
+		INSERT INTO mfa_recovery_codes (user_id, code_hash)
+		VALUES (?, ?)
+	`
+
+	useRecoveryCode = `
+		UPDATE mfa_recovery_codes
+		SET used_at = NOW()
+		WHERE user_id = ? AND code_hash = ? AND used_at IS NULL
+	`
+
+	insertPasskey = `
+		INSERT INTO webauthn_credentials (user_id, credential_id, name, credential, sign_count)
+		VALUES (?, ?, ?, ?, ?)
+	`
+
+	listPasskeys = `
+		SELECT * FROM webauthn_credentials WHERE user_id = ? ORDER BY created_at, id
+	`
+
+	findPasskeyByCredentialID = `
+		SELECT * FROM webauthn_credentials WHERE credential_id = ? LIMIT 1
+	`
+
+	updatePasskeyUsage = `
+		UPDATE webauthn_credentials
+		SET sign_count = ?, credential = ?, last_used_at = NOW()
+		WHERE id = ?
+	`
+
+	deletePasskey = `
+		DELETE FROM webauthn_credentials WHERE id = ? AND user_id = ?
+	`
+
+	insertWebAuthnSession = `
+		INSERT INTO webauthn_sessions (id, user_id, ceremony, session_data, expires_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+
+	deleteExpiredWebAuthnSessions = `
+		DELETE FROM webauthn_sessions WHERE expires_at < ?
+	`
+
+	findWebAuthnSessionForUpdate = `
+		SELECT * FROM webauthn_sessions WHERE id = ? AND ceremony = ? FOR UPDATE
+	`
+
+	deleteWebAuthnSession = `
+		DELETE FROM webauthn_sessions WHERE id = ?
+	`
+
+	revokeSessionFamily = `
+		UPDATE user_sessions
+		SET revoked_at = NOW()
+		WHERE user_id = ? AND family_id = ? AND revoked_at IS NULL
+	`
+
+	revokeUserSessions = `
+		UPDATE user_sessions
+		SET revoked_at = NOW()
+		WHERE user_id = ? AND revoked_at IS NULL
+	`
+
+	revokeOtherSessions = `
+		UPDATE user_sessions
+		SET revoked_at = NOW()
+		WHERE user_id = ? AND family_id <> ? AND revoked_at IS NULL
+	`
+
+	upsertSession = `
+		INSERT INTO user_sessions (user_id, family_id, device_name, user_agent, ip_address, created_at, last_seen_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			device_name = IF(VALUES(device_name) = '', device_name, VALUES(device_name)),
+			user_agent = VALUES(user_agent),
+			ip_address = VALUES(ip_address),
+			last_seen_at = VALUES(last_seen_at)
+	`
+
+	touchSession = `
+		UPDATE user_sessions
+		SET last_seen_at = ?, device_name = IF(? = '', device_name, ?), user_agent = ?, ip_address = ?
+		WHERE id = ?
+	`
+
+	findSessionByFamily = `
+		SELECT * FROM user_sessions WHERE family_id = ? LIMIT 1
+	`
+
+	findSession = `
+		SELECT * FROM user_sessions WHERE id = ? AND user_id = ? LIMIT 1
+	`
+
+	listActiveSessions = `
+		SELECT * FROM user_sessions
+		WHERE user_id = ? AND revoked_at IS NULL AND last_seen_at >= ?
+		ORDER BY last_seen_at DESC, id DESC
+	`
+
+	findLoginThrottle = `
+		SELECT * FROM login_throttles WHERE scope = ? AND scope_key = ? LIMIT 1
+	`
+
+	recordLoginFailure = `
+		INSERT INTO login_throttles (scope, scope_key, failures, last_failure_at)
+		VALUES (?, ?, 1, ?)
+		ON DUPLICATE KEY UPDATE
+			failures = IF(last_failure_at < ?, 1, failures + 1),
+			last_failure_at = VALUES(last_failure_at)
+	`
+
+	countLoginFailures = `
+		SELECT failures FROM login_throttles WHERE scope = ? AND scope_key = ?
+	`
+
+	lockLogin = `
+		UPDATE login_throttles
+		SET locked_until = ?,
+			unlock_user_id = COALESCE(?, unlock_user_id),
+			unlock_token = COALESCE(?, unlock_token),
+			unlock_expires_at = COALESCE(?, unlock_expires_at)
+		WHERE scope = ? AND scope_key = ?
+	`
+
+	clearLoginThrottle = `
+		DELETE FROM login_throttles WHERE scope = ? AND scope_key = ?
+	`
+
+	findUnlockUser = `
+		SELECT unlock_user_id FROM login_throttles
+		WHERE unlock_token = ? AND unlock_expires_at > ?
+		LIMIT 1
+	`
+
+	unlockLoginByToken = `
+		DELETE FROM login_throttles WHERE unlock_token = ? AND unlock_expires_at > ?
+	`
+
+	insertOIDCState = `
+		INSERT INTO oidc_states (id, provider, code_verifier, nonce, expires_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+
+	deleteExpiredOIDCStates = `
+		DELETE FROM oidc_states WHERE expires_at < ?
+	`
+
+	findOIDCStateForUpdate = `
+		SELECT * FROM oidc_states WHERE id = ? AND provider = ? FOR UPDATE
+	`
+
+	deleteOIDCState = `
+		DELETE FROM oidc_states WHERE id = ?
+	`
+
+	findIdentity = `
+		SELECT * FROM user_identities WHERE provider = ? AND subject = ? LIMIT 1
+	`
+
+	insertIdentity = `
+		INSERT INTO user_identities (user_id, provider, subject, email, created_at, last_login_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+
+	touchIdentity = `
+		UPDATE user_identities SET email = ?, last_login_at = ? WHERE id = ?
+	`
+
+	insertMagicLink = `
+		INSERT INTO magic_links (user_id, token_hash, device_hash, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+
+	countMagicLinksSince = `
+		SELECT COUNT(*) FROM magic_links WHERE user_id = ? AND created_at > ?
+	`
+
+	findMagicLink = `
+		SELECT * FROM magic_links WHERE token_hash = ? LIMIT 1
+	`
+
+	consumeMagicLink = `
+		UPDATE magic_links
+		SET consumed_at = ?
+		WHERE id = ? AND consumed_at IS NULL AND expires_at > ?
+	`
 )
@@ -2,6 +2,8 @@ package auth
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"time"
 
 	"finlog-api/api/contracts"
@@ -23,9 +25,62 @@ type Statement struct {
 	insertUser              *sqlx.Stmt
 	updateVerificationToken *sqlx.Stmt
 	markUserVerified        *sqlx.Stmt
+
+	findByPasswordResetToken *sqlx.Stmt
+	updatePasswordResetToken *sqlx.Stmt
+	resetPassword            *sqlx.Stmt
+	updatePassword           *sqlx.Stmt
+	setPendingEmail          *sqlx.Stmt
+	confirmPendingEmail      *sqlx.Stmt
+
+	insertRefreshToken       *sqlx.Stmt
+	findRefreshTokenByHash   *sqlx.Stmt
+	markRefreshTokenRotated  *sqlx.Stmt
+	revokeRefreshTokenFamily *sqlx.Stmt
+	revokeUserRefreshTokens  *sqlx.Stmt
+	revokeOtherFamilies      *sqlx.Stmt
+
+	setMFASecret      *sqlx.Stmt
+	enableMFA         *sqlx.Stmt
+	disableMFA        *sqlx.Stmt
+	updateMFALastStep *sqlx.Stmt
+	useRecoveryCode   *sqlx.Stmt
+
+	insertPasskey             *sqlx.Stmt
+	listPasskeys              *sqlx.Stmt
+	findPasskeyByCredentialID *sqlx.Stmt
+	updatePasskeyUsage        *sqlx.Stmt
+	deletePasskey             *sqlx.Stmt
+	insertWebAuthnSession     *sqlx.Stmt
+	deleteExpiredSessions     *sqlx.Stmt
+
+	upsertSession       *sqlx.Stmt
+	touchSession        *sqlx.Stmt
+	findSessionByFamily *sqlx.Stmt
+	findSession         *sqlx.Stmt
+	listActiveSessions  *sqlx.Stmt
+
+	findLoginThrottle  *sqlx.Stmt
+	recordLoginFailure *sqlx.Stmt
+	countLoginFailures *sqlx.Stmt
+	lockLogin          *sqlx.Stmt
+	clearLoginThrottle *sqlx.Stmt
+	findUnlockUser     *sqlx.Stmt
+	unlockLoginByToken *sqlx.Stmt
+
+	insertOIDCState         *sqlx.Stmt
+	deleteExpiredOIDCStates *sqlx.Stmt
+	findIdentity            *sqlx.Stmt
+	insertIdentity          *sqlx.Stmt
+	touchIdentity           *sqlx.Stmt
+
+	insertMagicLink      *sqlx.Stmt
+	countMagicLinksSince *sqlx.Stmt
+	findMagicLink        *sqlx.Stmt
+	consumeMagicLink     *sqlx.Stmt
 }
 
-func initRepository(app *contracts.App) contracts.AuthRepository {
+func initRepository(app *contracts.App) *Repository {
 	stmts := Statement{
 		findByEmail:             datasources.Prepare(app.Ds.ReaderDB, findByEmail),
 		findByID:                datasources.Prepare(app.Ds.ReaderDB, findByID),
@@ -33,6 +88,59 @@ func initRepository(app *contracts.App) contracts.AuthRepository {
 		insertUser:              datasources.Prepare(app.Ds.WriterDB, insertUser),
 		updateVerificationToken: datasources.Prepare(app.Ds.WriterDB, updateVerificationToken),
 		markUserVerified:        datasources.Prepare(app.Ds.WriterDB, markUserVerified),
+
+		findByPasswordResetToken: datasources.Prepare(app.Ds.WriterDB, findByPasswordResetToken),
+		updatePasswordResetToken: datasources.Prepare(app.Ds.WriterDB, updatePasswordResetToken),
+		resetPassword:            datasources.Prepare(app.Ds.WriterDB, resetPassword),
+		updatePassword:           datasources.Prepare(app.Ds.WriterDB, updatePassword),
+		setPendingEmail:          datasources.Prepare(app.Ds.WriterDB, setPendingEmail),
+		confirmPendingEmail:      datasources.Prepare(app.Ds.WriterDB, confirmPendingEmail),
+
+		insertRefreshToken:       datasources.Prepare(app.Ds.WriterDB, insertRefreshToken),
+		findRefreshTokenByHash:   datasources.Prepare(app.Ds.WriterDB, findRefreshTokenByHash),
+		markRefreshTokenRotated:  datasources.Prepare(app.Ds.WriterDB, markRefreshTokenRotated),
+		revokeRefreshTokenFamily: datasources.Prepare(app.Ds.WriterDB, revokeRefreshTokenFamily),
+		revokeUserRefreshTokens:  datasources.Prepare(app.Ds.WriterDB, revokeUserRefreshTokens),
+		revokeOtherFamilies:      datasources.Prepare(app.Ds.WriterDB, revokeOtherRefreshTokenFamilies),
+
+		setMFASecret:      datasources.Prepare(app.Ds.WriterDB, setMFASecret),
+		enableMFA:         datasources.Prepare(app.Ds.WriterDB, enableMFA),
+		disableMFA:        datasources.Prepare(app.Ds.WriterDB, disableMFA),
+		updateMFALastStep: datasources.Prepare(app.Ds.WriterDB, updateMFALastStep),
+		useRecoveryCode:   datasources.Prepare(app.Ds.WriterDB, useRecoveryCode),
+
+		insertPasskey:             datasources.Prepare(app.Ds.WriterDB, insertPasskey),
+		listPasskeys:              datasources.Prepare(app.Ds.ReaderDB, listPasskeys),
+		findPasskeyByCredentialID: datasources.Prepare(app.Ds.ReaderDB, findPasskeyByCredentialID),
+		updatePasskeyUsage:        datasources.Prepare(app.Ds.WriterDB, updatePasskeyUsage),
+		deletePasskey:             datasources.Prepare(app.Ds.WriterDB, deletePasskey),
+		insertWebAuthnSession:     datasources.Prepare(app.Ds.WriterDB, insertWebAuthnSession),
+		deleteExpiredSessions:     datasources.Prepare(app.Ds.WriterDB, deleteExpiredWebAuthnSessions),
+
+		upsertSession:       datasources.Prepare(app.Ds.WriterDB, upsertSession),
+		touchSession:        datasources.Prepare(app.Ds.WriterDB, touchSession),
+		findSessionByFamily: datasources.Prepare(app.Ds.WriterDB, findSessionByFamily),
+		findSession:         datasources.Prepare(app.Ds.ReaderDB, findSession),
+		listActiveSessions:  datasources.Prepare(app.Ds.ReaderDB, listActiveSessions),
+
+		findLoginThrottle:  datasources.Prepare(app.Ds.WriterDB, findLoginThrottle),
+		recordLoginFailure: datasources.Prepare(app.Ds.WriterDB, recordLoginFailure),
+		countLoginFailures: datasources.Prepare(app.Ds.WriterDB, countLoginFailures),
+		lockLogin:          datasources.Prepare(app.Ds.WriterDB, lockLogin),
+		clearLoginThrottle: datasources.Prepare(app.Ds.WriterDB, clearLoginThrottle),
+		findUnlockUser:     datasources.Prepare(app.Ds.WriterDB, findUnlockUser),
+		unlockLoginByToken: datasources.Prepare(app.Ds.WriterDB, unlockLoginByToken),
+
+		insertOIDCState:         datasources.Prepare(app.Ds.WriterDB, insertOIDCState),
+		deleteExpiredOIDCStates: datasources.Prepare(app.Ds.WriterDB, deleteExpiredOIDCStates),
+		findIdentity:            datasources.Prepare(app.Ds.ReaderDB, findIdentity),
+		insertIdentity:          datasources.Prepare(app.Ds.WriterDB, insertIdentity),
+		touchIdentity:           datasources.Prepare(app.Ds.WriterDB, touchIdentity),
+
+		insertMagicLink:      datasources.Prepare(app.Ds.WriterDB, insertMagicLink),
+		countMagicLinksSince: datasources.Prepare(app.Ds.WriterDB, countMagicLinksSince),
+		findMagicLink:        datasources.Prepare(app.Ds.ReaderDB, findMagicLink),
+		consumeMagicLink:     datasources.Prepare(app.Ds.WriterDB, consumeMagicLink),
 	}
 
 	r := Repository{
@@ -98,3 +206,496 @@ func (r *Repository) MarkUserAsVerified(ctx context.Context, userID int64) error
 	_, err := r.stmt.markUserVerified.ExecContext(ctx, userID)
 	return err
 }
+
+// FindByPasswordResetToken reads from the writer, so a link opened right after it
+// was sent resolves and a used token stops resolving at once.
+func (r *Repository) FindByPasswordResetToken(ctx context.Context, token string) (*entities.User, error) {
+	user := new(entities.User)
+	if err := r.stmt.findByPasswordResetToken.GetContext(ctx, user, token); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (r *Repository) UpdatePasswordResetToken(ctx context.Context, userID int64, token *string, expiresAt *time.Time) error {
+	_, err := r.stmt.updatePasswordResetToken.ExecContext(ctx, token, expiresAt, userID)
+	return err
+}
+
+// ResetPassword swaps the password and consumes the reset token in one statement,
+// so a token can only ever be redeemed once.
+func (r *Repository) ResetPassword(ctx context.Context, userID int64, token string, hashedPassword string) (bool, error) {
+	res, err := r.stmt.resetPassword.ExecContext(ctx, hashedPassword, userID, token)
+	if err != nil {
+		return false, err
+	}
+	affected, _ := res.RowsAffected()
+	return affected > 0, nil
+}
+
+func (r *Repository) UpdatePassword(ctx context.Context, userID int64, hashedPassword string) error {
+	_, err := r.stmt.updatePassword.ExecContext(ctx, hashedPassword, userID)
+	return err
+}
+
+// SetPendingEmail records an email change awaiting confirmation. The confirmation
+// token shares the verification token columns so /auth/verify can complete it.
+// A pending address it replaces was mailed, so it goes to the email history that
+// the account purge clears.
+func (r *Repository) SetPendingEmail(ctx context.Context, userID int64, email *string, token *string, expiresAt *time.Time) error {
+	tx, err := r.app.Ds.WriterDB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, recordPendingEmail, userID); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if _, err := tx.StmtxContext(ctx, r.stmt.setPendingEmail).ExecContext(ctx, email, token, expiresAt, userID); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// ConfirmPendingEmail promotes the pending email, provided it has not changed
+// meanwhile. The replaced address goes to the email history.
+func (r *Repository) ConfirmPendingEmail(ctx context.Context, userID int64, email string) (bool, error) {
+	tx, err := r.app.Ds.WriterDB.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	if _, err := tx.ExecContext(ctx, recordReplacedEmail, userID, email); err != nil {
+		_ = tx.Rollback()
+		return false, err
+	}
+	res, err := tx.StmtxContext(ctx, r.stmt.confirmPendingEmail).ExecContext(ctx, userID, email)
+	if err != nil {
+		_ = tx.Rollback()
+		return false, err
+	}
+	affected, _ := res.RowsAffected()
+	if affected == 0 {
+		_ = tx.Rollback()
+		return false, nil
+	}
+	return true, tx.Commit()
+}
+
+// CreateRefreshToken stores the hash of a newly issued refresh token.
+func (r *Repository) CreateRefreshToken(ctx context.Context, token *entities.RefreshToken) (int64, error) {
+	res, err := r.stmt.insertRefreshToken.ExecContext(ctx, token.UserID, token.FamilyID, token.TokenHash, token.ExpiresAt)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// FindRefreshTokenByHash returns the stored refresh token regardless of its state.
+// It reads from the writer: a token issued or rotated a moment ago may not have
+// reached a replica, and a stale rotated_at would hide reuse.
+func (r *Repository) FindRefreshTokenByHash(ctx context.Context, tokenHash string) (*entities.RefreshToken, error) {
+	token := new(entities.RefreshToken)
+	if err := r.stmt.findRefreshTokenByHash.GetContext(ctx, token, tokenHash); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// MarkRefreshTokenRotated flags a token as used. It reports false when the token
+// was already rotated or revoked, which callers must treat as reuse.
+func (r *Repository) MarkRefreshTokenRotated(ctx context.Context, id int64) (bool, error) {
+	res, err := r.stmt.markRefreshTokenRotated.ExecContext(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	affected, _ := res.RowsAffected()
+	return affected > 0, nil
+}
+
+// RevokeRefreshTokenFamily revokes every token issued for a single login, and its session.
+func (r *Repository) RevokeRefreshTokenFamily(ctx context.Context, userID int64, familyID string) error {
+	return r.revokeWithSessions(ctx, r.stmt.revokeRefreshTokenFamily, revokeSessionFamily, userID, familyID)
+}
+
+// RevokeUserRefreshTokens revokes every token family and session owned by the user.
+func (r *Repository) RevokeUserRefreshTokens(ctx context.Context, userID int64) error {
+	return r.revokeWithSessions(ctx, r.stmt.revokeUserRefreshTokens, revokeUserSessions, userID)
+}
+
+// RevokeOtherRefreshTokenFamilies revokes every login of the user except keepFamilyID.
+func (r *Repository) RevokeOtherRefreshTokenFamilies(ctx context.Context, userID int64, keepFamilyID string) error {
+	return r.revokeWithSessions(ctx, r.stmt.revokeOtherFamilies, revokeOtherSessions, userID, keepFamilyID)
+}
+
+// revokeWithSessions runs a refresh token revocation and the matching session
+// revocation in one transaction; both statements take the same arguments.
+func (r *Repository) revokeWithSessions(ctx context.Context, tokenStmt *sqlx.Stmt, sessionQuery string, args ...interface{}) error {
+	tx, err := r.app.Ds.WriterDB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.StmtxContext(ctx, tokenStmt).ExecContext(ctx, args...); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, sessionQuery, args...); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetMFASecret stores a pending TOTP secret. MFA stays disabled until EnableMFA.
+func (r *Repository) SetMFASecret(ctx context.Context, userID int64, secret *string) error {
+	_, err := r.stmt.setMFASecret.ExecContext(ctx, secret, userID)
+	return err
+}
+
+func (r *Repository) EnableMFA(ctx context.Context, userID int64, step int64) error {
+	_, err := r.stmt.enableMFA.ExecContext(ctx, step, userID)
+	return err
+}
+
+// DisableMFA removes the TOTP secret together with every recovery code.
+func (r *Repository) DisableMFA(ctx context.Context, userID int64) error {
+	tx, err := r.app.Ds.WriterDB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.StmtxContext(ctx, r.stmt.disableMFA).ExecContext(ctx, userID); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, deleteRecoveryCodes, userID); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateMFALastStep records the last accepted TOTP step. It reports false when a
+// newer or equal step was already used, i.e. the code is being replayed.
+func (r *Repository) UpdateMFALastStep(ctx context.Context, userID int64, step int64) (bool, error) {
+	res, err := r.stmt.updateMFALastStep.ExecContext(ctx, step, userID, step)
+	if err != nil {
+		return false, err
+	}
+	affected, _ := res.RowsAffected()
+	return affected > 0, nil
+}
+
+// ReplaceRecoveryCodes swaps the user's recovery codes for a new set.
+func (r *Repository) ReplaceRecoveryCodes(ctx context.Context, userID int64, codeHashes []string) error {
+	tx, err := r.app.Ds.WriterDB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, deleteRecoveryCodes, userID); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	for _, hash := range codeHashes {
+		if _, err := tx.ExecContext(ctx, insertRecoveryCode, userID, hash); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// UseRecoveryCode consumes a recovery code, reporting false if it is unknown or used.
+func (r *Repository) UseRecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error) {
+	res, err := r.stmt.useRecoveryCode.ExecContext(ctx, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	affected, _ := res.RowsAffected()
+	return affected > 0, nil
+}
+
+// CreatePasskey stores a newly registered WebAuthn credential.
+func (r *Repository) CreatePasskey(ctx context.Context, passkey *entities.Passkey) (int64, error) {
+	res, err := r.stmt.insertPasskey.ExecContext(
+		ctx,
+		passkey.UserID,
+		passkey.CredentialID,
+		passkey.Name,
+		passkey.Credential,
+		passkey.SignCount,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (r *Repository) ListPasskeys(ctx context.Context, userID int64) ([]entities.Passkey, error) {
+	var passkeys []entities.Passkey
+	if err := r.stmt.listPasskeys.SelectContext(ctx, &passkeys, userID); err != nil {
+		return nil, err
+	}
+	return passkeys, nil
+}
+
+func (r *Repository) FindPasskeyByCredentialID(ctx context.Context, credentialID []byte) (*entities.Passkey, error) {
+	passkey := new(entities.Passkey)
+	if err := r.stmt.findPasskeyByCredentialID.GetContext(ctx, passkey, credentialID); err != nil {
+		return nil, err
+	}
+	return passkey, nil
+}
+
+// UpdatePasskeyUsage records the signature counter and flags of a successful assertion.
+func (r *Repository) UpdatePasskeyUsage(ctx context.Context, id int64, signCount uint32, credential string) error {
+	_, err := r.stmt.updatePasskeyUsage.ExecContext(ctx, signCount, credential, id)
+	return err
+}
+
+// DeletePasskey removes a credential owned by the user, reporting false if none matched.
+func (r *Repository) DeletePasskey(ctx context.Context, userID, id int64) (bool, error) {
+	res, err := r.stmt.deletePasskey.ExecContext(ctx, id, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, _ := res.RowsAffected()
+	return affected > 0, nil
+}
+
+// CreateWebAuthnSession stores ceremony state and clears out abandoned ceremonies.
+func (r *Repository) CreateWebAuthnSession(ctx context.Context, session *entities.WebAuthnSession) error {
+	if _, err := r.stmt.deleteExpiredSessions.ExecContext(ctx, time.Now().UTC()); err != nil {
+		return err
+	}
+	_, err := r.stmt.insertWebAuthnSession.ExecContext(
+		ctx,
+		session.ID,
+		session.UserID,
+		session.Ceremony,
+		session.SessionData,
+		session.ExpiresAt,
+	)
+	return err
+}
+
+// ConsumeWebAuthnSession loads and deletes ceremony state in one transaction, so a
+// challenge can only be answered once. Unknown sessions yield sql.ErrNoRows.
+func (r *Repository) ConsumeWebAuthnSession(ctx context.Context, id, ceremony string) (*entities.WebAuthnSession, error) {
+	tx, err := r.app.Ds.WriterDB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	session := new(entities.WebAuthnSession)
+	if err := tx.GetContext(ctx, session, findWebAuthnSessionForUpdate, id, ceremony); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, deleteWebAuthnSession, id); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// UpsertSession records the login behind a refresh token family, or refreshes the
+// device details of an existing one. A revoked session stays revoked.
+func (r *Repository) UpsertSession(ctx context.Context, session *entities.Session) error {
+	_, err := r.stmt.upsertSession.ExecContext(
+		ctx,
+		session.UserID,
+		session.FamilyID,
+		session.DeviceName,
+		session.UserAgent,
+		session.IPAddress,
+		session.CreatedAt,
+		session.LastSeenAt,
+	)
+	return err
+}
+
+func (r *Repository) TouchSession(ctx context.Context, id int64, seenAt time.Time, client entities.ClientInfo) error {
+	_, err := r.stmt.touchSession.ExecContext(
+		ctx,
+		seenAt,
+		client.DeviceName,
+		client.DeviceName,
+		client.UserAgent,
+		client.IPAddress,
+		id,
+	)
+	return err
+}
+
+func (r *Repository) FindSessionByFamily(ctx context.Context, familyID string) (*entities.Session, error) {
+	session := new(entities.Session)
+	if err := r.stmt.findSessionByFamily.GetContext(ctx, session, familyID); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (r *Repository) FindSession(ctx context.Context, userID, id int64) (*entities.Session, error) {
+	session := new(entities.Session)
+	if err := r.stmt.findSession.GetContext(ctx, session, id, userID); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// ListActiveSessions returns unrevoked sessions seen since seenSince, most recent first.
+func (r *Repository) ListActiveSessions(ctx context.Context, userID int64, seenSince time.Time) ([]entities.Session, error) {
+	var sessions []entities.Session
+	if err := r.stmt.listActiveSessions.SelectContext(ctx, &sessions, userID, seenSince); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// FindLoginThrottle reads from the writer so a lock set by another container is
+// visible immediately, without waiting for replication.
+func (r *Repository) FindLoginThrottle(ctx context.Context, scope, key string) (*entities.LoginThrottle, error) {
+	throttle := new(entities.LoginThrottle)
+	if err := r.stmt.findLoginThrottle.GetContext(ctx, throttle, scope, key); err != nil {
+		return nil, err
+	}
+	return throttle, nil
+}
+
+// RecordLoginFailure bumps the failure counter and returns its new value. Counters
+// whose last failure is older than resetBefore start again from one.
+func (r *Repository) RecordLoginFailure(ctx context.Context, scope, key string, at, resetBefore time.Time) (int, error) {
+	if _, err := r.stmt.recordLoginFailure.ExecContext(ctx, scope, key, at, resetBefore); err != nil {
+		return 0, err
+	}
+	var failures int
+	if err := r.stmt.countLoginFailures.GetContext(ctx, &failures, scope, key); err != nil {
+		return 0, err
+	}
+	return failures, nil
+}
+
+// LockLogin blocks the scope until the given time. A nil unlock token keeps the current one.
+func (r *Repository) LockLogin(ctx context.Context, scope, key string, until time.Time, unlockUserID *int64, unlockToken *string, unlockExpiresAt *time.Time) error {
+	_, err := r.stmt.lockLogin.ExecContext(ctx, until, unlockUserID, unlockToken, unlockExpiresAt, scope, key)
+	return err
+}
+
+func (r *Repository) ClearLoginThrottle(ctx context.Context, scope, key string) error {
+	_, err := r.stmt.clearLoginThrottle.ExecContext(ctx, scope, key)
+	return err
+}
+
+// UnlockLoginByToken removes the lock identified by an emailed unlock token. Of
+// two concurrent calls with the same token only the one that deletes it succeeds.
+func (r *Repository) UnlockLoginByToken(ctx context.Context, tokenHash string, now time.Time) (int64, bool, error) {
+	var userID sql.NullInt64
+	if err := r.stmt.findUnlockUser.GetContext(ctx, &userID, tokenHash, now); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+	res, err := r.stmt.unlockLoginByToken.ExecContext(ctx, tokenHash, now)
+	if err != nil {
+		return 0, false, err
+	}
+	affected, _ := res.RowsAffected()
+	return userID.Int64, affected > 0, nil
+}
+
+// CreateOIDCState stores a pending login and clears out abandoned ones.
+func (r *Repository) CreateOIDCState(ctx context.Context, state *entities.OIDCState) error {
+	if _, err := r.stmt.deleteExpiredOIDCStates.ExecContext(ctx, time.Now().UTC()); err != nil {
+		return err
+	}
+	_, err := r.stmt.insertOIDCState.ExecContext(ctx, state.ID, state.Provider, state.CodeVerifier, state.Nonce, state.ExpiresAt)
+	return err
+}
+
+// ConsumeOIDCState loads and deletes a pending login in one transaction, so each
+// state value is accepted once. Unknown states yield sql.ErrNoRows.
+func (r *Repository) ConsumeOIDCState(ctx context.Context, id, provider string) (*entities.OIDCState, error) {
+	tx, err := r.app.Ds.WriterDB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	state := new(entities.OIDCState)
+	if err := tx.GetContext(ctx, state, findOIDCStateForUpdate, id, provider); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, deleteOIDCState, id); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+func (r *Repository) FindIdentity(ctx context.Context, provider, subject string) (*entities.UserIdentity, error) {
+	identity := new(entities.UserIdentity)
+	if err := r.stmt.findIdentity.GetContext(ctx, identity, provider, subject); err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
+
+func (r *Repository) CreateIdentity(ctx context.Context, identity *entities.UserIdentity) (int64, error) {
+	res, err := r.stmt.insertIdentity.ExecContext(
+		ctx,
+		identity.UserID,
+		identity.Provider,
+		identity.Subject,
+		identity.Email,
+		identity.CreatedAt,
+		identity.LastLoginAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (r *Repository) TouchIdentity(ctx context.Context, id int64, email string, at time.Time) error {
+	_, err := r.stmt.touchIdentity.ExecContext(ctx, email, at, id)
+	return err
+}
+
+func (r *Repository) CreateMagicLink(ctx context.Context, link *entities.MagicLink) error {
+	_, err := r.stmt.insertMagicLink.ExecContext(ctx, link.UserID, link.TokenHash, link.DeviceHash, link.ExpiresAt, link.CreatedAt)
+	return err
+}
+
+// CountMagicLinksSince reads from the writer so every container sees the same
+// count when rate limiting.
+func (r *Repository) CountMagicLinksSince(ctx context.Context, userID int64, since time.Time) (int, error) {
+	var count int
+	if err := r.stmt.countMagicLinksSince.GetContext(ctx, &count, userID, since); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *Repository) FindMagicLink(ctx context.Context, tokenHash string) (*entities.MagicLink, error) {
+	link := new(entities.MagicLink)
+	if err := r.stmt.findMagicLink.GetContext(ctx, link, tokenHash); err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+// ConsumeMagicLink marks the link used. It reports false when the link was already
+// used or has expired, so each link signs in once.
+func (r *Repository) ConsumeMagicLink(ctx context.Context, id int64, now time.Time) (bool, error) {
+	res, err := r.stmt.consumeMagicLink.ExecContext(ctx, now, id, now)
+	if err != nil {
+		return false, err
+	}
+	affected, _ := res.RowsAffected()
+	return affected > 0, nil
+}
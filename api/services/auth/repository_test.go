@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"finlog-api/api/contracts"
+	"finlog-api/api/datasources/dbtest"
+)
+
+func TestEmailChangesAreKeptInHistory(t *testing.T) {
+	db := dbtest.Open(t)
+	userID := dbtest.Seed(t, db, 1, 1)[0]
+	repo := initRepository(&contracts.App{Ds: &contracts.Datasources{WriterDB: db, ReaderDB: db}})
+	ctx := context.Background()
+
+	var original string
+	if err := db.Get(&original, "SELECT email FROM users WHERE id = ?", userID); err != nil {
+		t.Fatalf("find email: %v", err)
+	}
+	abandoned := fmt.Sprintf("abandoned-%d@example.com", userID)
+	confirmed := fmt.Sprintf("confirmed-%d@example.com", userID)
+	token, expiresAt := "token", time.Now().Add(time.Hour)
+
+	if err := repo.SetPendingEmail(ctx, userID, &abandoned, &token, &expiresAt); err != nil {
+		t.Fatalf("set pending email: %v", err)
+	}
+	if err := repo.SetPendingEmail(ctx, userID, &confirmed, &token, &expiresAt); err != nil {
+		t.Fatalf("replace pending email: %v", err)
+	}
+	if ok, err := repo.ConfirmPendingEmail(ctx, userID, abandoned); err != nil || ok {
+		t.Fatalf("expected a replaced pending email not to confirm, got %v, %v", ok, err)
+	}
+	if ok, err := repo.ConfirmPendingEmail(ctx, userID, confirmed); err != nil || !ok {
+		t.Fatalf("confirm email: %v, %v", ok, err)
+	}
+
+	var history []string
+	if err := db.Select(&history, "SELECT email FROM user_email_history WHERE user_id = ? ORDER BY email", userID); err != nil {
+		t.Fatalf("read history: %v", err)
+	}
+	want := []string{abandoned, original}
+	slices.Sort(want)
+	if !slices.Equal(history, want) {
+		t.Fatalf("expected history %v, got %v", want, history)
+	}
+}
@@ -16,19 +16,31 @@ import (
 	"strings"
 	"time"
 
+	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/bcrypt"
 
 	"finlog-api/api/constants"
 	"finlog-api/api/contracts"
 	"finlog-api/api/entities"
+	"finlog-api/api/jwtkeys"
 	"finlog-api/api/seeds"
 	"finlog-api/api/services/category"
+	"finlog-api/api/services/email"
 )
 
+// Values of the typ claim, so one kind of token can never stand in for another.
 const (
-	verificationTTL = 24 * time.Hour
-	emailSubject    = "Aktivasi Akun FinLog"
+	tokenTypeAccess  = "access"
+	tokenTypeRefresh = "refresh"
+	tokenTypeMFA     = "mfa"
+)
+
+const (
+	verificationTTL  = 24 * time.Hour
+	passwordResetTTL = time.Hour
+	mfaChallengeTTL  = 5 * time.Minute
+	recoveryCodeSize = 10
+	emailSubject     = "Aktivasi Akun FinLog"
 )
 
 var (
@@ -40,6 +52,14 @@ var (
 	errVerificationTokenExpired = errors.New("verification token expired")
 	errEmailAlreadyVerified     = errors.New("email already verified")
 	errUserNotFound             = errors.New("user not found")
+	errRefreshTokenReused       = errors.New("refresh token reuse detected")
+	errResetTokenInvalid        = errors.New("invalid password reset token")
+	errResetTokenExpired        = errors.New("password reset token expired")
+	errInvalidMFACode           = errors.New("invalid mfa code")
+	errMFAAlreadyEnabled        = errors.New("mfa already enabled")
+	errMFANotEnrolled           = errors.New("mfa not enrolled")
+	errAccountPendingDeletion   = errors.New("account is scheduled for deletion")
+	errAccountDisabled          = errors.New("account is disabled")
 )
 
 func ErrInvalidCredentials() error       { return errInvalidCredentials }
@@ -50,21 +70,83 @@ func ErrVerificationTokenInvalid() error { return errVerificationTokenInvalid }
 func ErrVerificationTokenExpired() error { return errVerificationTokenExpired }
 func ErrEmailAlreadyVerified() error     { return errEmailAlreadyVerified }
 func ErrUserNotFound() error             { return errUserNotFound }
+func ErrRefreshTokenReused() error       { return errRefreshTokenReused }
+func ErrResetTokenInvalid() error        { return errResetTokenInvalid }
+func ErrResetTokenExpired() error        { return errResetTokenExpired }
+func ErrInvalidMFACode() error           { return errInvalidMFACode }
+func ErrMFAAlreadyEnabled() error        { return errMFAAlreadyEnabled }
+func ErrMFANotEnrolled() error           { return errMFANotEnrolled }
+func ErrAccountPendingDeletion() error   { return errAccountPendingDeletion }
+func ErrAccountDisabled() error          { return errAccountDisabled }
+
+// MFARequiredError is returned by Login when the password matched but the account
+// has TOTP enabled. ChallengeToken must be exchanged together with a code at LoginMFA.
+type MFARequiredError struct {
+	ChallengeToken string
+}
+
+func (e *MFARequiredError) Error() string { return "mfa required" }
 
 // Service endpoints require the application context.
 type Service struct {
-	app     *contracts.App
-	repo    contracts.AuthRepository
-	catRepo contracts.CategoryRepository
+	app      *contracts.App
+	repo     contracts.AuthRepository
+	tokens   contracts.RefreshTokenRepository
+	mfa      contracts.MFARepository
+	passkeys contracts.PasskeyRepository
+	sessions contracts.SessionRepository
+	catRepo  contracts.CategoryRepository
+	webauthn *webauthn.WebAuthn
+
+	passwords passwordHasher
+	policy    passwordPolicy
+
+	throttles contracts.LoginThrottleRepository
+	throttle  throttlePolicy
+
+	oidcRepo contracts.OIDCRepository
+	oidc     map[string]*oidcProvider
+
+	magicLinks contracts.MagicLinkRepository
 }
 
 func Init(app *contracts.App) contracts.AuthService {
 	repo := initRepository(app)
 
+	wa, err := newWebAuthn(app.Config)
+	if err != nil {
+		app.Logger.Warn().
+			Err(err).
+			Msg("passkeys_disabled")
+	}
+
+	policy, err := loadPasswordPolicy(app.Config)
+	if err != nil {
+		app.Logger.Warn().
+			Err(err).
+			Msg("breached_password_check_disabled")
+	}
+
 	return &Service{
-		app:     app,
-		repo:    repo,
-		catRepo: category.NewRepository(app),
+		app:      app,
+		repo:     repo,
+		tokens:   repo,
+		mfa:      repo,
+		passkeys: repo,
+		sessions: repo,
+		catRepo:  category.NewRepository(app),
+		webauthn: wa,
+
+		passwords: parsePasswordHasher(app.Config),
+		policy:    policy,
+
+		throttles: repo,
+		throttle:  parseThrottlePolicy(app.Config),
+
+		oidcRepo: repo,
+		oidc:     newOIDCProviders(app.Config),
+
+		magicLinks: repo,
 	}
 }
 
@@ -74,37 +156,253 @@ func (s *Service) Login(ctx context.Context, email, password string) (string, st
 	if err := validateCredentials(email, password); err != nil {
 		return "", "", nil, errInvalidCredentials
 	}
+	if err := s.checkLoginAllowed(ctx, email); err != nil {
+		return "", "", nil, err
+	}
 
 	user, err := s.repo.FindByEmail(ctx, email)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
+			s.recordLoginFailure(ctx, email, nil)
 			return "", "", nil, errInvalidCredentials
 		}
 		return "", "", nil, err
 	}
-	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) != nil {
+	matched, needsRehash := s.passwords.verify(user.Password, password)
+	if !matched {
+		s.recordLoginFailure(ctx, email, user)
 		return "", "", nil, errInvalidCredentials
 	}
+	if needsRehash {
+		s.rehashPassword(ctx, user, password)
+	}
 	if !user.IsVerified {
+		s.app.Services.Audit.Record(ctx, user.ID, constants.AuditLogin, constants.AuditFailure, "email_not_verified")
 		return "", "", nil, errEmailNotVerified
 	}
+	if user.DeletionRequestedAt != nil {
+		s.app.Services.Audit.Record(ctx, user.ID, constants.AuditLogin, constants.AuditFailure, "pending_deletion")
+		return "", "", nil, errAccountPendingDeletion
+	}
+	if user.DisabledAt != nil {
+		s.app.Services.Audit.Record(ctx, user.ID, constants.AuditLogin, constants.AuditFailure, "disabled")
+		return "", "", nil, errAccountDisabled
+	}
+	if user.MFAEnabled {
+		challenge, err := s.generateMFAChallenge(user)
+		if err != nil {
+			return "", "", nil, err
+		}
+		return "", "", nil, &MFARequiredError{ChallengeToken: challenge}
+	}
 
-	return s.issueTokens(user)
+	// MFA accounts keep their counter until the second factor succeeds.
+	s.clearLoginFailures(ctx, email)
+	return s.issueTokens(ctx, user, "")
+}
+
+// LoginMFA completes a login started by Login using a TOTP or recovery code.
+func (s *Service) LoginMFA(ctx context.Context, challengeToken, code string) (string, string, *entities.User, error) {
+	claims, err := s.parseToken(challengeToken, jwtkeys.PurposeAccess, tokenTypeMFA)
+	if err != nil {
+		return "", "", nil, errInvalidCredentials
+	}
+	sub, ok := claims["sub"].(float64)
+	if !ok {
+		return "", "", nil, errInvalidCredentials
+	}
+	user, err := s.repo.FindByID(ctx, int64(sub))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", "", nil, errInvalidCredentials
+		}
+		return "", "", nil, err
+	}
+	if !user.MFAEnabled {
+		return "", "", nil, errInvalidCredentials
+	}
+	if err := s.checkLoginAllowed(ctx, user.Email); err != nil {
+		return "", "", nil, err
+	}
+	if err := s.verifyMFACode(ctx, user, code, true); err != nil {
+		if errors.Is(err, errInvalidMFACode) {
+			s.recordLoginFailure(ctx, user.Email, user)
+		}
+		return "", "", nil, err
+	}
+	s.clearLoginFailures(ctx, user.Email)
+	return s.issueTokens(ctx, user, "")
+}
+
+// EnrollMFA generates a new TOTP secret and returns it with its otpauth:// URI.
+// The secret only takes effect once confirmed with ConfirmMFA.
+func (s *Service) EnrollMFA(ctx context.Context, userID int64) (string, string, error) {
+	user, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", "", errUserNotFound
+		}
+		return "", "", err
+	}
+	if user.MFAEnabled {
+		return "", "", errMFAAlreadyEnabled
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return "", "", err
+	}
+	if err := s.mfa.SetMFASecret(ctx, userID, &secret); err != nil {
+		return "", "", err
+	}
+	return secret, totpProvisioningURI(user.Email, secret), nil
+}
+
+// ConfirmMFA enables MFA after the first valid code and returns fresh recovery codes.
+// The codes are only ever shown here; the server keeps their hashes.
+func (s *Service) ConfirmMFA(ctx context.Context, userID int64, code string) ([]string, error) {
+	user, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errUserNotFound
+		}
+		return nil, err
+	}
+	if user.MFAEnabled {
+		return nil, errMFAAlreadyEnabled
+	}
+	if user.MFASecret == nil {
+		return nil, errMFANotEnrolled
+	}
+	step, ok := validateTOTP(*user.MFASecret, strings.TrimSpace(code), time.Now(), 0)
+	if !ok {
+		return nil, errInvalidMFACode
+	}
+
+	codes, err := generateRecoveryCodes(recoveryCodeSize)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, c := range codes {
+		hashes[i] = hashToken(normalizeRecoveryCode(c))
+	}
+	if err := s.mfa.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	if err := s.mfa.EnableMFA(ctx, userID, step); err != nil {
+		return nil, err
+	}
+	s.app.Services.Audit.Record(ctx, userID, constants.AuditMFAEnabled, constants.AuditSuccess, "")
+	return codes, nil
+}
+
+// DisableMFA turns MFA off. Both the password and a current code are required.
+func (s *Service) DisableMFA(ctx context.Context, userID int64, password, code string) error {
+	user, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errUserNotFound
+		}
+		return err
+	}
+	if !s.checkPassword(user, password) {
+		return errInvalidCredentials
+	}
+	if !user.MFAEnabled {
+		return errMFANotEnrolled
+	}
+	if err := s.verifyMFACode(ctx, user, code, true); err != nil {
+		return err
+	}
+	if err := s.mfa.DisableMFA(ctx, userID); err != nil {
+		return err
+	}
+	s.app.Services.Audit.Record(ctx, userID, constants.AuditMFADisabled, constants.AuditSuccess, "")
+	return nil
+}
+
+// Reauthenticate confirms a sensitive action with the current password or, when
+// MFA is enabled, a TOTP or recovery code.
+func (s *Service) Reauthenticate(ctx context.Context, userID int64, password, code string) error {
+	user, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errUserNotFound
+		}
+		return err
+	}
+	if password != "" {
+		if !s.checkPassword(user, password) {
+			return errInvalidCredentials
+		}
+		return nil
+	}
+	if code != "" && user.MFAEnabled {
+		return s.verifyMFACode(ctx, user, code, true)
+	}
+	return errInvalidCredentials
+}
+
+// verifyMFACode accepts a TOTP code or, when allowRecovery is set, a recovery code.
+// Accepted codes are consumed so they cannot be replayed.
+func (s *Service) verifyMFACode(ctx context.Context, user *entities.User, code string, allowRecovery bool) error {
+	code = strings.TrimSpace(code)
+	if user.MFASecret == nil || code == "" {
+		return errInvalidMFACode
+	}
+
+	if len(code) == totpDigits {
+		var lastStep int64
+		if user.MFALastStep != nil {
+			lastStep = *user.MFALastStep
+		}
+		step, ok := validateTOTP(*user.MFASecret, code, time.Now(), lastStep)
+		if !ok {
+			return errInvalidMFACode
+		}
+		accepted, err := s.mfa.UpdateMFALastStep(ctx, user.ID, step)
+		if err != nil {
+			return err
+		}
+		if !accepted {
+			return errInvalidMFACode
+		}
+		return nil
+	}
+
+	if !allowRecovery {
+		return errInvalidMFACode
+	}
+	used, err := s.mfa.UseRecoveryCode(ctx, user.ID, hashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return errInvalidMFACode
+	}
+	s.app.Logger.Info().
+		Int64("user_id", user.ID).
+		Msg("mfa_recovery_code_used")
+	return nil
 }
 
 // Register creates a new user account.
 func (s *Service) Register(ctx context.Context, email, password string) (*entities.User, error) {
 	email = normalizeEmail(email)
-	if err := validateCredentials(email, password); err != nil {
+	if _, err := mail.ParseAddress(email); err != nil {
 		return nil, errInvalidInput
 	}
+	if err := s.checkPasswordPolicy(password, email); err != nil {
+		return nil, err
+	}
 	if _, err := s.repo.FindByEmail(ctx, email); err == nil {
 		return nil, errEmailExists
 	} else if !errors.Is(err, sql.ErrNoRows) {
 		return nil, err
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hashedPassword, err := s.passwords.hash(password)
 	if err != nil {
 		return nil, err
 	}
@@ -117,8 +415,8 @@ func (s *Service) Register(ctx context.Context, email, password string) (*entiti
 	user := &entities.User{
 		Email:                 email,
 		Name:                  defaultName(email),
-		Role:                  "user",
-		Password:              string(hashedPassword),
+		Role:                  constants.RoleUser,
+		Password:              hashedPassword,
 		IsVerified:            false,
 		VerificationToken:     &hashedToken,
 		VerificationExpiresAt: &expiresAt,
@@ -138,47 +436,80 @@ func (s *Service) Register(ctx context.Context, email, password string) (*entiti
 	return user, nil
 }
 
-// Refresh renews tokens using a refresh token.
+// Refresh rotates a refresh token. Every refresh token is single-use; presenting one
+// that was already rotated means it leaked, so the whole login family is revoked.
 func (s *Service) Refresh(ctx context.Context, refreshToken string) (string, string, *entities.User, error) {
 	refreshToken = strings.TrimSpace(refreshToken)
 	if refreshToken == "" {
 		return "", "", nil, errInvalidCredentials
 	}
-	token, err := jwt.Parse(refreshToken, func(t *jwt.Token) (interface{}, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errInvalidCredentials
-		}
-		return []byte(s.app.Config[constants.REFRESH_SECRET]), nil
-	})
-	if err != nil || !token.Valid {
+	claims, err := s.parseToken(refreshToken, jwtkeys.PurposeRefresh, tokenTypeRefresh)
+	if err != nil {
 		return "", "", nil, errInvalidCredentials
 	}
-
-	claims, ok := token.Claims.(jwt.MapClaims)
+	sub, ok := claims["sub"].(float64)
 	if !ok {
 		return "", "", nil, errInvalidCredentials
 	}
-	sub, ok := claims["sub"].(float64)
-	if !ok {
+
+	stored, err := s.tokens.FindRefreshTokenByHash(ctx, hashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", "", nil, errInvalidCredentials
+		}
+		return "", "", nil, err
+	}
+	if stored.UserID != int64(sub) || stored.RevokedAt != nil {
 		return "", "", nil, errInvalidCredentials
 	}
-	user, err := s.repo.FindByID(ctx, int64(sub))
+	if stored.RotatedAt != nil {
+		return "", "", nil, s.revokeReusedFamily(ctx, stored)
+	}
+	if stored.ExpiresAt.Before(time.Now()) {
+		return "", "", nil, errInvalidCredentials
+	}
+
+	rotated, err := s.tokens.MarkRefreshTokenRotated(ctx, stored.ID)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if !rotated {
+		// Lost a race against another request presenting the same token.
+		return "", "", nil, s.revokeReusedFamily(ctx, stored)
+	}
+
+	user, err := s.repo.FindByID(ctx, stored.UserID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return "", "", nil, errInvalidCredentials
 		}
 		return "", "", nil, err
 	}
-	return s.issueTokens(user)
+	return s.issueTokens(ctx, user, stored.FamilyID)
 }
 
-// Logout keeps stateless JWT flow but allows future revocation hook.
-func (s *Service) Logout(ctx context.Context, userID int64) error {
-	_ = userID
+// Logout revokes the refresh token family of the current login.
+func (s *Service) Logout(ctx context.Context, userID int64, familyID string) error {
+	if familyID == "" {
+		return nil
+	}
+	if err := s.tokens.RevokeRefreshTokenFamily(ctx, userID, familyID); err != nil {
+		return err
+	}
+	s.app.Services.Audit.Record(ctx, userID, constants.AuditLogout, constants.AuditSuccess, "")
 	return nil
 }
 
-// VerifyEmail completes account activation.
+// LogoutAll revokes every refresh token family of the user.
+func (s *Service) LogoutAll(ctx context.Context, userID int64) error {
+	if err := s.tokens.RevokeUserRefreshTokens(ctx, userID); err != nil {
+		return err
+	}
+	s.app.Services.Audit.Record(ctx, userID, constants.AuditLogoutAll, constants.AuditSuccess, "")
+	return nil
+}
+
+// VerifyEmail completes account activation, or a pending email change.
 func (s *Service) VerifyEmail(ctx context.Context, token string) (*entities.User, error) {
 	hashed := hashToken(token)
 	user, err := s.repo.FindByVerificationToken(ctx, hashed)
@@ -188,6 +519,9 @@ func (s *Service) VerifyEmail(ctx context.Context, token string) (*entities.User
 		}
 		return nil, err
 	}
+	if user.PendingEmail != nil {
+		return s.confirmEmailChange(ctx, user)
+	}
 	if user.IsVerified {
 		return nil, errEmailAlreadyVerified
 	}
@@ -197,6 +531,7 @@ func (s *Service) VerifyEmail(ctx context.Context, token string) (*entities.User
 	if err := s.repo.MarkUserAsVerified(ctx, user.ID); err != nil {
 		return nil, err
 	}
+	s.app.Services.Audit.Record(ctx, user.ID, constants.AuditEmailVerified, constants.AuditSuccess, "")
 	user.IsVerified = true
 	user.VerificationToken = nil
 	user.VerificationExpiresAt = nil
@@ -230,36 +565,280 @@ func (s *Service) ResendVerification(ctx context.Context, email string) error {
 	return s.sendVerificationEmail(user, rawToken)
 }
 
-func (s *Service) issueTokens(user *entities.User) (string, string, *entities.User, error) {
+// RequestPasswordReset emails a single-use reset link. The outcome is the same whether
+// or not the email is registered, so callers cannot probe for accounts.
+func (s *Service) RequestPasswordReset(ctx context.Context, email string) error {
+	email = normalizeEmail(email)
+	user, err := s.repo.FindByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	rawToken, hashedToken, expiresAt, err := s.prepareToken(passwordResetTTL)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.UpdatePasswordResetToken(ctx, user.ID, &hashedToken, &expiresAt); err != nil {
+		return err
+	}
+
+	// Sending retries for several seconds; doing it inline would leak which emails exist.
+	go s.sendPasswordResetEmail(user, rawToken)
+	return nil
+}
+
+// ResetPassword sets a new password using a reset token and signs out every session.
+func (s *Service) ResetPassword(ctx context.Context, token, newPassword string) error {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return errResetTokenInvalid
+	}
+	hashed := hashToken(token)
+	user, err := s.repo.FindByPasswordResetToken(ctx, hashed)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errResetTokenInvalid
+		}
+		return err
+	}
+	if user.PasswordResetExpiresAt == nil || user.PasswordResetExpiresAt.Before(time.Now()) {
+		return errResetTokenExpired
+	}
+	if err := s.checkPasswordPolicy(newPassword, user.Email); err != nil {
+		return err
+	}
+
+	hashedPassword, err := s.passwords.hash(newPassword)
+	if err != nil {
+		return err
+	}
+	ok, err := s.repo.ResetPassword(ctx, user.ID, hashed, hashedPassword)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errResetTokenInvalid
+	}
+	s.app.Services.Audit.Record(ctx, user.ID, constants.AuditPasswordReset, constants.AuditSuccess, "")
+
+	return s.tokens.RevokeUserRefreshTokens(ctx, user.ID)
+}
+
+// ChangePassword replaces the password after checking the current one. Other logins
+// are signed out; the login identified by familyID stays active.
+func (s *Service) ChangePassword(ctx context.Context, userID int64, familyID, currentPassword, newPassword string) error {
+	user, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errUserNotFound
+		}
+		return err
+	}
+	if !s.checkPassword(user, currentPassword) {
+		s.app.Services.Audit.Record(ctx, userID, constants.AuditPasswordChanged, constants.AuditFailure, "invalid_credentials")
+		return errInvalidCredentials
+	}
+	if err := s.checkPasswordPolicy(newPassword, user.Email); err != nil {
+		return err
+	}
+
+	hashedPassword, err := s.passwords.hash(newPassword)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.UpdatePassword(ctx, userID, hashedPassword); err != nil {
+		return err
+	}
+	s.app.Services.Audit.Record(ctx, userID, constants.AuditPasswordChanged, constants.AuditSuccess, "")
+	return s.tokens.RevokeOtherRefreshTokenFamilies(ctx, userID, familyID)
+}
+
+// ChangeEmail stores newEmail as pending and sends a verification link to it. The
+// account email only changes once the link is opened.
+func (s *Service) ChangeEmail(ctx context.Context, userID int64, newEmail, password string) error {
+	newEmail = normalizeEmail(newEmail)
+	if _, err := mail.ParseAddress(newEmail); err != nil {
+		return errInvalidInput
+	}
+
+	user, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errUserNotFound
+		}
+		return err
+	}
+	if !s.checkPassword(user, password) {
+		return errInvalidCredentials
+	}
+	if newEmail == user.Email {
+		return errInvalidInput
+	}
+	if _, err := s.repo.FindByEmail(ctx, newEmail); err == nil {
+		return errEmailExists
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	rawToken, hashedToken, expiresAt, err := s.prepareVerificationToken()
+	if err != nil {
+		return err
+	}
+	if err := s.repo.SetPendingEmail(ctx, userID, &newEmail, &hashedToken, &expiresAt); err != nil {
+		return err
+	}
+
+	recipient := *user
+	recipient.Email = newEmail
+	return s.sendVerificationEmail(&recipient, rawToken)
+}
+
+func (s *Service) confirmEmailChange(ctx context.Context, user *entities.User) (*entities.User, error) {
+	if user.VerificationExpiresAt == nil || user.VerificationExpiresAt.Before(time.Now()) {
+		return nil, errVerificationTokenExpired
+	}
+	newEmail := *user.PendingEmail
+	if existing, err := s.repo.FindByEmail(ctx, newEmail); err == nil && existing.ID != user.ID {
+		return nil, errEmailExists
+	} else if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	ok, err := s.repo.ConfirmPendingEmail(ctx, user.ID, newEmail)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errVerificationTokenInvalid
+	}
+
+	s.app.Services.Audit.Record(ctx, user.ID, constants.AuditEmailChanged, constants.AuditSuccess, "")
+	oldEmail := user.Email
+	user.Email = newEmail
+	user.PendingEmail = nil
+	user.VerificationToken = nil
+	user.VerificationExpiresAt = nil
+
+	go s.sendEmailChangedNotice(user.Name, oldEmail, newEmail)
+	return user, nil
+}
+
+func (s *Service) issueTokens(ctx context.Context, user *entities.User, familyID string) (string, string, *entities.User, error) {
+	if user.DeletionRequestedAt != nil {
+		return "", "", nil, errAccountPendingDeletion
+	}
+	if user.DisabledAt != nil {
+		return "", "", nil, errAccountDisabled
+	}
 	if user.Role == "" {
-		user.Role = "user"
+		user.Role = constants.RoleUser
+	}
+	auditAction := constants.AuditTokenRefresh
+	if familyID == "" {
+		auditAction = constants.AuditLogin
+		generated, err := generateRandomToken()
+		if err != nil {
+			return "", "", nil, err
+		}
+		familyID = generated
 	}
 
-	access, err := s.generateToken(user, []byte(s.app.Config[constants.JWT_SECRET]), mustDuration(s.app.Config[constants.JWT_TTL], time.Hour))
+	refreshTTL := mustDuration(s.app.Config[constants.REFRESH_TTL], 7*24*time.Hour)
+	access, err := s.generateToken(user, familyID, tokenTypeAccess, jwtkeys.PurposeAccess, mustDuration(s.app.Config[constants.JWT_TTL], time.Hour))
 	if err != nil {
 		return "", "", nil, err
 	}
-	refresh, err := s.generateToken(user, []byte(s.app.Config[constants.REFRESH_SECRET]), mustDuration(s.app.Config[constants.REFRESH_TTL], 7*24*time.Hour))
+	refresh, err := s.generateToken(user, familyID, tokenTypeRefresh, jwtkeys.PurposeRefresh, refreshTTL)
 	if err != nil {
 		return "", "", nil, err
 	}
+	if _, err := s.tokens.CreateRefreshToken(ctx, &entities.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(refresh),
+		ExpiresAt: time.Now().UTC().Add(refreshTTL),
+	}); err != nil {
+		return "", "", nil, err
+	}
+	if err := s.recordSession(ctx, user.ID, familyID); err != nil {
+		return "", "", nil, err
+	}
+	s.app.Services.Audit.Record(ctx, user.ID, auditAction, constants.AuditSuccess, "")
+
 	safeUser := *user
 	safeUser.Password = ""
 	safeUser.VerificationToken = nil
 	safeUser.VerificationExpiresAt = nil
+	safeUser.PasswordResetToken = nil
+	safeUser.PasswordResetExpiresAt = nil
+	safeUser.MFASecret = nil
+	safeUser.MFALastStep = nil
+	safeUser.DeletionCancelToken = nil
 	return access, refresh, &safeUser, nil
 }
 
-func (s *Service) generateToken(user *entities.User, secret []byte, ttl time.Duration) (string, error) {
+func (s *Service) generateToken(user *entities.User, familyID, tokenType string, purpose jwtkeys.Purpose, ttl time.Duration) (string, error) {
+	jti, err := generateRandomToken()
+	if err != nil {
+		return "", err
+	}
 	claims := jwt.MapClaims{
 		"sub":   user.ID,
 		"email": user.Email,
 		"name":  user.Name,
 		"role":  user.Role,
+		"fam":   familyID,
+		"typ":   tokenType,
+		"jti":   jti,
 		"exp":   time.Now().Add(ttl).Unix(),
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(secret)
+	return s.app.JWTKeys.Sign(claims, purpose)
+}
+
+// generateMFAChallenge issues the short-lived token handed out between the password
+// and the second factor. Its typ claim keeps it from being used as an access token.
+func (s *Service) generateMFAChallenge(user *entities.User) (string, error) {
+	jti, err := generateRandomToken()
+	if err != nil {
+		return "", err
+	}
+	claims := jwt.MapClaims{
+		"sub": user.ID,
+		"typ": tokenTypeMFA,
+		"jti": jti,
+		"exp": time.Now().Add(mfaChallengeTTL).Unix(),
+	}
+	return s.app.JWTKeys.Sign(claims, jwtkeys.PurposeAccess)
+}
+
+// parseToken verifies a token against the keyset and checks that its typ claim matches.
+func (s *Service) parseToken(raw string, purpose jwtkeys.Purpose, tokenType string) (jwt.MapClaims, error) {
+	claims, err := s.app.JWTKeys.Parse(strings.TrimSpace(raw), purpose)
+	if err != nil {
+		return nil, errInvalidCredentials
+	}
+	if typ, _ := claims["typ"].(string); typ != tokenType {
+		return nil, errInvalidCredentials
+	}
+	return claims, nil
+}
+
+// revokeReusedFamily kills a login family after a rotated refresh token was replayed.
+func (s *Service) revokeReusedFamily(ctx context.Context, stored *entities.RefreshToken) error {
+	if err := s.tokens.RevokeRefreshTokenFamily(ctx, stored.UserID, stored.FamilyID); err != nil {
+		return err
+	}
+	if s.app.Logger != nil {
+		s.app.Logger.Warn().
+			Int64("user_id", stored.UserID).
+			Int64("refresh_token_id", stored.ID).
+			Msg("refresh_token_reuse_detected")
+	}
+	s.app.Services.Audit.Record(ctx, stored.UserID, constants.AuditTokenRefresh, constants.AuditFailure, "reuse_detected")
+	return errRefreshTokenReused
 }
 
 func mustDuration(v string, fallback time.Duration) time.Duration {
@@ -284,6 +863,10 @@ func validateCredentials(email, password string) error {
 	if _, err := mail.ParseAddress(email); err != nil {
 		return errInvalidCredentials
 	}
+	return validatePassword(password)
+}
+
+func validatePassword(password string) error {
 	if len(password) < 6 {
 		return errInvalidCredentials
 	}
@@ -315,12 +898,17 @@ func (s *Service) seedDefaultCategories(ctx context.Context, userID int64) {
 }
 
 func (s *Service) prepareVerificationToken() (string, string, time.Time, error) {
+	return s.prepareToken(verificationTTL)
+}
+
+// prepareToken returns a raw token for the email link and the hash to store.
+func (s *Service) prepareToken(ttl time.Duration) (string, string, time.Time, error) {
 	raw, err := generateRandomToken()
 	if err != nil {
 		return "", "", time.Time{}, err
 	}
 	hashed := hashToken(raw)
-	expiresAt := time.Now().UTC().Add(verificationTTL)
+	expiresAt := time.Now().UTC().Add(ttl)
 	return raw, hashed, expiresAt, nil
 }
 
@@ -358,11 +946,44 @@ func (s *Service) sendVerificationEmail(user *entities.User, token string) error
 	return nil
 }
 
+func (s *Service) sendPasswordResetEmail(user *entities.User, token string) {
+	variables := map[string]interface{}{
+		"name": user.Name,
+		"link": s.buildURL("/reset-password", token),
+	}
+
+	if err := s.app.Services.Email.SendTemplate(user.Email, email.TemplatePasswordReset, variables); err != nil {
+		s.app.Logger.Error().
+			Err(err).
+			Str("email", user.Email).
+			Msg("password_reset_email_failed")
+	}
+}
+
+// sendEmailChangedNotice tells the previous address that the account email moved.
+func (s *Service) sendEmailChangedNotice(name, oldEmail, newEmail string) {
+	variables := map[string]interface{}{
+		"name":      name,
+		"new_email": newEmail,
+	}
+
+	if err := s.app.Services.Email.SendTemplate(oldEmail, email.TemplateEmailChanged, variables); err != nil {
+		s.app.Logger.Error().
+			Err(err).
+			Str("email", oldEmail).
+			Msg("email_changed_notice_failed")
+	}
+}
+
 func (s *Service) buildActivationURL(token string) string {
+	return s.buildURL("/v1/auth/verify", token)
+}
+
+func (s *Service) buildURL(path, token string) string {
 	baseURL := strings.TrimRight(strings.TrimSpace(s.app.Config[constants.APIBaseURL]), "/")
 	if baseURL == "" {
 		baseURL = "https://api.finlog.app"
 	}
 	escaped := url.QueryEscape(token)
-	return fmt.Sprintf("%s/v1/auth/verify?token=%s", baseURL, escaped)
+	return fmt.Sprintf("%s%s?token=%s", baseURL, path, escaped)
 }
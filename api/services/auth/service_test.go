@@ -2,21 +2,35 @@ package auth
 
 import (
 	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
 	"database/sql"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
+	"strconv"
+	"sync"
 	"testing"
 	"time"
 
+	"finlog-api/api/constants"
 	"finlog-api/api/contracts"
 	"finlog-api/api/entities"
+	"finlog-api/api/helpers"
+	"finlog-api/api/jwtkeys"
+	"finlog-api/api/models/request"
+	"finlog-api/api/services/email"
 
+	"github.com/rs/zerolog"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type fakeRepo struct {
-	usersByEmail map[string]*entities.User
-	usersByID    map[int64]*entities.User
-	nextID       int64
+	usersByEmail  map[string]*entities.User
+	usersByID     map[int64]*entities.User
+	recoveryCodes map[string]bool
+	nextID        int64
 }
 
 func newFakeRepo(user *entities.User) *fakeRepo {
@@ -64,9 +78,401 @@ func (f *fakeRepo) UpdateVerificationToken(ctx context.Context, userID int64, to
 }
 
 func (f *fakeRepo) MarkUserAsVerified(ctx context.Context, userID int64) error {
+	if u, ok := f.usersByID[userID]; ok {
+		u.IsVerified = true
+	}
+	return nil
+}
+
+func (f *fakeRepo) UpdatePasswordResetToken(ctx context.Context, userID int64, token *string, expiresAt *time.Time) error {
+	if u, ok := f.usersByID[userID]; ok {
+		u.PasswordResetToken = token
+		u.PasswordResetExpiresAt = expiresAt
+	}
+	return nil
+}
+
+func (f *fakeRepo) FindByPasswordResetToken(ctx context.Context, token string) (*entities.User, error) {
+	for _, u := range f.usersByID {
+		if u.PasswordResetToken != nil && *u.PasswordResetToken == token {
+			return u, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeRepo) ResetPassword(ctx context.Context, userID int64, token string, hashedPassword string) (bool, error) {
+	u, ok := f.usersByID[userID]
+	if !ok || u.PasswordResetToken == nil || *u.PasswordResetToken != token {
+		return false, nil
+	}
+	u.Password = hashedPassword
+	u.PasswordResetToken = nil
+	u.PasswordResetExpiresAt = nil
+	return true, nil
+}
+
+func (f *fakeRepo) UpdatePassword(ctx context.Context, userID int64, hashedPassword string) error {
+	if u, ok := f.usersByID[userID]; ok {
+		u.Password = hashedPassword
+	}
+	return nil
+}
+
+func (f *fakeRepo) SetPendingEmail(ctx context.Context, userID int64, email *string, token *string, expiresAt *time.Time) error {
+	if u, ok := f.usersByID[userID]; ok {
+		u.PendingEmail = email
+		u.VerificationToken = token
+		u.VerificationExpiresAt = expiresAt
+	}
+	return nil
+}
+
+func (f *fakeRepo) ConfirmPendingEmail(ctx context.Context, userID int64, email string) (bool, error) {
+	u, ok := f.usersByID[userID]
+	if !ok || u.PendingEmail == nil || *u.PendingEmail != email {
+		return false, nil
+	}
+	delete(f.usersByEmail, u.Email)
+	u.Email = email
+	u.PendingEmail = nil
+	f.usersByEmail[email] = u
+	return true, nil
+}
+
+func (f *fakeRepo) SetMFASecret(ctx context.Context, userID int64, secret *string) error {
+	if u, ok := f.usersByID[userID]; ok {
+		u.MFASecret = secret
+		u.MFAEnabled = false
+		u.MFALastStep = nil
+	}
+	return nil
+}
+
+func (f *fakeRepo) EnableMFA(ctx context.Context, userID int64, step int64) error {
+	if u, ok := f.usersByID[userID]; ok {
+		u.MFAEnabled = true
+		u.MFALastStep = &step
+	}
+	return nil
+}
+
+func (f *fakeRepo) DisableMFA(ctx context.Context, userID int64) error {
+	if u, ok := f.usersByID[userID]; ok {
+		u.MFAEnabled = false
+		u.MFASecret = nil
+		u.MFALastStep = nil
+	}
+	f.recoveryCodes = nil
+	return nil
+}
+
+func (f *fakeRepo) UpdateMFALastStep(ctx context.Context, userID int64, step int64) (bool, error) {
+	u, ok := f.usersByID[userID]
+	if !ok || (u.MFALastStep != nil && *u.MFALastStep >= step) {
+		return false, nil
+	}
+	u.MFALastStep = &step
+	return true, nil
+}
+
+func (f *fakeRepo) ReplaceRecoveryCodes(ctx context.Context, userID int64, codeHashes []string) error {
+	f.recoveryCodes = map[string]bool{}
+	for _, h := range codeHashes {
+		f.recoveryCodes[h] = false
+	}
+	return nil
+}
+
+func (f *fakeRepo) UseRecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error) {
+	used, ok := f.recoveryCodes[codeHash]
+	if !ok || used {
+		return false, nil
+	}
+	f.recoveryCodes[codeHash] = true
+	return true, nil
+}
+
+// fakeTokenRepo also tracks sessions, mirroring the repository where revoking a
+// token family revokes its session in the same transaction.
+type fakeTokenRepo struct {
+	tokens   map[string]*entities.RefreshToken
+	sessions map[string]*entities.Session
+	nextID   int64
+}
+
+func newFakeTokenRepo() *fakeTokenRepo {
+	return &fakeTokenRepo{
+		tokens:   map[string]*entities.RefreshToken{},
+		sessions: map[string]*entities.Session{},
+	}
+}
+
+func (f *fakeTokenRepo) CreateRefreshToken(ctx context.Context, token *entities.RefreshToken) (int64, error) {
+	f.nextID++
+	token.ID = f.nextID
+	f.tokens[token.TokenHash] = token
+	return token.ID, nil
+}
+
+func (f *fakeTokenRepo) FindRefreshTokenByHash(ctx context.Context, tokenHash string) (*entities.RefreshToken, error) {
+	if t, ok := f.tokens[tokenHash]; ok {
+		copied := *t
+		return &copied, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeTokenRepo) MarkRefreshTokenRotated(ctx context.Context, id int64) (bool, error) {
+	for _, t := range f.tokens {
+		if t.ID == id && t.RotatedAt == nil && t.RevokedAt == nil {
+			now := time.Now()
+			t.RotatedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *fakeTokenRepo) RevokeRefreshTokenFamily(ctx context.Context, userID int64, familyID string) error {
+	f.revoke(userID, func(family string) bool { return family == familyID })
+	return nil
+}
+
+func (f *fakeTokenRepo) RevokeUserRefreshTokens(ctx context.Context, userID int64) error {
+	f.revoke(userID, func(string) bool { return true })
+	return nil
+}
+
+func (f *fakeTokenRepo) RevokeOtherRefreshTokenFamilies(ctx context.Context, userID int64, keepFamilyID string) error {
+	f.revoke(userID, func(family string) bool { return family != keepFamilyID })
+	return nil
+}
+
+func (f *fakeTokenRepo) revoke(userID int64, match func(family string) bool) {
+	now := time.Now()
+	for _, t := range f.tokens {
+		if t.UserID == userID && match(t.FamilyID) && t.RevokedAt == nil {
+			t.RevokedAt = &now
+		}
+	}
+	for _, s := range f.sessions {
+		if s.UserID == userID && match(s.FamilyID) && s.RevokedAt == nil {
+			s.RevokedAt = &now
+		}
+	}
+}
+
+func (f *fakeTokenRepo) UpsertSession(ctx context.Context, session *entities.Session) error {
+	if existing, ok := f.sessions[session.FamilyID]; ok {
+		existing.LastSeenAt = session.LastSeenAt
+		return nil
+	}
+	f.nextID++
+	copied := *session
+	copied.ID = f.nextID
+	f.sessions[session.FamilyID] = &copied
 	return nil
 }
 
+func (f *fakeTokenRepo) TouchSession(ctx context.Context, id int64, seenAt time.Time, client entities.ClientInfo) error {
+	for _, s := range f.sessions {
+		if s.ID == id {
+			s.LastSeenAt = seenAt
+		}
+	}
+	return nil
+}
+
+func (f *fakeTokenRepo) FindSessionByFamily(ctx context.Context, familyID string) (*entities.Session, error) {
+	if s, ok := f.sessions[familyID]; ok {
+		copied := *s
+		return &copied, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeTokenRepo) FindSession(ctx context.Context, userID, id int64) (*entities.Session, error) {
+	for _, s := range f.sessions {
+		if s.ID == id && s.UserID == userID {
+			copied := *s
+			return &copied, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeTokenRepo) ListActiveSessions(ctx context.Context, userID int64, seenSince time.Time) ([]entities.Session, error) {
+	var out []entities.Session
+	for _, s := range f.sessions {
+		if s.UserID == userID && s.RevokedAt == nil && !s.LastSeenAt.Before(seenSince) {
+			out = append(out, *s)
+		}
+	}
+	return out, nil
+}
+
+type fakeThrottleRepo struct {
+	throttles map[string]*entities.LoginThrottle
+}
+
+func newFakeThrottleRepo() *fakeThrottleRepo {
+	return &fakeThrottleRepo{throttles: map[string]*entities.LoginThrottle{}}
+}
+
+func (f *fakeThrottleRepo) FindLoginThrottle(ctx context.Context, scope, key string) (*entities.LoginThrottle, error) {
+	if t, ok := f.throttles[scope+":"+key]; ok {
+		copied := *t
+		return &copied, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeThrottleRepo) RecordLoginFailure(ctx context.Context, scope, key string, at, resetBefore time.Time) (int, error) {
+	t, ok := f.throttles[scope+":"+key]
+	if !ok {
+		t = &entities.LoginThrottle{Scope: scope, ScopeKey: key}
+		f.throttles[scope+":"+key] = t
+	}
+	if t.LastFailureAt.Before(resetBefore) {
+		t.Failures = 0
+	}
+	t.Failures++
+	t.LastFailureAt = at
+	return t.Failures, nil
+}
+
+func (f *fakeThrottleRepo) LockLogin(ctx context.Context, scope, key string, until time.Time, unlockUserID *int64, unlockToken *string, unlockExpiresAt *time.Time) error {
+	if t, ok := f.throttles[scope+":"+key]; ok {
+		t.LockedUntil = &until
+		if unlockToken != nil {
+			t.UnlockUserID, t.UnlockToken, t.UnlockExpiresAt = unlockUserID, unlockToken, unlockExpiresAt
+		}
+	}
+	return nil
+}
+
+func (f *fakeThrottleRepo) ClearLoginThrottle(ctx context.Context, scope, key string) error {
+	delete(f.throttles, scope+":"+key)
+	return nil
+}
+
+func (f *fakeThrottleRepo) UnlockLoginByToken(ctx context.Context, tokenHash string, now time.Time) (int64, bool, error) {
+	for k, t := range f.throttles {
+		if t.UnlockToken != nil && *t.UnlockToken == tokenHash && t.UnlockExpiresAt.After(now) {
+			delete(f.throttles, k)
+			return *t.UnlockUserID, true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// fakeAudit keeps recorded events in memory.
+type fakeAudit struct {
+	mu     sync.Mutex
+	events []entities.AuditEvent
+}
+
+func (f *fakeAudit) Record(ctx context.Context, userID int64, action, outcome, detail string) {
+	f.RecordAs(ctx, userID, userID, action, outcome, detail)
+}
+
+func (f *fakeAudit) RecordAs(ctx context.Context, actorID, userID int64, action, outcome, detail string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	event := entities.AuditEvent{Action: action, Outcome: outcome}
+	if userID > 0 {
+		event.UserID = &userID
+	}
+	if actorID > 0 {
+		event.ActorID = &actorID
+	}
+	if detail != "" {
+		event.Detail = &detail
+	}
+	f.events = append(f.events, event)
+}
+
+func (f *fakeAudit) ListForUser(ctx context.Context, userID, beforeID int64, limit int) ([]entities.AuditEvent, int64, error) {
+	return nil, 0, nil
+}
+
+func (f *fakeAudit) Search(ctx context.Context, filter contracts.AuditFilter) ([]entities.AuditEvent, int64, error) {
+	return nil, 0, nil
+}
+
+// fakeMailer reports the template of every email sent, which may happen from a goroutine.
+type fakeMailer struct {
+	sent chan string
+}
+
+func (f *fakeMailer) SendEmail(to string, variables map[string]interface{}) error {
+	f.sent <- "verification"
+	return nil
+}
+
+func (f *fakeMailer) SendTemplate(to, templateID string, variables map[string]interface{}) error {
+	f.sent <- templateID
+	return nil
+}
+
+func (f *fakeMailer) HandleWebhook(ctx context.Context, body request.ResendWebhookPayload, payload []byte) error {
+	return nil
+}
+
+func newTestService(user *entities.User) *Service {
+	logger := zerolog.Nop()
+	repo := newFakeRepo(user)
+	tokens := newFakeTokenRepo()
+	return &Service{
+		app: &contracts.App{
+			Config: map[string]string{
+				"JWT_SECRET":     "secret",
+				"REFRESH_SECRET": "refresh",
+				"JWT_TTL":        "1h",
+				"REFRESH_TTL":    "24h",
+			},
+			Logger:   &logger,
+			JWTKeys:  testKeyset(),
+			Services: &contracts.Services{Audit: &fakeAudit{}},
+		},
+		repo:     repo,
+		tokens:   tokens,
+		mfa:      repo,
+		sessions: tokens,
+
+		throttles: newFakeThrottleRepo(),
+		throttle:  parseThrottlePolicy(nil),
+		passwords: testPasswordHasher,
+		policy:    parsePasswordPolicy(nil),
+	}
+}
+
+// testPasswordHasher keeps argon2 cheap enough for unit tests.
+var testPasswordHasher = passwordHasher{memory: 64, time: 1, threads: 1}
+
+// testKeyset signs with the legacy HMAC secrets used throughout these tests.
+func testKeyset() *jwtkeys.Keyset {
+	keys, _ := jwtkeys.Load(map[string]string{
+		"JWT_SECRET":     "secret",
+		"REFRESH_SECRET": "refresh",
+	})
+	return keys
+}
+
+func verifiedUser(t *testing.T) *entities.User {
+	t.Helper()
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("secret123"), bcrypt.DefaultCost)
+	return &entities.User{
+		ID:         1,
+		Email:      "user@example.com",
+		Name:       "user",
+		Role:       "user",
+		Password:   string(hashed),
+		IsVerified: true,
+	}
+}
+
 func TestLoginSuccess(t *testing.T) {
 	hashed, _ := bcrypt.GenerateFromPassword([]byte("secret123"), bcrypt.DefaultCost)
 	repo := newFakeRepo(&entities.User{
@@ -77,14 +483,21 @@ func TestLoginSuccess(t *testing.T) {
 		Password:   string(hashed),
 		IsVerified: true,
 	})
+	tokens := newFakeTokenRepo()
+	logger := zerolog.Nop()
 	svc := &Service{
 		app: &contracts.App{Config: map[string]string{
 			"JWT_SECRET":     "secret",
 			"REFRESH_SECRET": "refresh",
 			"JWT_TTL":        "1h",
 			"REFRESH_TTL":    "24h",
-		}},
-		repo: repo,
+		}, Logger: &logger, JWTKeys: testKeyset(), Services: &contracts.Services{Audit: &fakeAudit{}}},
+		repo:      repo,
+		tokens:    tokens,
+		sessions:  tokens,
+		throttles: newFakeThrottleRepo(),
+		throttle:  parseThrottlePolicy(nil),
+		passwords: testPasswordHasher,
 	}
 
 	access, refresh, user, err := svc.Login(context.Background(), "user@example.com", "secret123")
@@ -109,14 +522,21 @@ func TestLoginInvalidPassword(t *testing.T) {
 		Password:   string(hashed),
 		IsVerified: true,
 	})
+	tokens := newFakeTokenRepo()
+	logger := zerolog.Nop()
 	svc := &Service{
 		app: &contracts.App{Config: map[string]string{
 			"JWT_SECRET":     "secret",
 			"REFRESH_SECRET": "refresh",
 			"JWT_TTL":        "1h",
 			"REFRESH_TTL":    "24h",
-		}},
-		repo: repo,
+		}, Logger: &logger, JWTKeys: testKeyset(), Services: &contracts.Services{Audit: &fakeAudit{}}},
+		repo:      repo,
+		tokens:    tokens,
+		sessions:  tokens,
+		throttles: newFakeThrottleRepo(),
+		throttle:  parseThrottlePolicy(nil),
+		passwords: testPasswordHasher,
 	}
 
 	_, _, _, err := svc.Login(context.Background(), "user@example.com", "wrong")
@@ -125,9 +545,384 @@ func TestLoginInvalidPassword(t *testing.T) {
 	}
 }
 
+func TestRefreshRotatesToken(t *testing.T) {
+	svc := newTestService(verifiedUser(t))
+
+	_, refresh, _, err := svc.Login(context.Background(), "user@example.com", "secret123")
+	if err != nil {
+		t.Fatalf("unexpected login error: %v", err)
+	}
+	_, rotated, _, err := svc.Refresh(context.Background(), refresh)
+	if err != nil {
+		t.Fatalf("unexpected refresh error: %v", err)
+	}
+	if rotated == refresh {
+		t.Fatalf("refresh token should be rotated")
+	}
+	if _, _, _, err := svc.Refresh(context.Background(), rotated); err != nil {
+		t.Fatalf("rotated token should be accepted, got %v", err)
+	}
+}
+
+func TestRefreshSurvivesSigningKeyRotation(t *testing.T) {
+	svc := newTestService(verifiedUser(t))
+	oldPublic, oldPrivate, _ := ed25519.GenerateKey(rand.Reader)
+	_, newPrivate, _ := ed25519.GenerateKey(rand.Reader)
+	svc.app.JWTKeys = mustKeyset(t, map[string]string{
+		"JWT_KEYS": keysJSON(t, map[string]interface{}{"kid": "old", "alg": "EdDSA", "private_key": pkcs8(t, oldPrivate)}),
+	})
+
+	_, refresh, _, err := svc.Login(context.Background(), "user@example.com", "secret123")
+	if err != nil {
+		t.Fatalf("unexpected login error: %v", err)
+	}
+
+	publicDER, _ := x509.MarshalPKIXPublicKey(oldPublic)
+	svc.app.JWTKeys = mustKeyset(t, map[string]string{
+		"JWT_KEYS": keysJSON(t,
+			map[string]interface{}{"kid": "new", "alg": "EdDSA", "private_key": pkcs8(t, newPrivate)},
+			map[string]interface{}{"kid": "old", "alg": "EdDSA", "public_key": base64.StdEncoding.EncodeToString(publicDER)},
+		),
+	})
+	access, _, _, err := svc.Refresh(context.Background(), refresh)
+	if err != nil {
+		t.Fatalf("refresh token signed by the previous key should still work: %v", err)
+	}
+	if _, err := svc.app.JWTKeys.Parse(access, jwtkeys.PurposeAccess); err != nil {
+		t.Fatalf("new access token should verify: %v", err)
+	}
+}
+
+func mustKeyset(t *testing.T, config map[string]string) *jwtkeys.Keyset {
+	t.Helper()
+	keys, err := jwtkeys.Load(config)
+	if err != nil {
+		t.Fatalf("load keys: %v", err)
+	}
+	return keys
+}
+
+func keysJSON(t *testing.T, entries ...map[string]interface{}) string {
+	t.Helper()
+	raw, err := json.Marshal(entries)
+	if err != nil {
+		t.Fatalf("encode keys: %v", err)
+	}
+	return string(raw)
+}
+
+func pkcs8(t *testing.T, key ed25519.PrivateKey) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("encode key: %v", err)
+	}
+	return base64.StdEncoding.EncodeToString(der)
+}
+
+func TestRefreshReuseRevokesFamily(t *testing.T) {
+	svc := newTestService(verifiedUser(t))
+
+	_, refresh, _, err := svc.Login(context.Background(), "user@example.com", "secret123")
+	if err != nil {
+		t.Fatalf("unexpected login error: %v", err)
+	}
+	_, rotated, _, err := svc.Refresh(context.Background(), refresh)
+	if err != nil {
+		t.Fatalf("unexpected refresh error: %v", err)
+	}
+
+	if _, _, _, err := svc.Refresh(context.Background(), refresh); !errors.Is(err, errRefreshTokenReused) {
+		t.Fatalf("expected reuse error, got %v", err)
+	}
+	if _, _, _, err := svc.Refresh(context.Background(), rotated); !errors.Is(err, errInvalidCredentials) {
+		t.Fatalf("family should be revoked after reuse, got %v", err)
+	}
+}
+
+func TestLogoutRevokesFamily(t *testing.T) {
+	svc := newTestService(verifiedUser(t))
+
+	_, refresh, _, err := svc.Login(context.Background(), "user@example.com", "secret123")
+	if err != nil {
+		t.Fatalf("unexpected login error: %v", err)
+	}
+	stored, _ := svc.tokens.FindRefreshTokenByHash(context.Background(), hashToken(refresh))
+	if err := svc.Logout(context.Background(), 1, stored.FamilyID); err != nil {
+		t.Fatalf("unexpected logout error: %v", err)
+	}
+	if _, _, _, err := svc.Refresh(context.Background(), refresh); !errors.Is(err, errInvalidCredentials) {
+		t.Fatalf("expected invalid credentials after logout, got %v", err)
+	}
+}
+
+func TestRevokeSessionRejectsItsAccessTokens(t *testing.T) {
+	svc := newTestService(verifiedUser(t))
+	ctx := helpers.WithClientInfo(context.Background(), entities.ClientInfo{DeviceName: "Pixel 8"})
+
+	_, phone, _, err := svc.Login(ctx, "user@example.com", "secret123")
+	if err != nil {
+		t.Fatalf("unexpected login error: %v", err)
+	}
+	_, laptop, _, err := svc.Login(context.Background(), "user@example.com", "secret123")
+	if err != nil {
+		t.Fatalf("unexpected login error: %v", err)
+	}
+	phoneToken, _ := svc.tokens.FindRefreshTokenByHash(context.Background(), hashToken(phone))
+	laptopToken, _ := svc.tokens.FindRefreshTokenByHash(context.Background(), hashToken(laptop))
+
+	sessions, err := svc.ListSessions(context.Background(), 1, laptopToken.FamilyID)
+	if err != nil || len(sessions) != 2 {
+		t.Fatalf("expected two sessions, got %d (%v)", len(sessions), err)
+	}
+	var phoneSession entities.Session
+	for _, s := range sessions {
+		if s.FamilyID == phoneToken.FamilyID {
+			phoneSession = s
+		}
+		if s.Current != (s.FamilyID == laptopToken.FamilyID) {
+			t.Fatalf("only the laptop session should be current")
+		}
+	}
+	if phoneSession.DeviceName != "Pixel 8" {
+		t.Fatalf("expected device name to be recorded, got %q", phoneSession.DeviceName)
+	}
+
+	if err := svc.RevokeSession(context.Background(), 1, phoneSession.ID); err != nil {
+		t.Fatalf("unexpected revoke error: %v", err)
+	}
+	events := svc.app.Services.Audit.(*fakeAudit).events
+	if last := events[len(events)-1]; last.Action != constants.AuditSessionRevoked || *last.Detail != strconv.FormatInt(phoneSession.ID, 10) {
+		t.Fatalf("expected the revoke to be audited, got %+v", last)
+	}
+	if err := svc.ValidateSession(context.Background(), 1, phoneToken.FamilyID); !errors.Is(err, errSessionRevoked) {
+		t.Fatalf("expected revoked session, got %v", err)
+	}
+	if _, _, _, err := svc.Refresh(context.Background(), phone); !errors.Is(err, errInvalidCredentials) {
+		t.Fatalf("expected refresh to fail for revoked session, got %v", err)
+	}
+	if err := svc.ValidateSession(context.Background(), 1, laptopToken.FamilyID); err != nil {
+		t.Fatalf("other session should stay active, got %v", err)
+	}
+}
+
+func TestResetPasswordIsSingleUseAndRevokesSessions(t *testing.T) {
+	user := verifiedUser(t)
+	svc := newTestService(user)
+
+	_, refresh, _, err := svc.Login(context.Background(), "user@example.com", "secret123")
+	if err != nil {
+		t.Fatalf("unexpected login error: %v", err)
+	}
+
+	raw, hashed, expiresAt, err := svc.prepareToken(passwordResetTTL)
+	if err != nil {
+		t.Fatalf("unexpected token error: %v", err)
+	}
+	_ = svc.repo.UpdatePasswordResetToken(context.Background(), user.ID, &hashed, &expiresAt)
+
+	if err := svc.ResetPassword(context.Background(), raw, "newsecret"); err != nil {
+		t.Fatalf("unexpected reset error: %v", err)
+	}
+	if err := svc.ResetPassword(context.Background(), raw, "another"); !errors.Is(err, errResetTokenInvalid) {
+		t.Fatalf("reset token should be single-use, got %v", err)
+	}
+	if _, _, _, err := svc.Refresh(context.Background(), refresh); !errors.Is(err, errInvalidCredentials) {
+		t.Fatalf("refresh tokens should be revoked after reset, got %v", err)
+	}
+	if _, _, _, err := svc.Login(context.Background(), "user@example.com", "newsecret"); err != nil {
+		t.Fatalf("login with new password failed: %v", err)
+	}
+}
+
+func TestResetPasswordExpiredToken(t *testing.T) {
+	user := verifiedUser(t)
+	svc := newTestService(user)
+
+	raw, hashed, _, _ := svc.prepareToken(passwordResetTTL)
+	expired := time.Now().Add(-time.Minute)
+	_ = svc.repo.UpdatePasswordResetToken(context.Background(), user.ID, &hashed, &expired)
+
+	if err := svc.ResetPassword(context.Background(), raw, "newsecret"); !errors.Is(err, errResetTokenExpired) {
+		t.Fatalf("expected expired token error, got %v", err)
+	}
+}
+
+func TestChangePasswordKeepsCurrentLogin(t *testing.T) {
+	svc := newTestService(verifiedUser(t))
+	ctx := context.Background()
+
+	_, current, _, _ := svc.Login(ctx, "user@example.com", "secret123")
+	_, other, _, _ := svc.Login(ctx, "user@example.com", "secret123")
+	stored, _ := svc.tokens.FindRefreshTokenByHash(ctx, hashToken(current))
+
+	if err := svc.ChangePassword(ctx, 1, stored.FamilyID, "wrong", "newsecret"); !errors.Is(err, errInvalidCredentials) {
+		t.Fatalf("expected invalid credentials, got %v", err)
+	}
+	if err := svc.ChangePassword(ctx, 1, stored.FamilyID, "secret123", "newsecret"); err != nil {
+		t.Fatalf("unexpected change password error: %v", err)
+	}
+	if _, _, _, err := svc.Refresh(ctx, current); err != nil {
+		t.Fatalf("current login should survive, got %v", err)
+	}
+	if _, _, _, err := svc.Refresh(ctx, other); !errors.Is(err, errInvalidCredentials) {
+		t.Fatalf("other logins should be revoked, got %v", err)
+	}
+}
+
+func TestLoginWithMFA(t *testing.T) {
+	svc := newTestService(verifiedUser(t))
+	ctx := context.Background()
+
+	secret, uri, err := svc.EnrollMFA(ctx, 1)
+	if err != nil || uri == "" {
+		t.Fatalf("unexpected enroll error: %v", err)
+	}
+	key, _ := totpEncoding.DecodeString(secret)
+	step := time.Now().Unix() / totpPeriod
+	codes, err := svc.ConfirmMFA(ctx, 1, totpCode(key, step))
+	if err != nil {
+		t.Fatalf("unexpected confirm error: %v", err)
+	}
+	if len(codes) != recoveryCodeSize {
+		t.Fatalf("expected %d recovery codes, got %d", recoveryCodeSize, len(codes))
+	}
+
+	_, _, _, err = svc.Login(ctx, "user@example.com", "secret123")
+	var mfaErr *MFARequiredError
+	if !errors.As(err, &mfaErr) {
+		t.Fatalf("expected mfa challenge, got %v", err)
+	}
+	if _, _, _, err := svc.LoginMFA(ctx, mfaErr.ChallengeToken, totpCode(key, step)); !errors.Is(err, errInvalidMFACode) {
+		t.Fatalf("confirmation code must not be replayed, got %v", err)
+	}
+	access, refresh, _, err := svc.LoginMFA(ctx, mfaErr.ChallengeToken, totpCode(key, step+1))
+	if err != nil || access == "" || refresh == "" {
+		t.Fatalf("expected tokens after valid code, got %v", err)
+	}
+	if _, _, _, err := svc.LoginMFA(ctx, mfaErr.ChallengeToken, codes[0]); err != nil {
+		t.Fatalf("recovery code should be accepted, got %v", err)
+	}
+	if _, _, _, err := svc.LoginMFA(ctx, mfaErr.ChallengeToken, codes[0]); !errors.Is(err, errInvalidMFACode) {
+		t.Fatalf("recovery code must be single-use, got %v", err)
+	}
+	if _, _, _, err := svc.LoginMFA(ctx, access, codes[1]); !errors.Is(err, errInvalidCredentials) {
+		t.Fatalf("access token must not work as mfa challenge, got %v", err)
+	}
+}
+
+func TestLoginLocksAccountAfterRepeatedFailures(t *testing.T) {
+	svc := newTestService(verifiedUser(t))
+	svc.throttle.emailThreshold = 3
+	mailer := &fakeMailer{sent: make(chan string, 1)}
+	svc.app.Services.Email = mailer
+	ctx := helpers.WithClientInfo(context.Background(), entities.ClientInfo{IPAddress: "203.0.113.7"})
+
+	for i := 0; i < 3; i++ {
+		if _, _, _, err := svc.Login(ctx, "user@example.com", "wrong-password"); !errors.Is(err, errInvalidCredentials) {
+			t.Fatalf("attempt %d: expected invalid credentials, got %v", i+1, err)
+		}
+	}
+
+	_, _, _, err := svc.Login(ctx, "user@example.com", "secret123")
+	var locked *LoginLockedError
+	if !errors.As(err, &locked) || locked.RetryAfter <= 0 {
+		t.Fatalf("expected lockout even with the right password, got %v", err)
+	}
+
+	throttles := svc.throttles.(*fakeThrottleRepo)
+	account := throttles.throttles[throttleScopeEmail+":"+hashToken("user@example.com")]
+	if account.UnlockToken == nil {
+		t.Fatalf("expected an unlock token to be issued")
+	}
+	select {
+	case template := <-mailer.sent:
+		if template != email.TemplateAccountLocked {
+			t.Fatalf("expected unlock email, got %q", template)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected unlock email to be sent")
+	}
+	if ip := throttles.throttles[throttleScopeIP+":203.0.113.7"]; ip == nil || ip.LockedUntil != nil {
+		t.Fatalf("expected ip failures to be counted below its threshold, got %+v", ip)
+	}
+
+	// The emailed token is only stored hashed; swap in one the test knows.
+	known := hashToken("unlock-token")
+	account.UnlockToken = &known
+	if err := svc.UnlockLogin(ctx, "unlock-token"); err != nil {
+		t.Fatalf("unlock: %v", err)
+	}
+	events := svc.app.Services.Audit.(*fakeAudit).events
+	last := events[len(events)-1]
+	if last.Action != constants.AuditLoginUnlocked || last.UserID == nil || *last.UserID != 1 {
+		t.Fatalf("expected the unlock to be audited for user 1, got %+v", last)
+	}
+	if err := svc.UnlockLogin(ctx, "unlock-token"); !errors.Is(err, errUnlockTokenInvalid) {
+		t.Fatalf("expected the unlock token to work once, got %v", err)
+	}
+}
+
+func TestLockoutDurationBacksOffExponentially(t *testing.T) {
+	policy := throttlePolicy{base: 30 * time.Second, max: 5 * time.Minute}
+	cases := map[int]time.Duration{
+		2: 0,
+		3: 30 * time.Second,
+		4: time.Minute,
+		6: 4 * time.Minute,
+		9: 5 * time.Minute,
+	}
+	for failures, want := range cases {
+		if got := policy.lockoutDuration(failures, 3); got != want {
+			t.Fatalf("failures=%d: expected %s, got %s", failures, want, got)
+		}
+	}
+}
+
 func TestMustDurationFallback(t *testing.T) {
 	d := mustDuration("bad", time.Hour)
 	if d != time.Hour {
 		t.Fatalf("expected fallback duration, got %v", d)
 	}
 }
+
+func TestSecurityEventsAreAudited(t *testing.T) {
+	svc := newTestService(verifiedUser(t))
+	ctx := context.Background()
+
+	_, _, _, _ = svc.Login(ctx, "nobody@example.com", "secret123")
+	_, _, _, _ = svc.Login(ctx, "user@example.com", "wrong-password")
+	_, refresh, _, err := svc.Login(ctx, "user@example.com", "secret123")
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if _, _, _, err := svc.Refresh(ctx, refresh); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	_, _, _, _ = svc.Refresh(ctx, refresh)
+
+	type recorded struct {
+		userID          int64
+		action, outcome string
+	}
+	want := []recorded{
+		{0, constants.AuditLogin, constants.AuditFailure},
+		{1, constants.AuditLogin, constants.AuditFailure},
+		{1, constants.AuditLogin, constants.AuditSuccess},
+		{1, constants.AuditTokenRefresh, constants.AuditSuccess},
+		{1, constants.AuditTokenRefresh, constants.AuditFailure},
+	}
+	events := svc.app.Services.Audit.(*fakeAudit).events
+	if len(events) != len(want) {
+		t.Fatalf("expected %d events, got %+v", len(want), events)
+	}
+	for i, event := range events {
+		got := recorded{action: event.Action, outcome: event.Outcome}
+		if event.UserID != nil {
+			got.userID = *event.UserID
+		}
+		if got != want[i] {
+			t.Errorf("event %d = %+v, want %+v", i, got, want[i])
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"finlog-api/api/constants"
+	"finlog-api/api/entities"
+	"finlog-api/api/helpers"
+)
+
+// sessionTouchInterval limits how often authenticated requests write last_seen_at.
+const sessionTouchInterval = 5 * time.Minute
+
+var (
+	errSessionRevoked  = errors.New("session revoked")
+	errSessionNotFound = errors.New("session not found")
+)
+
+func ErrSessionRevoked() error  { return errSessionRevoked }
+func ErrSessionNotFound() error { return errSessionNotFound }
+
+// ListSessions returns the user's active logins, flagging the one identified by currentFamilyID.
+func (s *Service) ListSessions(ctx context.Context, userID int64, currentFamilyID string) ([]entities.Session, error) {
+	refreshTTL := mustDuration(s.app.Config[constants.REFRESH_TTL], 7*24*time.Hour)
+	sessions, err := s.sessions.ListActiveSessions(ctx, userID, time.Now().Add(-refreshTTL))
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].FamilyID == currentFamilyID
+	}
+	return sessions, nil
+}
+
+// RevokeSession signs a single device out by revoking its refresh token family.
+// Access tokens of that session are rejected from the next request on.
+func (s *Service) RevokeSession(ctx context.Context, userID, sessionID int64) error {
+	session, err := s.sessions.FindSession(ctx, userID, sessionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errSessionNotFound
+		}
+		return err
+	}
+	if session.RevokedAt != nil {
+		return nil
+	}
+	if err := s.tokens.RevokeRefreshTokenFamily(ctx, userID, session.FamilyID); err != nil {
+		return err
+	}
+	s.app.Logger.Info().
+		Int64("user_id", userID).
+		Int64("session_id", session.ID).
+		Msg("session_revoked")
+	s.app.Services.Audit.Record(ctx, userID, constants.AuditSessionRevoked, constants.AuditSuccess, strconv.FormatInt(session.ID, 10))
+	return nil
+}
+
+// ValidateSession checks that the login behind an access token is still active and
+// periodically records the request as the session's last activity. The session is
+// read from the writer so a revocation takes effect on the very next request.
+func (s *Service) ValidateSession(ctx context.Context, userID int64, familyID string) error {
+	// Tokens without a family predate session tracking. They are rejected, so the
+	// client logs in again and gets a session that can be revoked.
+	if familyID == "" {
+		return errSessionRevoked
+	}
+	session, err := s.sessions.FindSessionByFamily(ctx, familyID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errSessionRevoked
+		}
+		return err
+	}
+	if session.UserID != userID || session.RevokedAt != nil {
+		return errSessionRevoked
+	}
+
+	now := time.Now()
+	if now.Sub(session.LastSeenAt) >= sessionTouchInterval {
+		if err := s.sessions.TouchSession(ctx, session.ID, now, helpers.ClientInfoFrom(ctx)); err != nil {
+			s.app.Logger.Err(err).
+				Int64("session_id", session.ID).
+				Msg("session_touch_failed")
+		}
+	}
+	return nil
+}
+
+// recordSession stores or refreshes the session of a freshly issued token pair.
+func (s *Service) recordSession(ctx context.Context, userID int64, familyID string) error {
+	client := helpers.ClientInfoFrom(ctx)
+	now := time.Now()
+	return s.sessions.UpsertSession(ctx, &entities.Session{
+		UserID:     userID,
+		FamilyID:   familyID,
+		DeviceName: client.DeviceName,
+		UserAgent:  client.UserAgent,
+		IPAddress:  client.IPAddress,
+		CreatedAt:  now,
+		LastSeenAt: now,
+	})
+}
@@ -0,0 +1,259 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"finlog-api/api/constants"
+	"finlog-api/api/entities"
+	"finlog-api/api/helpers"
+	"finlog-api/api/services/email"
+)
+
+const (
+	throttleScopeEmail = "email"
+	throttleScopeIP    = "ip"
+
+	// failureWindow is how long a failure counts towards the next lockout.
+	failureWindow = 24 * time.Hour
+	unlockTTL     = 24 * time.Hour
+)
+
+var errUnlockTokenInvalid = errors.New("invalid unlock token")
+
+func ErrUnlockTokenInvalid() error { return errUnlockTokenInvalid }
+
+// LoginLockedError is returned while an email address or client IP is locked out
+// after repeated failed logins.
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string { return "too many failed login attempts" }
+
+// throttlePolicy controls when failures start locking a scope. The first lock is
+// base long and every further failure doubles it, up to max.
+type throttlePolicy struct {
+	emailThreshold int
+	ipThreshold    int
+	base           time.Duration
+	max            time.Duration
+}
+
+func parseThrottlePolicy(config map[string]string) throttlePolicy {
+	policy := throttlePolicy{
+		emailThreshold: 5,
+		ipThreshold:    20,
+		base:           30 * time.Second,
+		max:            time.Hour,
+	}
+	if raw := config[constants.LoginLockoutThreshold]; raw != "" {
+		if parsed, err := strconv.Atoi(raw); err == nil && parsed > 0 {
+			policy.emailThreshold = parsed
+		}
+	}
+	if raw := config[constants.LoginIPLockoutThreshold]; raw != "" {
+		if parsed, err := strconv.Atoi(raw); err == nil && parsed > 0 {
+			policy.ipThreshold = parsed
+		}
+	}
+	if raw := config[constants.LoginLockoutBase]; raw != "" {
+		if parsed, err := time.ParseDuration(raw); err == nil && parsed > 0 {
+			policy.base = parsed
+		}
+	}
+	if raw := config[constants.LoginLockoutMax]; raw != "" {
+		if parsed, err := time.ParseDuration(raw); err == nil && parsed > 0 {
+			policy.max = parsed
+		}
+	}
+	return policy
+}
+
+// lockoutDuration returns how long a scope stays locked after its n-th failure.
+func (p throttlePolicy) lockoutDuration(failures, threshold int) time.Duration {
+	if failures < threshold {
+		return 0
+	}
+	d := p.base
+	for i := threshold; i < failures && d < p.max; i++ {
+		d *= 2
+	}
+	if d > p.max {
+		d = p.max
+	}
+	return d
+}
+
+// checkLoginAllowed rejects the attempt while the email or client IP is locked.
+func (s *Service) checkLoginAllowed(ctx context.Context, email string) error {
+	now := time.Now().UTC()
+	for _, scope := range loginScopes(ctx, email) {
+		throttle, err := s.throttles.FindLoginThrottle(ctx, scope.name, scope.key)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			return err
+		}
+		if throttle.LockedUntil != nil && throttle.LockedUntil.After(now) {
+			retryAfter := throttle.LockedUntil.Sub(now)
+			s.app.Logger.Warn().
+				Str("scope", scope.name).
+				Str("email", email).
+				Str("ip", helpers.ClientInfoFrom(ctx).IPAddress).
+				Dur("retry_after", retryAfter).
+				Msg("login_blocked")
+			s.app.Services.Audit.Record(ctx, s.lockedUserID(ctx, email), constants.AuditLogin, constants.AuditFailure, "locked")
+			return &LoginLockedError{RetryAfter: retryAfter}
+		}
+	}
+	return nil
+}
+
+// lockedUserID finds the account a blocked attempt was aimed at so the owner can
+// see it in their audit log. It returns 0 when there is none.
+func (s *Service) lockedUserID(ctx context.Context, email string) int64 {
+	user, err := s.repo.FindByEmail(ctx, email)
+	if err != nil {
+		return 0
+	}
+	return user.ID
+}
+
+// recordLoginFailure counts a failed attempt against the email and client IP and
+// locks whichever scope crossed its threshold. When the account itself becomes
+// locked its owner is emailed an unlock link. Storage errors are logged only so
+// they never change the response the caller sees.
+func (s *Service) recordLoginFailure(ctx context.Context, email string, user *entities.User) {
+	now := time.Now().UTC()
+	ip := helpers.ClientInfoFrom(ctx).IPAddress
+
+	if user != nil {
+		s.app.Services.Audit.Record(ctx, user.ID, constants.AuditLogin, constants.AuditFailure, "invalid_credentials")
+	} else {
+		s.app.Services.Audit.Record(ctx, 0, constants.AuditLogin, constants.AuditFailure, "unknown_account")
+	}
+
+	for _, scope := range loginScopes(ctx, email) {
+		failures, err := s.throttles.RecordLoginFailure(ctx, scope.name, scope.key, now, now.Add(-failureWindow))
+		if err != nil {
+			s.app.Logger.Error().
+				Err(err).
+				Str("scope", scope.name).
+				Msg("login_throttle_failed")
+			continue
+		}
+		s.app.Logger.Warn().
+			Str("scope", scope.name).
+			Str("email", email).
+			Str("ip", ip).
+			Int("failures", failures).
+			Msg("login_failed")
+
+		threshold := s.throttle.emailThreshold
+		if scope.name == throttleScopeIP {
+			threshold = s.throttle.ipThreshold
+		}
+		lockout := s.throttle.lockoutDuration(failures, threshold)
+		if lockout == 0 {
+			continue
+		}
+
+		var rawToken string
+		var unlockUserID *int64
+		var unlockToken *string
+		var unlockExpiresAt *time.Time
+		if scope.name == throttleScopeEmail && failures == threshold && user != nil {
+			if rawToken, err = generateRandomToken(); err == nil {
+				hashed := hashToken(rawToken)
+				expiresAt := now.Add(unlockTTL)
+				unlockUserID, unlockToken, unlockExpiresAt = &user.ID, &hashed, &expiresAt
+			}
+		}
+
+		until := now.Add(lockout)
+		if err := s.throttles.LockLogin(ctx, scope.name, scope.key, until, unlockUserID, unlockToken, unlockExpiresAt); err != nil {
+			s.app.Logger.Error().
+				Err(err).
+				Str("scope", scope.name).
+				Msg("login_throttle_failed")
+			continue
+		}
+		s.app.Logger.Warn().
+			Str("scope", scope.name).
+			Str("email", email).
+			Str("ip", ip).
+			Int("failures", failures).
+			Time("locked_until", until).
+			Msg("login_locked")
+
+		if unlockToken != nil {
+			go s.sendUnlockEmail(user, rawToken)
+		}
+	}
+}
+
+// clearLoginFailures resets the account counter after a successful login. The
+// IP counter is left to expire so one valid account cannot shield an attacker.
+func (s *Service) clearLoginFailures(ctx context.Context, email string) {
+	if err := s.throttles.ClearLoginThrottle(ctx, throttleScopeEmail, hashToken(email)); err != nil {
+		s.app.Logger.Error().
+			Err(err).
+			Str("scope", throttleScopeEmail).
+			Msg("login_throttle_failed")
+	}
+}
+
+// UnlockLogin lifts an account lockout using the token from the unlock email.
+func (s *Service) UnlockLogin(ctx context.Context, token string) error {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return errUnlockTokenInvalid
+	}
+	userID, unlocked, err := s.throttles.UnlockLoginByToken(ctx, hashToken(token), time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if !unlocked {
+		return errUnlockTokenInvalid
+	}
+	s.app.Services.Audit.Record(ctx, userID, constants.AuditLoginUnlocked, constants.AuditSuccess, "")
+	s.app.Logger.Info().
+		Str("scope", throttleScopeEmail).
+		Msg("login_unlocked")
+	return nil
+}
+
+func (s *Service) sendUnlockEmail(user *entities.User, token string) {
+	variables := map[string]interface{}{
+		"name": user.Name,
+		"link": s.buildURL("/v1/auth/unlock", token),
+	}
+
+	if err := s.app.Services.Email.SendTemplate(user.Email, email.TemplateAccountLocked, variables); err != nil {
+		s.app.Logger.Error().
+			Err(err).
+			Str("email", user.Email).
+			Msg("unlock_email_failed")
+	}
+}
+
+type loginScope struct {
+	name string
+	key  string
+}
+
+// loginScopes lists the counters an attempt is tracked under. Email keys are
+// hashed; the IP scope is skipped when the request carried no client address.
+func loginScopes(ctx context.Context, email string) []loginScope {
+	scopes := []loginScope{{name: throttleScopeEmail, key: hashToken(email)}}
+	if ip := helpers.ClientInfoFrom(ctx).IPAddress; ip != "" {
+		scopes = append(scopes, loginScope{name: throttleScopeIP, key: ip})
+	}
+	return scopes
+}
@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTOTPCodeRFC6238Vectors(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tt := range tests {
+		if got := totpCode(secret, tt.unix/totpPeriod); got != tt.want {
+			t.Fatalf("totpCode(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTOTPRejectsReplay(t *testing.T) {
+	secret := totpEncoding.EncodeToString([]byte("12345678901234567890"))
+	at := time.Unix(1111111109, 0)
+
+	step, ok := validateTOTP(secret, "081804", at, 0)
+	if !ok {
+		t.Fatalf("expected code to validate")
+	}
+	if _, ok := validateTOTP(secret, "081804", at, step); ok {
+		t.Fatalf("code should not validate twice")
+	}
+	if _, ok := validateTOTP(secret, "000000", at, 0); ok {
+		t.Fatalf("wrong code should not validate")
+	}
+}
+
+func TestRecoveryCodeNormalization(t *testing.T) {
+	codes, err := generateRecoveryCodes(3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, code := range codes {
+		typed := strings.ToUpper(strings.ReplaceAll(code, "-", " "))
+		if normalizeRecoveryCode(typed) != normalizeRecoveryCode(code) {
+			t.Fatalf("normalization mismatch for %s", code)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package budget
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"finlog-api/api/datasources/dbtest"
+	"finlog-api/api/period"
+)
+
+// TestBudgetQueryIsCovered checks the budget is computed from the index alone.
+func TestBudgetQueryIsCovered(t *testing.T) {
+	db := dbtest.Open(t)
+	const perUser = 1000
+	userID := dbtest.Seed(t, db, 20, perUser)[10]
+
+	jakarta, _ := period.LoadLocation("Asia/Jakarta")
+	now := time.Now()
+	month := period.Month(now.Year(), now.Month(), jakarta)
+
+	plan := dbtest.Explain(t, db, getBudget, userID, month.Start, month.End)["transactions"]
+	if plan.Key != "idx_transactions_user_date_updated" || plan.Type != "range" || !strings.Contains(plan.Extra, "Using index") {
+		t.Fatalf("expected a covering range scan, got %+v", plan)
+	}
+	if plan.Rows > perUser {
+		t.Fatalf("expected at most the user's %d rows examined, got %d", perUser, plan.Rows)
+	}
+}
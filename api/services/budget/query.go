@@ -1,12 +1,12 @@
 package budget
 
 const (
-	getMonthlyBudget = `
+	getBudget = `
 		SELECT 
 			0 AS income,
 			0 AS expense,
 			MAX(updated_at) AS last_updated
 		FROM transactions
-		WHERE user_id = ? AND YEAR(occurred_at) = ? AND MONTH(occurred_at) = ?
+		WHERE user_id = ? AND occurred_at >= ? AND occurred_at < ? AND deleted_at IS NULL
 	`
 )
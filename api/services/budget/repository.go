@@ -7,6 +7,7 @@ import (
 	"finlog-api/api/contracts"
 	"finlog-api/api/datasources"
 	"finlog-api/api/entities"
+	"finlog-api/api/period"
 
 	"github.com/jmoiron/sqlx"
 )
@@ -25,13 +26,13 @@ type budgetRow struct {
 func initRepository(app *contracts.App) contracts.BudgetRepository {
 	return &repository{
 		reader: app.Ds.ReaderDB,
-		stmt:   datasources.Prepare(app.Ds.ReaderDB, getMonthlyBudget),
+		stmt:   datasources.Prepare(app.Ds.ReaderDB, getBudget),
 	}
 }
 
-func (r *repository) GetMonthly(ctx context.Context, userID int64, year int, month int) (*entities.Budget, error) {
+func (r *repository) Get(ctx context.Context, userID int64, within period.Range) (*entities.Budget, error) {
 	row := budgetRow{}
-	if err := r.stmt.GetContext(ctx, &row, userID, year, month); err != nil {
+	if err := r.stmt.GetContext(ctx, &row, userID, within.Start, within.End); err != nil {
 		return nil, err
 	}
 
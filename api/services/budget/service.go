@@ -6,6 +6,7 @@ import (
 
 	"finlog-api/api/contracts"
 	"finlog-api/api/entities"
+	"finlog-api/api/period"
 )
 
 var errInvalidPeriod = errors.New("invalid period")
@@ -22,11 +23,17 @@ func Init(app *contracts.App) contracts.BudgetService {
 	}
 }
 
-func (s *Service) GetMonthly(ctx context.Context, userID int64, year int, month int) (*entities.Budget, error) {
-	if year <= 0 || month < 1 || month > 12 {
+func (s *Service) Get(ctx context.Context, userID int64, within period.Range) (*entities.Budget, error) {
+	if !within.Valid() {
 		return nil, errInvalidPeriod
 	}
-	return s.repo.GetMonthly(ctx, userID, year, month)
+	b, err := s.repo.Get(ctx, userID, within)
+	if err != nil {
+		return nil, err
+	}
+	b.PeriodStart = within.Start
+	b.PeriodEnd = within.End
+	return b, nil
 }
 
 func ErrInvalidPeriod() error { return errInvalidPeriod }
@@ -2,21 +2,21 @@ package category
 
 const (
 	listCategories = `
-		SELECT id, user_id, name, is_expense, icon_key, is_active, created_at, updated_at
+		SELECT id, user_id, name, is_expense, icon_key, is_active, created_at, updated_at, version
 		FROM categories
 		WHERE user_id = ?
 		  AND is_active = 1
 	`
 
 	findCategoryByID = `
-		SELECT id, user_id, name, is_expense, icon_key, is_active, created_at, updated_at
+		SELECT id, user_id, name, is_expense, icon_key, is_active, created_at, updated_at, version
 		FROM categories
 		WHERE id = ? AND user_id = ?
 		LIMIT 1
 	`
 
 	findCategoryByName = `
-		SELECT id, user_id, name, is_expense, icon_key, is_active, created_at, updated_at
+		SELECT id, user_id, name, is_expense, icon_key, is_active, created_at, updated_at, version
 		FROM categories
 		WHERE user_id = ? AND LOWER(name) = LOWER(?) AND is_expense = ?
 		LIMIT 1
@@ -29,13 +29,19 @@ const (
 
 	updateCategory = `
 		UPDATE categories
-		SET name = ?, is_expense = ?, icon_key = ?, is_active = ?, updated_at = NOW()
-		WHERE id = ? AND user_id = ?
+		SET name = ?, is_expense = ?, icon_key = ?, is_active = ?, updated_at = NOW(), version = version + 1
+		WHERE id = ? AND user_id = ? AND version = ?
+	`
+
+	reviveCategory = `
+		UPDATE categories
+		SET name = ?, icon_key = ?, is_active = 1, updated_at = NOW(), version = version + 1
+		WHERE id = ? AND user_id = ? AND is_active = 0
 	`
 
 	deleteCategory = `
 		UPDATE categories
-		SET is_active = 0, updated_at = NOW()
+		SET is_active = 0, updated_at = NOW(), version = version + 1
 		WHERE id = ? AND user_id = ?
 	`
 )
@@ -7,6 +7,7 @@ import (
 	"finlog-api/api/contracts"
 	"finlog-api/api/datasources"
 	"finlog-api/api/entities"
+	"finlog-api/api/services/changelog"
 
 	"github.com/jmoiron/sqlx"
 )
@@ -19,6 +20,7 @@ type repository struct {
 		findByName *sqlx.Stmt
 		insert     *sqlx.Stmt
 		update     *sqlx.Stmt
+		revive     *sqlx.Stmt
 		delete     *sqlx.Stmt
 	}
 }
@@ -32,12 +34,14 @@ func initRepository(app *contracts.App) contracts.CategoryRepository {
 			findByName *sqlx.Stmt
 			insert     *sqlx.Stmt
 			update     *sqlx.Stmt
+			revive     *sqlx.Stmt
 			delete     *sqlx.Stmt
 		}{
-			findByID:   datasources.Prepare(app.Ds.ReaderDB, findCategoryByID),
-			findByName: datasources.Prepare(app.Ds.ReaderDB, findCategoryByName),
+			findByID:   datasources.Prepare(app.Ds.WriterDB, findCategoryByID),
+			findByName: datasources.Prepare(app.Ds.WriterDB, findCategoryByName),
 			insert:     datasources.Prepare(app.Ds.WriterDB, insertCategory),
 			update:     datasources.Prepare(app.Ds.WriterDB, updateCategory),
+			revive:     datasources.Prepare(app.Ds.WriterDB, reviveCategory),
 			delete:     datasources.Prepare(app.Ds.WriterDB, deleteCategory),
 		},
 	}
@@ -62,6 +66,8 @@ func (r *repository) List(ctx context.Context, userID int64, filter contracts.Ca
 	return categories, nil
 }
 
+// FindByID reads from the writer: its version becomes the ETag a client sends
+// back as If-Match, so it must never trail the client's own last write.
 func (r *repository) FindByID(ctx context.Context, id, userID int64) (*entities.Category, error) {
 	cat := new(entities.Category)
 	if err := r.stmt.findByID.GetContext(ctx, cat, id, userID); err != nil {
@@ -70,6 +76,8 @@ func (r *repository) FindByID(ctx context.Context, id, userID int64) (*entities.
 	return cat, nil
 }
 
+// FindByName reads from the writer: CreateCategory decides on it whether to
+// revive an inactive category or insert a new one.
 func (r *repository) FindByName(ctx context.Context, name string, isExpense bool, userID int64) (*entities.Category, error) {
 	cat := new(entities.Category)
 	if err := r.stmt.findByName.GetContext(ctx, cat, userID, name, isExpense); err != nil {
@@ -79,35 +87,81 @@ func (r *repository) FindByName(ctx context.Context, name string, isExpense bool
 }
 
 func (r *repository) Create(ctx context.Context, category *entities.Category) (int64, error) {
-	res, err := r.stmt.insert.ExecContext(ctx, category.UserID, category.Name, category.IsExpense, category.IconKey)
+	var id int64
+	err := r.inTx(ctx, func(tx *sqlx.Tx) error {
+		res, err := tx.StmtxContext(ctx, r.stmt.insert).ExecContext(ctx, category.UserID, category.Name, category.IsExpense, category.IconKey)
+		if err != nil {
+			return err
+		}
+		if id, err = res.LastInsertId(); err != nil {
+			return err
+		}
+		return changelog.Record(ctx, tx, category.UserID, changelog.EntityCategory, changelog.OpUpsert, id)
+	})
 	if err != nil {
 		return 0, err
 	}
-	return res.LastInsertId()
+	return id, nil
 }
 
 func (r *repository) Update(ctx context.Context, category *entities.Category) error {
-	res, err := r.stmt.update.ExecContext(ctx, category.Name, category.IsExpense, category.IconKey, boolToInt(category.IsActive), category.ID, category.UserID)
-	if err != nil {
-		return err
-	}
-	affected, _ := res.RowsAffected()
-	if affected == 0 {
-		return sql.ErrNoRows
-	}
-	return nil
+	return r.inTx(ctx, func(tx *sqlx.Tx) error {
+		res, err := tx.StmtxContext(ctx, r.stmt.update).ExecContext(ctx, category.Name, category.IsExpense, category.IconKey, boolToInt(category.IsActive), category.ID, category.UserID, category.Version)
+		if err != nil {
+			return err
+		}
+		affected, _ := res.RowsAffected()
+		if affected == 0 {
+			return sql.ErrNoRows
+		}
+		// Sync reports an inactive category as deleted.
+		return changelog.Record(ctx, tx, category.UserID, changelog.EntityCategory, changelog.OpUpsert, category.ID)
+	})
 }
 
+// Revive reactivates an inactive category under its new name and icon. It
+// returns sql.ErrNoRows when the category is already active again.
+func (r *repository) Revive(ctx context.Context, category *entities.Category) error {
+	return r.inTx(ctx, func(tx *sqlx.Tx) error {
+		res, err := tx.StmtxContext(ctx, r.stmt.revive).ExecContext(ctx, category.Name, category.IconKey, category.ID, category.UserID)
+		if err != nil {
+			return err
+		}
+		affected, _ := res.RowsAffected()
+		if affected == 0 {
+			return sql.ErrNoRows
+		}
+		return changelog.Record(ctx, tx, category.UserID, changelog.EntityCategory, changelog.OpUpsert, category.ID)
+	})
+}
+
+// Delete deactivates the category; transactions keep pointing at it.
 func (r *repository) Delete(ctx context.Context, id, userID int64) error {
-	res, err := r.stmt.delete.ExecContext(ctx, id, userID)
+	return r.inTx(ctx, func(tx *sqlx.Tx) error {
+		res, err := tx.StmtxContext(ctx, r.stmt.delete).ExecContext(ctx, id, userID)
+		if err != nil {
+			return err
+		}
+		affected, _ := res.RowsAffected()
+		if affected == 0 {
+			return sql.ErrNoRows
+		}
+		return changelog.Record(ctx, tx, userID, changelog.EntityCategory, changelog.OpDelete, id)
+	})
+}
+
+// inTx runs fn in a database transaction so the write and its change log entry
+// commit together.
+func (r *repository) inTx(ctx context.Context, fn func(tx *sqlx.Tx) error) error {
+	tx, err := r.writer.BeginTxx(ctx, nil)
 	if err != nil {
 		return err
 	}
-	affected, _ := res.RowsAffected()
-	if affected == 0 {
-		return sql.ErrNoRows
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
 	}
-	return nil
+	return tx.Commit()
 }
 
 func boolToInt(v bool) int {
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"finlog-api/api/contracts"
+	"finlog-api/api/datasources"
 	"finlog-api/api/entities"
 )
 
@@ -14,8 +15,17 @@ var (
 	errCategoryNotFound = errors.New("category not found")
 	errCategoryExists   = errors.New("category already exists")
 	errInvalidCategory  = errors.New("invalid category input")
+	errVersionRequired  = errors.New("category version required")
 )
 
+// VersionConflictError is returned by UpdateCategory when the category changed
+// since the version the client sent.
+type VersionConflictError struct {
+	Current *entities.Category
+}
+
+func (e *VersionConflictError) Error() string { return "category was changed by another request" }
+
 type Service struct {
 	app  *contracts.App
 	repo contracts.CategoryRepository
@@ -45,12 +55,13 @@ func (s *Service) CreateCategory(ctx context.Context, userID int64, name string,
 		}
 		existing.Name = name
 		existing.IconKey = iconKey
-		existing.IsExpense = isExpense
-		existing.IsActive = true
-		if err := s.repo.Update(ctx, existing); err != nil {
+		if err := s.repo.Revive(ctx, existing); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, errCategoryExists
+			}
 			return nil, err
 		}
-		return existing, nil
+		return s.GetCategory(ctx, userID, existing.ID)
 	} else if !errors.Is(err, sql.ErrNoRows) {
 		return nil, err
 	}
@@ -63,27 +74,50 @@ func (s *Service) CreateCategory(ctx context.Context, userID int64, name string,
 	}
 	id, err := s.repo.Create(ctx, category)
 	if err != nil {
+		if datasources.IsDuplicateKey(err) {
+			return nil, errCategoryExists
+		}
 		return nil, err
 	}
 	category.ID = id
+	category.Version = 1
 	return category, nil
 }
 
-func (s *Service) UpdateCategory(ctx context.Context, userID, categoryID int64, name string, isExpense bool, iconKey string) error {
+func (s *Service) GetCategory(ctx context.Context, userID, categoryID int64) (*entities.Category, error) {
 	if categoryID <= 0 {
-		return errInvalidCategory
+		return nil, errInvalidCategory
+	}
+	category, err := s.repo.FindByID(ctx, categoryID, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errCategoryNotFound
+		}
+		return nil, err
+	}
+	return category, nil
+}
+
+// UpdateCategory overwrites the category only if it is still at version.
+// Otherwise it returns VersionConflictError with the stored copy.
+func (s *Service) UpdateCategory(ctx context.Context, userID, categoryID, version int64, name string, isExpense bool, iconKey string) (*entities.Category, error) {
+	if categoryID <= 0 {
+		return nil, errInvalidCategory
+	}
+	if version <= 0 {
+		return nil, errVersionRequired
 	}
 	if err := validateCategoryInput(name); err != nil {
-		return err
+		return nil, err
 	}
 	if iconKey == "" {
 		iconKey = "category"
 	}
 
 	if existing, err := s.repo.FindByName(ctx, name, isExpense, userID); err == nil && existing.ID != categoryID && existing.IsActive {
-		return errCategoryExists
+		return nil, errCategoryExists
 	} else if err != nil && !errors.Is(err, sql.ErrNoRows) {
-		return err
+		return nil, err
 	}
 
 	category := &entities.Category{
@@ -93,14 +127,19 @@ func (s *Service) UpdateCategory(ctx context.Context, userID, categoryID int64,
 		IsExpense: isExpense,
 		IconKey:   iconKey,
 		IsActive:  true,
+		Version:   version,
 	}
 	if err := s.repo.Update(ctx, category); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return errCategoryNotFound
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
 		}
-		return err
+		current, err := s.GetCategory(ctx, userID, categoryID)
+		if err != nil {
+			return nil, err
+		}
+		return nil, &VersionConflictError{Current: current}
 	}
-	return nil
+	return s.GetCategory(ctx, userID, categoryID)
 }
 
 func (s *Service) DeleteCategory(ctx context.Context, userID, categoryID int64) error {
@@ -130,3 +169,4 @@ func validateCategoryInput(name string) error {
 func ErrCategoryNotFound() error { return errCategoryNotFound }
 func ErrCategoryExists() error   { return errCategoryExists }
 func ErrInvalidCategory() error  { return errInvalidCategory }
+func ErrVersionRequired() error  { return errVersionRequired }
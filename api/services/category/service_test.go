@@ -0,0 +1,170 @@
+package category
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+
+	"finlog-api/api/contracts"
+	"finlog-api/api/entities"
+)
+
+// fakeRepo implements lookups, Create, Update and Revive over an in-memory
+// table, applying the same conditions as the queries.
+type fakeRepo struct {
+	contracts.CategoryRepository
+	categories []entities.Category
+	// hideNames makes FindByName miss once, as if a concurrent create had not
+	// been seen yet when it ran.
+	hideNames int
+	// reviveFirst makes the next Revive find the category already active, as if
+	// a concurrent request had revived it.
+	reviveFirst bool
+}
+
+func (f *fakeRepo) Create(ctx context.Context, category *entities.Category) (int64, error) {
+	for _, stored := range f.categories {
+		if stored.UserID == category.UserID && stored.IsExpense == category.IsExpense && strings.EqualFold(stored.Name, category.Name) {
+			return 0, &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}
+		}
+	}
+	stored := *category
+	stored.ID = int64(len(f.categories) + 1)
+	stored.IsActive = true
+	stored.Version = 1
+	f.categories = append(f.categories, stored)
+	return stored.ID, nil
+}
+
+func (f *fakeRepo) FindByID(ctx context.Context, id, userID int64) (*entities.Category, error) {
+	for _, stored := range f.categories {
+		if stored.UserID == userID && stored.ID == id {
+			copied := stored
+			return &copied, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeRepo) FindByName(ctx context.Context, name string, isExpense bool, userID int64) (*entities.Category, error) {
+	if f.hideNames > 0 {
+		f.hideNames--
+		return nil, sql.ErrNoRows
+	}
+	for _, stored := range f.categories {
+		if stored.UserID == userID && stored.IsExpense == isExpense && strings.EqualFold(stored.Name, name) {
+			copied := stored
+			return &copied, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeRepo) Update(ctx context.Context, category *entities.Category) error {
+	for i, stored := range f.categories {
+		if stored.UserID == category.UserID && stored.ID == category.ID && stored.Version == category.Version {
+			updated := *category
+			updated.Version++
+			f.categories[i] = updated
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (f *fakeRepo) Revive(ctx context.Context, category *entities.Category) error {
+	if f.reviveFirst {
+		f.reviveFirst = false
+		for i := range f.categories {
+			if f.categories[i].ID == category.ID {
+				f.categories[i].IsActive = true
+			}
+		}
+	}
+	for i, stored := range f.categories {
+		if stored.UserID == category.UserID && stored.ID == category.ID && !stored.IsActive {
+			f.categories[i].Name = category.Name
+			f.categories[i].IconKey = category.IconKey
+			f.categories[i].IsActive = true
+			f.categories[i].Version++
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func TestUpdateCategoryDetectsStaleVersion(t *testing.T) {
+	svc := &Service{repo: &fakeRepo{}}
+	ctx := context.Background()
+	created, err := svc.CreateCategory(ctx, 1, "Food", true, "")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	if _, err := svc.UpdateCategory(ctx, 1, created.ID, 0, "Groceries", true, ""); !errors.Is(err, errVersionRequired) {
+		t.Fatalf("expected errVersionRequired, got %v", err)
+	}
+
+	// Two devices both start from version 1.
+	updated, err := svc.UpdateCategory(ctx, 1, created.ID, created.Version, "Groceries", true, "")
+	if err != nil || updated.Version != 2 || updated.Name != "Groceries" {
+		t.Fatalf("expected version 2, got %+v, %v", updated, err)
+	}
+
+	var conflict *VersionConflictError
+	if _, err := svc.UpdateCategory(ctx, 1, created.ID, created.Version, "Dining", true, ""); !errors.As(err, &conflict) {
+		t.Fatalf("expected a version conflict, got %v", err)
+	}
+	if conflict.Current.Version != 2 || conflict.Current.Name != "Groceries" {
+		t.Fatalf("expected the stored copy, got %+v", conflict.Current)
+	}
+
+	if _, err := svc.UpdateCategory(ctx, 1, created.ID+1, 1, "Dining", true, ""); !errors.Is(err, errCategoryNotFound) {
+		t.Fatalf("expected errCategoryNotFound, got %v", err)
+	}
+}
+
+func TestCreateCategoryRevivesInactiveCategory(t *testing.T) {
+	repo := &fakeRepo{categories: []entities.Category{
+		{ID: 1, UserID: 1, Name: "food", IsExpense: true, IconKey: "category", Version: 3},
+	}}
+	svc := &Service{repo: repo}
+	ctx := context.Background()
+
+	revived, err := svc.CreateCategory(ctx, 1, "Food", true, "fork")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if revived.ID != 1 || !revived.IsActive || revived.Name != "Food" || revived.IconKey != "fork" || revived.Version != 4 {
+		t.Fatalf("expected the stored category back at version 4, got %+v", revived)
+	}
+	if _, err := svc.CreateCategory(ctx, 1, "food", true, ""); !errors.Is(err, errCategoryExists) {
+		t.Fatalf("expected errCategoryExists for an active category, got %v", err)
+	}
+}
+
+func TestCreateCategoryRacingAnotherCreate(t *testing.T) {
+	repo := &fakeRepo{categories: []entities.Category{
+		{ID: 1, UserID: 1, Name: "Food", IsExpense: true, IsActive: true, Version: 1},
+	}}
+	svc := &Service{repo: repo}
+	ctx := context.Background()
+
+	// The name lookup ran before the other create was visible.
+	repo.hideNames = 1
+	if _, err := svc.CreateCategory(ctx, 1, "Food", true, ""); !errors.Is(err, errCategoryExists) {
+		t.Fatalf("expected errCategoryExists for a duplicate insert, got %v", err)
+	}
+
+	// The lookup saw the category inactive, but another request revived it
+	// before this one could.
+	repo.categories[0].IsActive = false
+	repo.reviveFirst = true
+	if _, err := svc.CreateCategory(ctx, 1, "Food", true, ""); !errors.Is(err, errCategoryExists) {
+		t.Fatalf("expected errCategoryExists for a lost revive, got %v", err)
+	}
+}
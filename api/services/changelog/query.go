@@ -0,0 +1,67 @@
+package changelog
+
+const (
+	// bumpSeqQuery reserves sequence numbers. The row lock it takes is held until
+	// the caller's transaction ends, so a user's changes commit in seq order.
+	// updated_at is kept as is: the counter is not a change to the account.
+	bumpSeqQuery = `
+		UPDATE users SET change_seq = LAST_INSERT_ID(change_seq + ?), updated_at = updated_at WHERE id = ?
+	`
+
+	insertChangesQuery = `
+		INSERT INTO change_log (user_id, seq, entity, entity_id, op, created_at) VALUES
+	`
+
+	listChangesQuery = `
+		SELECT id, user_id, seq, entity, entity_id, op, created_at
+		FROM change_log
+		WHERE user_id = ? AND seq > ?
+		ORDER BY seq
+		LIMIT ?
+	`
+
+	currentSeqQuery = `
+		SELECT change_seq FROM users WHERE id = ? LIMIT 1
+	`
+
+	findTransactionsQuery = `
+		SELECT 
+			t.id,
+			t.uuid,
+			t.user_id,
+			t.category_id,
+			c.name AS category_name,
+			t.payload_ciphertext,
+			t.payload_nonce,
+			t.payload_tag,
+			t.occurred_at,
+			t.is_expense,
+			t.created_at,
+			t.updated_at,
+			t.version
+		FROM transactions t
+		JOIN categories c ON t.category_id = c.id
+		WHERE t.user_id = ? AND t.id IN (?) AND t.deleted_at IS NULL
+	`
+
+	findCategoriesQuery = `
+		SELECT id, user_id, name, is_expense, icon_key, is_active, created_at, updated_at, version
+		FROM categories
+		WHERE user_id = ? AND id IN (?)
+	`
+
+	findKeyBackupsQuery = `
+		SELECT 
+			id,
+			user_id,
+			encrypted_data_key,
+			salt,
+			is_active,
+			rotated_at,
+			deleted_at,
+			created_at,
+			updated_at
+		FROM user_encrypted_data_keys
+		WHERE user_id = ? AND id IN (?)
+	`
+)
@@ -0,0 +1,49 @@
+package changelog
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Entities and operations recorded in the change log.
+const (
+	EntityTransaction = "transaction"
+	EntityCategory    = "category"
+	EntityKeyBackup   = "key_backup"
+
+	OpUpsert = "upsert"
+	OpDelete = "delete"
+)
+
+// Record appends one change per id to the user's log. It must run in the same
+// database transaction as the write it describes, so the log never mentions a
+// change that was rolled back or misses one that was committed.
+func Record(ctx context.Context, exec sqlx.ExtContext, userID int64, entity, op string, ids ...int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	res, err := exec.ExecContext(ctx, bumpSeqQuery, len(ids), userID)
+	if err != nil {
+		return err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return errUserNotFound
+	}
+	last, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	rows := make([]string, 0, len(ids))
+	args := make([]interface{}, 0, len(ids)*6)
+	for i, id := range ids {
+		rows = append(rows, "(?, ?, ?, ?, ?, ?)")
+		args = append(args, userID, last-int64(len(ids)-1-i), entity, id, op, now)
+	}
+	_, err = exec.ExecContext(ctx, insertChangesQuery+strings.Join(rows, ", "), args...)
+	return err
+}
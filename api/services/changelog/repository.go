@@ -0,0 +1,65 @@
+package changelog
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+
+	"finlog-api/api/contracts"
+	"finlog-api/api/entities"
+)
+
+// repository serves sync from the writer: a device syncing right after its own
+// write must see that change, and a token issued from the writer must never be
+// ahead of the sequence read back.
+type repository struct {
+	writer *sqlx.DB
+}
+
+func initRepository(app *contracts.App) contracts.SyncRepository {
+	return &repository{
+		writer: app.Ds.WriterDB,
+	}
+}
+
+func (r *repository) ListChanges(ctx context.Context, userID, afterSeq int64, limit int) ([]entities.Change, error) {
+	changes := []entities.Change{}
+	if err := r.writer.SelectContext(ctx, &changes, listChangesQuery, userID, afterSeq, limit); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+func (r *repository) CurrentSeq(ctx context.Context, userID int64) (int64, error) {
+	var seq int64
+	if err := r.writer.GetContext(ctx, &seq, currentSeqQuery, userID); err != nil {
+		return 0, err
+	}
+	return seq, nil
+}
+
+func (r *repository) FindTransactions(ctx context.Context, userID int64, ids []int64) ([]entities.Transaction, error) {
+	txs := []entities.Transaction{}
+	return txs, r.selectIn(ctx, &txs, findTransactionsQuery, userID, ids)
+}
+
+func (r *repository) FindCategories(ctx context.Context, userID int64, ids []int64) ([]entities.Category, error) {
+	categories := []entities.Category{}
+	return categories, r.selectIn(ctx, &categories, findCategoriesQuery, userID, ids)
+}
+
+func (r *repository) FindKeyBackups(ctx context.Context, userID int64, ids []int64) ([]entities.UserEncryptedDataKey, error) {
+	keys := []entities.UserEncryptedDataKey{}
+	return keys, r.selectIn(ctx, &keys, findKeyBackupsQuery, userID, ids)
+}
+
+func (r *repository) selectIn(ctx context.Context, dest interface{}, query string, userID int64, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	query, args, err := sqlx.In(query, userID, ids)
+	if err != nil {
+		return err
+	}
+	return r.writer.SelectContext(ctx, dest, r.writer.Rebind(query), args...)
+}
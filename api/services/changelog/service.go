@@ -0,0 +1,187 @@
+package changelog
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"finlog-api/api/contracts"
+	"finlog-api/api/entities"
+)
+
+const (
+	defaultPageSize = 500
+	maxPageSize     = 1000
+)
+
+var (
+	errInvalidToken = errors.New("invalid sync token")
+	errUserNotFound = errors.New("change log: user not found")
+)
+
+type Service struct {
+	app  *contracts.App
+	repo contracts.SyncRepository
+}
+
+func Init(app *contracts.App) contracts.SyncService {
+	return &Service{
+		app:  app,
+		repo: initRepository(app),
+	}
+}
+
+// Changes returns the current state of every record changed after since, or of
+// everything when since is empty. A record changed several times appears once.
+func (s *Service) Changes(ctx context.Context, userID int64, since string, limit int) (*contracts.SyncPage, error) {
+	after, err := parseToken(since)
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.repo.CurrentSeq(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	// A token from the future was not issued to this user.
+	if after > current {
+		return nil, errInvalidToken
+	}
+
+	size := pageSize(limit)
+	// One extra row tells whether another page follows.
+	changes, err := s.repo.ListChanges(ctx, userID, after, size+1)
+	if err != nil {
+		return nil, err
+	}
+	page := &contracts.SyncPage{
+		Transactions: []entities.Transaction{},
+		Categories:   []entities.Category{},
+		KeyBackups:   []entities.UserEncryptedDataKey{},
+		Deleted: contracts.SyncTombstones{
+			Transactions: []int64{},
+			Categories:   []int64{},
+			KeyBackups:   []int64{},
+		},
+	}
+	if len(changes) > size {
+		changes = changes[:size]
+		page.HasMore = true
+	}
+	if len(changes) > 0 {
+		after = changes[len(changes)-1].Seq
+	}
+	page.Token = strconv.FormatInt(after, 10)
+
+	// Only the latest change of each record matters.
+	type record struct {
+		entity string
+		id     int64
+	}
+	latest := make(map[record]string, len(changes))
+	for _, change := range changes {
+		latest[record{change.Entity, change.EntityID}] = change.Op
+	}
+	upserts := map[string][]int64{}
+	for _, change := range changes {
+		key := record{change.Entity, change.EntityID}
+		op, pending := latest[key]
+		if !pending {
+			continue
+		}
+		delete(latest, key)
+		if op == OpDelete {
+			tombstone(&page.Deleted, key.entity, key.id)
+		} else {
+			upserts[key.entity] = append(upserts[key.entity], key.id)
+		}
+	}
+
+	if err := s.load(ctx, userID, upserts, page); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// load fills the page with the current rows. Records deleted or deactivated
+// since the change was logged are reported as tombstones instead.
+func (s *Service) load(ctx context.Context, userID int64, upserts map[string][]int64, page *contracts.SyncPage) error {
+	txs, err := s.repo.FindTransactions(ctx, userID, upserts[EntityTransaction])
+	if err != nil {
+		return err
+	}
+	page.Transactions = append(page.Transactions, txs...)
+	page.Deleted.Transactions = append(page.Deleted.Transactions, missing(upserts[EntityTransaction], len(txs), func(i int) int64 { return txs[i].ID })...)
+
+	categories, err := s.repo.FindCategories(ctx, userID, upserts[EntityCategory])
+	if err != nil {
+		return err
+	}
+	for _, category := range categories {
+		if category.IsActive {
+			page.Categories = append(page.Categories, category)
+		} else {
+			page.Deleted.Categories = append(page.Deleted.Categories, category.ID)
+		}
+	}
+	page.Deleted.Categories = append(page.Deleted.Categories, missing(upserts[EntityCategory], len(categories), func(i int) int64 { return categories[i].ID })...)
+
+	keys, err := s.repo.FindKeyBackups(ctx, userID, upserts[EntityKeyBackup])
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if key.IsActive && key.DeletedAt == nil {
+			page.KeyBackups = append(page.KeyBackups, key)
+		} else {
+			page.Deleted.KeyBackups = append(page.Deleted.KeyBackups, key.ID)
+		}
+	}
+	page.Deleted.KeyBackups = append(page.Deleted.KeyBackups, missing(upserts[EntityKeyBackup], len(keys), func(i int) int64 { return keys[i].ID })...)
+	return nil
+}
+
+func tombstone(deleted *contracts.SyncTombstones, entity string, id int64) {
+	switch entity {
+	case EntityTransaction:
+		deleted.Transactions = append(deleted.Transactions, id)
+	case EntityCategory:
+		deleted.Categories = append(deleted.Categories, id)
+	case EntityKeyBackup:
+		deleted.KeyBackups = append(deleted.KeyBackups, id)
+	}
+}
+
+// missing returns the ids that were asked for but not found.
+func missing(ids []int64, found int, idAt func(int) int64) []int64 {
+	seen := make(map[int64]bool, found)
+	for i := 0; i < found; i++ {
+		seen[idAt(i)] = true
+	}
+	gone := []int64{}
+	for _, id := range ids {
+		if !seen[id] {
+			gone = append(gone, id)
+		}
+	}
+	return gone
+}
+
+func parseToken(since string) (int64, error) {
+	if since == "" {
+		return 0, nil
+	}
+	seq, err := strconv.ParseInt(since, 10, 64)
+	if err != nil || seq < 0 {
+		return 0, errInvalidToken
+	}
+	return seq, nil
+}
+
+func pageSize(limit int) int {
+	if limit <= 0 {
+		return defaultPageSize
+	}
+	return min(limit, maxPageSize)
+}
+
+func ErrInvalidToken() error { return errInvalidToken }
@@ -0,0 +1,140 @@
+package changelog
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"finlog-api/api/contracts"
+	"finlog-api/api/entities"
+)
+
+type fakeRepo struct {
+	changes      []entities.Change
+	transactions map[int64]entities.Transaction
+	categories   map[int64]entities.Category
+}
+
+func (f *fakeRepo) log(entity, op string, id int64) {
+	f.changes = append(f.changes, entities.Change{
+		Seq:      int64(len(f.changes) + 1),
+		Entity:   entity,
+		EntityID: id,
+		Op:       op,
+	})
+}
+
+func (f *fakeRepo) ListChanges(ctx context.Context, userID, afterSeq int64, limit int) ([]entities.Change, error) {
+	changes := []entities.Change{}
+	for _, change := range f.changes {
+		if change.Seq > afterSeq && len(changes) < limit {
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+func (f *fakeRepo) CurrentSeq(ctx context.Context, userID int64) (int64, error) {
+	return int64(len(f.changes)), nil
+}
+
+func (f *fakeRepo) FindTransactions(ctx context.Context, userID int64, ids []int64) ([]entities.Transaction, error) {
+	txs := []entities.Transaction{}
+	for _, id := range ids {
+		if tx, ok := f.transactions[id]; ok {
+			txs = append(txs, tx)
+		}
+	}
+	return txs, nil
+}
+
+func (f *fakeRepo) FindCategories(ctx context.Context, userID int64, ids []int64) ([]entities.Category, error) {
+	categories := []entities.Category{}
+	for _, id := range ids {
+		if category, ok := f.categories[id]; ok {
+			categories = append(categories, category)
+		}
+	}
+	return categories, nil
+}
+
+func (f *fakeRepo) FindKeyBackups(ctx context.Context, userID int64, ids []int64) ([]entities.UserEncryptedDataKey, error) {
+	return []entities.UserEncryptedDataKey{}, nil
+}
+
+func newTestService(repo *fakeRepo) *Service {
+	return &Service{app: &contracts.App{}, repo: repo}
+}
+
+func TestChangesCollapseToLatestState(t *testing.T) {
+	repo := &fakeRepo{
+		transactions: map[int64]entities.Transaction{1: {ID: 1}},
+		categories:   map[int64]entities.Category{7: {ID: 7, IsActive: false}},
+	}
+	repo.log(EntityTransaction, OpUpsert, 1)
+	repo.log(EntityTransaction, OpUpsert, 2)
+	repo.log(EntityTransaction, OpUpsert, 1)
+	repo.log(EntityTransaction, OpDelete, 2)
+	repo.log(EntityCategory, OpUpsert, 7)
+	svc := newTestService(repo)
+
+	page, err := svc.Changes(context.Background(), 1, "", 0)
+	if err != nil {
+		t.Fatalf("changes: %v", err)
+	}
+	if len(page.Transactions) != 1 || page.Transactions[0].ID != 1 {
+		t.Fatalf("expected transaction 1 once, got %+v", page.Transactions)
+	}
+	if len(page.Deleted.Transactions) != 1 || page.Deleted.Transactions[0] != 2 {
+		t.Fatalf("expected transaction 2 as a tombstone, got %v", page.Deleted.Transactions)
+	}
+	if len(page.Categories) != 0 || len(page.Deleted.Categories) != 1 {
+		t.Fatalf("expected the inactive category as a tombstone, got %+v", page)
+	}
+	if page.Token != "5" || page.HasMore {
+		t.Fatalf("expected final token 5, got %q more=%v", page.Token, page.HasMore)
+	}
+
+	page, err = svc.Changes(context.Background(), 1, page.Token, 0)
+	if err != nil || len(page.Transactions)+len(page.Deleted.Transactions) != 0 || page.Token != "5" {
+		t.Fatalf("expected nothing new, got %+v, %v", page, err)
+	}
+}
+
+func TestChangesPageThroughLog(t *testing.T) {
+	repo := &fakeRepo{transactions: map[int64]entities.Transaction{}}
+	for id := int64(1); id <= 5; id++ {
+		repo.transactions[id] = entities.Transaction{ID: id}
+		repo.log(EntityTransaction, OpUpsert, id)
+	}
+	svc := newTestService(repo)
+
+	seen := 0
+	token := ""
+	for {
+		page, err := svc.Changes(context.Background(), 1, token, 2)
+		if err != nil {
+			t.Fatalf("changes: %v", err)
+		}
+		seen += len(page.Transactions)
+		token = page.Token
+		if !page.HasMore {
+			break
+		}
+	}
+	if seen != 5 || token != "5" {
+		t.Fatalf("expected 5 transactions ending at token 5, got %d at %q", seen, token)
+	}
+}
+
+func TestChangesRejectUnknownTokens(t *testing.T) {
+	repo := &fakeRepo{}
+	repo.log(EntityTransaction, OpDelete, 1)
+	svc := newTestService(repo)
+
+	for _, since := range []string{"abc", "-1", "2"} {
+		if _, err := svc.Changes(context.Background(), 1, since, 0); !errors.Is(err, errInvalidToken) {
+			t.Fatalf("since %q: expected invalid token, got %v", since, err)
+		}
+	}
+}
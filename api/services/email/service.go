@@ -13,6 +13,16 @@ import (
 	"github.com/resend/resend-go/v3"
 )
 
+// Resend template IDs.
+const (
+	TemplateVerification    = "aktivasi-akun"
+	TemplatePasswordReset   = "reset-password"
+	TemplateEmailChanged    = "email-changed"
+	TemplateAccountDeletion = "account-deletion"
+	TemplateAccountLocked   = "account-locked"
+	TemplateMagicLink       = "magic-link"
+)
+
 type Service struct {
 	app    *contracts.App
 	repo contracts.EmailRepository
@@ -41,7 +51,13 @@ func Init(app *contracts.App) contracts.EmailService {
 	}
 }
 
+// SendEmail sends the account activation template.
 func (s *Service) SendEmail(to string, variables map[string]interface{}) error {
+	return s.SendTemplate(to, TemplateVerification, variables)
+}
+
+// SendTemplate sends a Resend template with the given variables, retrying on failure.
+func (s *Service) SendTemplate(to, templateID string, variables map[string]interface{}) error {
 	const (
 		maxRetries = 3
 		timeout    = 10 * time.Second
@@ -56,7 +72,7 @@ func (s *Service) SendEmail(to string, variables map[string]interface{}) error {
 			From:    s.from,
 			To:      []string{to},
 			Template: &resend.EmailTemplate{
-				Id: "aktivasi-akun",
+				Id: templateID,
 				Variables: variables,
 			},
 		}
@@ -68,6 +84,7 @@ func (s *Service) SendEmail(to string, variables map[string]interface{}) error {
 		if err == nil {
 			s.app.Logger.Info().
 				Str("email", to).
+				Str("template", templateID).
 				Str("resend_id", resp.Id).
 				Int("attempt", attempt).
 				Msg("email_sent")
@@ -5,6 +5,7 @@ import (
 
 	"finlog-api/api/contracts"
 	"finlog-api/api/entities"
+	"finlog-api/api/services/changelog"
 
 	"github.com/jmoiron/sqlx"
 )
@@ -15,8 +16,13 @@ const (
 		VALUES (?, ?)
 	`
 	insertTransactionSQL = `
-		INSERT INTO transactions (user_id, category_id, payload_ciphertext, payload_nonce, payload_tag, occurred_at, is_expense, batch_id)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO transactions (uuid, user_id, category_id, payload_ciphertext, payload_nonce, payload_tag, occurred_at, is_expense, batch_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	selectExistingUUIDsSQL = `
+		SELECT uuid, deleted_at IS NOT NULL AS trashed
+		FROM transactions
+		WHERE user_id = ? AND uuid IN (?)
 	`
 	listImportBatchesSQL = `
 		SELECT id, user_id, batch_size, created_at
@@ -30,6 +36,12 @@ const (
 		WHERE id = ? AND user_id = ?
 		LIMIT 1
 	`
+	selectBatchTransactionIDsSQL = `
+		SELECT id
+		FROM transactions
+		WHERE user_id = ? AND batch_id = ?
+		FOR UPDATE
+	`
 	deleteTransactionsByBatchSQL = `
 		DELETE FROM transactions
 		WHERE user_id = ? AND batch_id = ?
@@ -54,28 +66,41 @@ func (r *repository) InsertBatch(
 	ctx context.Context,
 	userID int64,
 	items []entities.ImportedTransaction,
-) error {
+) (int, error) {
 	tx, err := r.writer.BeginTxx(ctx, nil)
 	if err != nil {
-		return err
+		return 0, err
+	}
+
+	items, err = r.withoutExisting(ctx, tx, userID, items)
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, err
+	}
+	if len(items) == 0 {
+		// Everything was imported before; an empty batch would only clutter the history.
+		_ = tx.Rollback()
+		return 0, nil
 	}
 
 	result, err := tx.ExecContext(ctx, insertImportBatchSQL, userID, len(items))
 	if err != nil {
 		_ = tx.Rollback()
-		return err
+		return 0, err
 	}
 
 	batchID, err := result.LastInsertId()
 	if err != nil {
 		_ = tx.Rollback()
-		return err
+		return 0, err
 	}
 
+	ids := make([]int64, 0, len(items))
 	for _, item := range items {
-		if _, err := tx.ExecContext(
+		result, err := tx.ExecContext(
 			ctx,
 			insertTransactionSQL,
+			item.UUID,
 			userID,
 			item.CategoryID,
 			item.Ciphertext,
@@ -84,17 +109,73 @@ func (r *repository) InsertBatch(
 			item.OccurredAt,
 			item.IsExpense,
 			batchID,
-		); err != nil {
+		)
+		if err != nil {
+			_ = tx.Rollback()
+			return 0, err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
 			_ = tx.Rollback()
-			return err
+			return 0, err
 		}
+		ids = append(ids, id)
+	}
+
+	if err := changelog.Record(ctx, tx, userID, changelog.EntityTransaction, changelog.OpUpsert, ids...); err != nil {
+		_ = tx.Rollback()
+		return 0, err
 	}
 
 	if err := tx.Commit(); err != nil {
-		return err
+		return 0, err
 	}
 
-	return nil
+	return len(items), nil
+}
+
+// withoutExisting drops the items whose UUID the user already has, and fails with
+// ErrTrashedTransaction when one of them is in the trash: skipping it would
+// report the item as imported while it stays invisible.
+func (r *repository) withoutExisting(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	userID int64,
+	items []entities.ImportedTransaction,
+) ([]entities.ImportedTransaction, error) {
+	uuids := make([]string, 0, len(items))
+	for _, item := range items {
+		uuids = append(uuids, item.UUID)
+	}
+	query, args, err := sqlx.In(selectExistingUUIDsSQL, userID, uuids)
+	if err != nil {
+		return nil, err
+	}
+	var existing []struct {
+		UUID    string `db:"uuid"`
+		Trashed bool   `db:"trashed"`
+	}
+	if err := tx.SelectContext(ctx, &existing, tx.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+	if len(existing) == 0 {
+		return items, nil
+	}
+
+	stored := make(map[string]bool, len(existing))
+	for _, row := range existing {
+		if row.Trashed {
+			return nil, ErrTrashedTransaction
+		}
+		stored[row.UUID] = true
+	}
+	fresh := make([]entities.ImportedTransaction, 0, len(items)-len(existing))
+	for _, item := range items {
+		if !stored[item.UUID] {
+			fresh = append(fresh, item)
+		}
+	}
+	return fresh, nil
 }
 
 func (r *repository) ListBatches(
@@ -124,6 +205,12 @@ func (r *repository) DeleteBatch(
 		return 0, err
 	}
 
+	var ids []int64
+	if err := tx.SelectContext(ctx, &ids, selectBatchTransactionIDsSQL, userID, batchID); err != nil {
+		_ = tx.Rollback()
+		return 0, err
+	}
+
 	result, err := tx.ExecContext(ctx, deleteTransactionsByBatchSQL, userID, batchID)
 	if err != nil {
 		_ = tx.Rollback()
@@ -136,6 +223,11 @@ func (r *repository) DeleteBatch(
 		return 0, err
 	}
 
+	if err := changelog.Record(ctx, tx, userID, changelog.EntityTransaction, changelog.OpDelete, ids...); err != nil {
+		_ = tx.Rollback()
+		return 0, err
+	}
+
 	if _, err := tx.ExecContext(ctx, deleteImportBatchSQL, batchID, userID); err != nil {
 		_ = tx.Rollback()
 		return 0, err
@@ -0,0 +1,60 @@
+package importbatch
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"finlog-api/api/datasources/dbtest"
+	"finlog-api/api/entities"
+)
+
+func TestInsertBatchRejectsTrashedUUIDs(t *testing.T) {
+	db := dbtest.Open(t)
+	userID := dbtest.Seed(t, db, 1, 1)[0]
+	repo := &repository{writer: db}
+	ctx := context.Background()
+
+	var categoryID int64
+	if err := db.Get(&categoryID, "SELECT id FROM categories WHERE user_id = ? AND is_expense = 1 LIMIT 1", userID); err != nil {
+		t.Fatalf("find category: %v", err)
+	}
+	item := func(uuid string) entities.ImportedTransaction {
+		return entities.ImportedTransaction{
+			UUID:       uuid,
+			Ciphertext: "c",
+			Nonce:      "n",
+			Tag:        "t",
+			OccurredAt: time.Now().UTC().Truncate(time.Second),
+			IsExpense:  true,
+			CategoryID: categoryID,
+		}
+	}
+	live := item("0b9c3f4e-8d1a-4a63-9f7e-2d8e5c1b7a01")
+	trashed := item("0b9c3f4e-8d1a-4a63-9f7e-2d8e5c1b7a02")
+
+	if inserted, err := repo.InsertBatch(ctx, userID, []entities.ImportedTransaction{live, trashed}); err != nil || inserted != 2 {
+		t.Fatalf("expected 2 inserted, got %d, %v", inserted, err)
+	}
+	if inserted, err := repo.InsertBatch(ctx, userID, []entities.ImportedTransaction{live}); err != nil || inserted != 0 {
+		t.Fatalf("expected a retried live uuid to be skipped, got %d, %v", inserted, err)
+	}
+
+	if _, err := db.Exec("UPDATE transactions SET deleted_at = NOW() WHERE user_id = ? AND uuid = ?", userID, trashed.UUID); err != nil {
+		t.Fatalf("trash transaction: %v", err)
+	}
+	fresh := item("0b9c3f4e-8d1a-4a63-9f7e-2d8e5c1b7a03")
+	if _, err := repo.InsertBatch(ctx, userID, []entities.ImportedTransaction{fresh, trashed}); !errors.Is(err, ErrTrashedTransaction) {
+		t.Fatalf("expected ErrTrashedTransaction, got %v", err)
+	}
+
+	var count int
+	if err := db.Get(&count, "SELECT COUNT(*) FROM transactions WHERE user_id = ?", userID); err != nil {
+		t.Fatalf("count transactions: %v", err)
+	}
+	// The seeded transaction plus the two from the first batch.
+	if count != 3 {
+		t.Fatalf("expected the rejected batch to insert nothing, got %d transactions", count)
+	}
+}
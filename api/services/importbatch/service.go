@@ -12,6 +12,7 @@ import (
 	"finlog-api/api/constants"
 	"finlog-api/api/contracts"
 	"finlog-api/api/entities"
+	"finlog-api/api/helpers"
 	"finlog-api/api/models/request"
 	"finlog-api/api/services/category"
 )
@@ -21,6 +22,7 @@ var (
 	ErrRateLimitExceeded     = errors.New("import rate limit exceeded")
 	ErrUndoRateLimitExceeded = errors.New("undo rate limit exceeded")
 	ErrImportBatchNotFound   = errors.New("import batch not found")
+	ErrTrashedTransaction    = errors.New("import contains transactions that are in the trash")
 )
 
 type Service struct {
@@ -53,7 +55,17 @@ func (s *Service) StoreBatch(ctx context.Context, userID int64, payload request.
 
 	items := make([]entities.ImportedTransaction, 0, len(payload.Items))
 	categoryCache := make(map[int64]*entities.Category)
+	uuids := make(map[string]bool, len(payload.Items))
 	for _, item := range payload.Items {
+		uuid, err := itemUUID(item.UUID)
+		if err != nil {
+			return err
+		}
+		if uuids[uuid] {
+			return ErrInvalidImportInput
+		}
+		uuids[uuid] = true
+
 		if strings.TrimSpace(item.Ciphertext) == "" ||
 			strings.TrimSpace(item.Nonce) == "" ||
 			strings.TrimSpace(item.Tag) == "" {
@@ -93,6 +105,7 @@ func (s *Service) StoreBatch(ctx context.Context, userID int64, payload request.
 		}
 
 		items = append(items, entities.ImportedTransaction{
+			UUID:       uuid,
 			Ciphertext: item.Ciphertext,
 			Nonce:      item.Nonce,
 			Tag:        item.Tag,
@@ -102,18 +115,34 @@ func (s *Service) StoreBatch(ctx context.Context, userID int64, payload request.
 		})
 	}
 
-	if err := s.repo.InsertBatch(ctx, userID, items); err != nil {
+	inserted, err := s.repo.InsertBatch(ctx, userID, items)
+	if err != nil {
 		return err
 	}
 
 	s.app.Logger.Info().
 		Int64("user_id", userID).
-		Int("batch_size", len(items)).
+		Int("batch_size", inserted).
+		Int("skipped", len(items)-inserted).
 		Msg("Import batch stored")
 
 	return nil
 }
 
+// itemUUID validates the client's UUID for an item, or generates one. Items whose
+// UUID is already stored are skipped, so a retried import adds nothing twice; a
+// UUID whose transaction is in the trash fails the batch with ErrTrashedTransaction.
+func itemUUID(value string) (string, error) {
+	if value == "" {
+		return helpers.NewUUID()
+	}
+	uuid, ok := helpers.NormalizeUUID(value)
+	if !ok {
+		return "", ErrInvalidImportInput
+	}
+	return uuid, nil
+}
+
 func (s *Service) ListHistory(ctx context.Context, userID int64) ([]entities.ImportBatch, error) {
 	return s.repo.ListBatches(ctx, userID)
 }
@@ -139,6 +168,7 @@ func (s *Service) UndoBatch(ctx context.Context, userID, batchID int64) (int64,
 		Int64("batch_id", batchID).
 		Int64("deleted_count", deleted).
 		Msg("Import batch undone")
+	s.app.Services.Audit.Record(ctx, userID, constants.AuditImportUndone, constants.AuditSuccess, strconv.FormatInt(batchID, 10))
 
 	return deleted, nil
 }
@@ -2,12 +2,18 @@ package services
 
 import (
 	"finlog-api/api/contracts"
+	"finlog-api/api/services/accesstoken"
+	"finlog-api/api/services/account"
+	"finlog-api/api/services/admin"
+	"finlog-api/api/services/audit"
 	"finlog-api/api/services/auth"
 	"finlog-api/api/services/budget"
 	"finlog-api/api/services/category"
+	"finlog-api/api/services/changelog"
 	"finlog-api/api/services/email"
 	"finlog-api/api/services/importbatch"
 	"finlog-api/api/services/keybackup"
+	"finlog-api/api/services/profile"
 	"finlog-api/api/services/transaction"
 )
 
@@ -20,6 +26,12 @@ func Init(app *contracts.App) *contracts.Services {
 		KeyBackup:    keybackup.Init(app),
 		Import:       importbatch.Init(app),
 		Email:        email.Init(app),
+		Account:      account.Init(app),
+		AccessTokens: accesstoken.Init(app),
+		Audit:        audit.Init(app),
+		Admin:        admin.Init(app),
+		Profile:      profile.Init(app),
+		Sync:         changelog.Init(app),
 	}
 
 	app.Logger.Log().Msg("Initializing Services: Pass")
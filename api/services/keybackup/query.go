@@ -23,6 +23,13 @@ const (
 		VALUES (?, ?, ?, ?)
 	`
 
+	selectActiveKeyIDsQuery = `
+		SELECT id
+		FROM user_encrypted_data_keys
+		WHERE user_id = ? AND is_active = 1
+		FOR UPDATE
+	`
+
 	deactivateActiveKeyQuery = `
 		UPDATE user_encrypted_data_keys
 		SET is_active = 0, rotated_at = ?, deleted_at = ?, updated_at = NOW()
@@ -8,6 +8,7 @@ import (
 
 	"finlog-api/api/contracts"
 	"finlog-api/api/entities"
+	"finlog-api/api/services/changelog"
 )
 
 type repository struct {
@@ -35,15 +36,29 @@ func (r *repository) Insert(ctx context.Context, exec sqlx.ExtContext, key *enti
 	if err != nil {
 		return 0, err
 	}
-	return res.LastInsertId()
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	if err := changelog.Record(ctx, exec, key.UserID, changelog.EntityKeyBackup, changelog.OpUpsert, id); err != nil {
+		return 0, err
+	}
+	return id, nil
 }
 
 func (r *repository) DeactivateActive(ctx context.Context, exec sqlx.ExtContext, userID int64, rotatedAt time.Time) (int64, error) {
+	var ids []int64
+	if err := sqlx.SelectContext(ctx, exec, &ids, selectActiveKeyIDsQuery, userID); err != nil {
+		return 0, err
+	}
 	res, err := exec.ExecContext(ctx, deactivateActiveKeyQuery, rotatedAt, rotatedAt, userID)
 	if err != nil {
 		return 0, err
 	}
 	affected, _ := res.RowsAffected()
+	if err := changelog.Record(ctx, exec, userID, changelog.EntityKeyBackup, changelog.OpDelete, ids...); err != nil {
+		return 0, err
+	}
 	return affected, nil
 }
 
@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"finlog-api/api/constants"
 	"finlog-api/api/contracts"
 	"finlog-api/api/entities"
 )
@@ -70,7 +71,7 @@ func (s *Service) StoreKeyBackup(ctx context.Context, userID int64, encryptedKey
 	}
 	tx = nil
 
-	s.logAction(userID, "backup_created")
+	s.logAction(ctx, userID, constants.AuditKeyBackupCreate)
 	return newKey, nil
 }
 
@@ -126,7 +127,7 @@ func (s *Service) RotateKey(ctx context.Context, userID int64, encryptedKey, sal
 	}
 	tx = nil
 
-	s.logAction(userID, "backup_rotated")
+	s.logAction(ctx, userID, constants.AuditKeyBackupRotate)
 	return newKey, nil
 }
 
@@ -156,14 +157,11 @@ func (s *Service) GetKeyStatus(ctx context.Context, userID int64) (*contracts.Ke
 	}, nil
 }
 
-func (s *Service) logAction(userID int64, action string) {
-	if s.app == nil || s.app.Logger == nil {
+func (s *Service) logAction(ctx context.Context, userID int64, action string) {
+	if s.app == nil || s.app.Services == nil {
 		return
 	}
-	s.app.Logger.Info().
-		Int64("user_id", userID).
-		Str("action", action).
-		Msg("key backup event")
+	s.app.Services.Audit.Record(ctx, userID, action, constants.AuditSuccess, "")
 }
 
 func validatePayload(encryptedKey, salt string) error {
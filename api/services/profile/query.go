@@ -0,0 +1,30 @@
+package profile
+
+const (
+	findUserQuery = `
+		SELECT * FROM users WHERE id = ? LIMIT 1
+	`
+
+	findPreferencesQuery = `
+		SELECT user_id, locale, currency, timezone, first_day_of_week, cycle_start_day, created_at, updated_at
+		FROM user_preferences
+		WHERE user_id = ?
+		LIMIT 1
+	`
+
+	upsertPreferencesQuery = `
+		INSERT INTO user_preferences (user_id, locale, currency, timezone, first_day_of_week, cycle_start_day, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			locale = COALESCE(?, locale),
+			currency = COALESCE(?, currency),
+			timezone = COALESCE(?, timezone),
+			first_day_of_week = COALESCE(?, first_day_of_week),
+			cycle_start_day = COALESCE(?, cycle_start_day),
+			updated_at = VALUES(updated_at)
+	`
+
+	updateNameQuery = `
+		UPDATE users SET name = ? WHERE id = ?
+	`
+)
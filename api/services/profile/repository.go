@@ -0,0 +1,80 @@
+package profile
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+
+	"finlog-api/api/contracts"
+	"finlog-api/api/entities"
+)
+
+type repository struct {
+	reader *sqlx.DB
+	writer *sqlx.DB
+}
+
+func initRepository(app *contracts.App) contracts.ProfileRepository {
+	return &repository{
+		reader: app.Ds.ReaderDB,
+		writer: app.Ds.WriterDB,
+	}
+}
+
+func (r *repository) FindUser(ctx context.Context, userID int64) (*entities.User, error) {
+	user := new(entities.User)
+	if err := r.reader.GetContext(ctx, user, findUserQuery, userID); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (r *repository) FindPreferences(ctx context.Context, userID int64) (*entities.UserPreferences, error) {
+	prefs := new(entities.UserPreferences)
+	if err := r.reader.GetContext(ctx, prefs, findPreferencesQuery, userID); err != nil {
+		return nil, err
+	}
+	return prefs, nil
+}
+
+// SaveProfile only overwrites the columns in changes, so concurrent updates of
+// different fields both land. The result is read back inside the transaction.
+func (r *repository) SaveProfile(ctx context.Context, userID int64, name *string, prefs *entities.UserPreferences, changes contracts.PreferenceChanges) (*entities.UserPreferences, error) {
+	tx, err := r.writer.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if name != nil {
+		if _, err := tx.ExecContext(ctx, updateNameQuery, *name, userID); err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, upsertPreferencesQuery,
+		userID,
+		prefs.Locale,
+		prefs.Currency,
+		prefs.Timezone,
+		prefs.FirstDayOfWeek,
+		prefs.CycleStartDay,
+		prefs.CreatedAt,
+		prefs.UpdatedAt,
+		changes.Locale,
+		changes.Currency,
+		changes.Timezone,
+		changes.FirstDayOfWeek,
+		changes.CycleStartDay,
+	); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	stored := new(entities.UserPreferences)
+	if err := tx.GetContext(ctx, stored, findPreferencesQuery, userID); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
@@ -0,0 +1,185 @@
+package profile
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"finlog-api/api/contracts"
+	"finlog-api/api/entities"
+	"finlog-api/api/models/request"
+	"finlog-api/api/period"
+)
+
+const (
+	maxNameLength = 100
+
+	defaultLocale         = "id"
+	defaultCurrency       = "IDR"
+	defaultTimezone       = "Asia/Jakarta"
+	defaultFirstDayOfWeek = int(time.Monday)
+	defaultCycleStartDay  = 1
+)
+
+var (
+	supportedLocales = []string{"id", "en"}
+	currencyPattern  = regexp.MustCompile(`^[A-Z]{3}$`)
+)
+
+var (
+	errUserNotFound          = errors.New("user not found")
+	errInvalidName           = errors.New("name must be 1-100 printable characters")
+	errInvalidLocale         = errors.New("locale must be one of: id, en")
+	errInvalidCurrency       = errors.New("currency must be a three-letter ISO 4217 code")
+	errInvalidTimezone       = errors.New("timezone must be an IANA time zone such as Asia/Jakarta")
+	errInvalidFirstDayOfWeek = errors.New("first_day_of_week must be between 0 (Sunday) and 6 (Saturday)")
+	errInvalidCycleStartDay  = errors.New("cycle_start_day must be between 1 and 31")
+)
+
+func ErrUserNotFound() error          { return errUserNotFound }
+func ErrInvalidName() error           { return errInvalidName }
+func ErrInvalidLocale() error         { return errInvalidLocale }
+func ErrInvalidCurrency() error       { return errInvalidCurrency }
+func ErrInvalidTimezone() error       { return errInvalidTimezone }
+func ErrInvalidFirstDayOfWeek() error { return errInvalidFirstDayOfWeek }
+func ErrInvalidCycleStartDay() error  { return errInvalidCycleStartDay }
+
+type Service struct {
+	app  *contracts.App
+	repo contracts.ProfileRepository
+}
+
+func Init(app *contracts.App) contracts.ProfileService {
+	return &Service{
+		app:  app,
+		repo: initRepository(app),
+	}
+}
+
+func (s *Service) Get(ctx context.Context, userID int64) (*entities.Profile, error) {
+	user, err := s.repo.FindUser(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errUserNotFound
+		}
+		return nil, err
+	}
+	prefs, err := s.Preferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &entities.Profile{User: *user, Preferences: prefs}, nil
+}
+
+// Preferences returns the stored preferences, or the defaults for users who never
+// changed them.
+func (s *Service) Preferences(ctx context.Context, userID int64) (*entities.UserPreferences, error) {
+	prefs, err := s.repo.FindPreferences(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return defaultPreferences(userID), nil
+		}
+		return nil, err
+	}
+	return prefs, nil
+}
+
+// Update validates every field present in body before saving any of them. Only
+// the fields present are written, so a concurrent update of other fields stays.
+func (s *Service) Update(ctx context.Context, userID int64, body request.UpdateProfile) (*entities.Profile, error) {
+	profile, err := s.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	prefs := profile.Preferences
+	var changes contracts.PreferenceChanges
+
+	var name *string
+	if body.Name != nil {
+		trimmed := strings.TrimSpace(*body.Name)
+		if !validName(trimmed) {
+			return nil, errInvalidName
+		}
+		name = &trimmed
+		profile.Name = trimmed
+	}
+	if body.Locale != nil {
+		locale := strings.ToLower(strings.TrimSpace(*body.Locale))
+		if !slices.Contains(supportedLocales, locale) {
+			return nil, errInvalidLocale
+		}
+		prefs.Locale = locale
+		changes.Locale = &locale
+	}
+	if body.Currency != nil {
+		currency := strings.ToUpper(strings.TrimSpace(*body.Currency))
+		if !currencyPattern.MatchString(currency) {
+			return nil, errInvalidCurrency
+		}
+		prefs.Currency = currency
+		changes.Currency = &currency
+	}
+	if body.Timezone != nil {
+		timezone := strings.TrimSpace(*body.Timezone)
+		if _, err := period.LoadLocation(timezone); err != nil {
+			return nil, errInvalidTimezone
+		}
+		prefs.Timezone = timezone
+		changes.Timezone = &timezone
+	}
+	if body.FirstDayOfWeek != nil {
+		if *body.FirstDayOfWeek < int(time.Sunday) || *body.FirstDayOfWeek > int(time.Saturday) {
+			return nil, errInvalidFirstDayOfWeek
+		}
+		prefs.FirstDayOfWeek = *body.FirstDayOfWeek
+		changes.FirstDayOfWeek = body.FirstDayOfWeek
+	}
+	if body.CycleStartDay != nil {
+		if *body.CycleStartDay < 1 || *body.CycleStartDay > 31 {
+			return nil, errInvalidCycleStartDay
+		}
+		prefs.CycleStartDay = *body.CycleStartDay
+		changes.CycleStartDay = body.CycleStartDay
+	}
+
+	now := time.Now().UTC()
+	if prefs.CreatedAt.IsZero() {
+		prefs.CreatedAt = now
+	}
+	prefs.UpdatedAt = now
+	stored, err := s.repo.SaveProfile(ctx, userID, name, prefs, changes)
+	if err != nil {
+		return nil, err
+	}
+	profile.Preferences = stored
+	return profile, nil
+}
+
+func defaultPreferences(userID int64) *entities.UserPreferences {
+	return &entities.UserPreferences{
+		UserID:         userID,
+		Locale:         defaultLocale,
+		Currency:       defaultCurrency,
+		Timezone:       defaultTimezone,
+		FirstDayOfWeek: defaultFirstDayOfWeek,
+		CycleStartDay:  defaultCycleStartDay,
+	}
+}
+
+func validName(name string) bool {
+	length := len([]rune(name))
+	if length == 0 || length > maxNameLength {
+		return false
+	}
+	for _, r := range name {
+		if !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,164 @@
+package profile
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"finlog-api/api/contracts"
+	"finlog-api/api/entities"
+	"finlog-api/api/models/request"
+)
+
+type fakeRepo struct {
+	user  *entities.User
+	prefs *entities.UserPreferences
+	// stale, when set, is what FindPreferences returns instead of prefs, as if
+	// the read ran before a concurrent write.
+	stale *entities.UserPreferences
+	saves int
+}
+
+func (f *fakeRepo) FindUser(ctx context.Context, userID int64) (*entities.User, error) {
+	if f.user == nil || f.user.ID != userID {
+		return nil, sql.ErrNoRows
+	}
+	copied := *f.user
+	return &copied, nil
+}
+
+func (f *fakeRepo) FindPreferences(ctx context.Context, userID int64) (*entities.UserPreferences, error) {
+	if f.stale != nil {
+		copied := *f.stale
+		return &copied, nil
+	}
+	if f.prefs == nil {
+		return nil, sql.ErrNoRows
+	}
+	copied := *f.prefs
+	return &copied, nil
+}
+
+// SaveProfile inserts prefs when nothing is stored and otherwise applies only
+// changes, like the upsert.
+func (f *fakeRepo) SaveProfile(ctx context.Context, userID int64, name *string, prefs *entities.UserPreferences, changes contracts.PreferenceChanges) (*entities.UserPreferences, error) {
+	if name != nil {
+		f.user.Name = *name
+	}
+	f.saves++
+	if f.prefs == nil {
+		copied := *prefs
+		f.prefs = &copied
+	} else {
+		if changes.Locale != nil {
+			f.prefs.Locale = *changes.Locale
+		}
+		if changes.Currency != nil {
+			f.prefs.Currency = *changes.Currency
+		}
+		if changes.Timezone != nil {
+			f.prefs.Timezone = *changes.Timezone
+		}
+		if changes.FirstDayOfWeek != nil {
+			f.prefs.FirstDayOfWeek = *changes.FirstDayOfWeek
+		}
+		if changes.CycleStartDay != nil {
+			f.prefs.CycleStartDay = *changes.CycleStartDay
+		}
+		f.prefs.UpdatedAt = prefs.UpdatedAt
+	}
+	copied := *f.prefs
+	return &copied, nil
+}
+
+func newTestService() *Service {
+	return &Service{
+		app:  &contracts.App{},
+		repo: &fakeRepo{user: &entities.User{ID: 1, Email: "budi@example.com", Name: "budi"}},
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
+
+func TestPreferencesDefaultUntilSaved(t *testing.T) {
+	svc := newTestService()
+	ctx := context.Background()
+
+	me, err := svc.Get(ctx, 1)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if me.Preferences.Locale != "id" || me.Preferences.Currency != "IDR" || me.Preferences.Timezone != "Asia/Jakarta" ||
+		me.Preferences.FirstDayOfWeek != 1 || me.Preferences.CycleStartDay != 1 {
+		t.Fatalf("expected default preferences, got %+v", me.Preferences)
+	}
+
+	me, err = svc.Update(ctx, 1, request.UpdateProfile{
+		Name:          ptr("  Budi Santoso "),
+		Currency:      ptr("usd"),
+		Timezone:      ptr("America/New_York"),
+		CycleStartDay: ptr(25),
+	})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if me.Name != "Budi Santoso" || me.Preferences.Currency != "USD" || me.Preferences.CycleStartDay != 25 || me.Preferences.Locale != "id" {
+		t.Fatalf("unexpected profile %+v %+v", me.User, me.Preferences)
+	}
+
+	stored, err := svc.Preferences(ctx, 1)
+	if err != nil {
+		t.Fatalf("preferences: %v", err)
+	}
+	if stored.Timezone != "America/New_York" || stored.CreatedAt.IsZero() {
+		t.Fatalf("expected saved preferences, got %+v", stored)
+	}
+}
+
+func TestConcurrentUpdatesKeepEachOthersFields(t *testing.T) {
+	svc := newTestService()
+	repo := svc.repo.(*fakeRepo)
+	ctx := context.Background()
+	// Another request changes the locale after this one read the preferences.
+	stored := defaultPreferences(1)
+	stale := *stored
+	stored.Locale = "en"
+	repo.prefs = stored
+	repo.stale = &stale
+
+	me, err := svc.Update(ctx, 1, request.UpdateProfile{Currency: ptr("USD")})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if repo.prefs.Locale != "en" || repo.prefs.Currency != "USD" {
+		t.Fatalf("expected both changes to be stored, got %+v", repo.prefs)
+	}
+	if me.Preferences.Locale != "en" || me.Preferences.Currency != "USD" {
+		t.Fatalf("expected the stored preferences back, got %+v", me.Preferences)
+	}
+}
+
+func TestUpdateRejectsInvalidFieldsWithoutSaving(t *testing.T) {
+	cases := []struct {
+		body request.UpdateProfile
+		want error
+	}{
+		{request.UpdateProfile{Name: ptr("   ")}, errInvalidName},
+		{request.UpdateProfile{Locale: ptr("fr")}, errInvalidLocale},
+		{request.UpdateProfile{Currency: ptr("RP")}, errInvalidCurrency},
+		{request.UpdateProfile{Timezone: ptr("Local")}, errInvalidTimezone},
+		{request.UpdateProfile{Timezone: ptr("Mars/Olympus")}, errInvalidTimezone},
+		{request.UpdateProfile{FirstDayOfWeek: ptr(7)}, errInvalidFirstDayOfWeek},
+		{request.UpdateProfile{Locale: ptr("en"), CycleStartDay: ptr(0)}, errInvalidCycleStartDay},
+	}
+	for _, tc := range cases {
+		svc := newTestService()
+		if _, err := svc.Update(context.Background(), 1, tc.body); !errors.Is(err, tc.want) {
+			t.Errorf("expected %v, got %v", tc.want, err)
+		}
+		if saves := svc.repo.(*fakeRepo).saves; saves != 0 {
+			t.Errorf("expected nothing saved after %v", tc.want)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package transaction
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
+	"finlog-api/api/contracts"
+)
+
+// encodeCursor hides the keyset position from clients, who only pass it back.
+func encodeCursor(cursor contracts.TransactionCursor) string {
+	raw := strconv.FormatInt(cursor.OccurredAt.UnixNano(), 10) + ":" + strconv.FormatInt(cursor.ID, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(value string) (*contracts.TransactionCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	nanos, id, found := strings.Cut(string(raw), ":")
+	if !found {
+		return nil, errInvalidCursor
+	}
+	unixNano, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	txID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil || txID <= 0 {
+		return nil, errInvalidCursor
+	}
+	return &contracts.TransactionCursor{OccurredAt: time.Unix(0, unixNano).UTC(), ID: txID}, nil
+}
+
+func pageSize(limit int) int {
+	if limit <= 0 {
+		return defaultPageSize
+	}
+	return min(limit, maxPageSize)
+}
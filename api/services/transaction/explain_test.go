@@ -0,0 +1,88 @@
+package transaction
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"finlog-api/api/contracts"
+	"finlog-api/api/datasources/dbtest"
+	"finlog-api/api/period"
+)
+
+const (
+	seedUsers   = 20
+	seedPerUser = 1000
+
+	// transactionsByUser stays next to transactionsByUserUpdated, which shares its
+	// prefix: InnoDB appends the primary key to it, so it returns rows already in
+	// (occurred_at, id) order and pages stop after LIMIT rows without a filesort.
+	transactionsByUser        = "idx_transactions_user_date"
+	transactionsByUserUpdated = "idx_transactions_user_date_updated"
+	transactionsByType        = "idx_transactions_user_type_date"
+	transactionsByCategory    = "idx_transactions_user_category_date"
+)
+
+type plannedQuery struct {
+	name  string
+	query string
+	args  []interface{}
+	keys  []string
+	// ordered queries take LIMIT rows and must be read in index order.
+	ordered bool
+}
+
+// TestTransactionQueriesUseIndexes guards the hot paths against regressing to
+// full scans, such as by wrapping occurred_at in a function again.
+func TestTransactionQueriesUseIndexes(t *testing.T) {
+	db := dbtest.Open(t)
+	userID := dbtest.Seed(t, db, seedUsers, seedPerUser)[seedUsers/2]
+
+	var categoryID int64
+	if err := db.Get(&categoryID, "SELECT id FROM categories WHERE user_id = ? LIMIT 1", userID); err != nil {
+		t.Fatalf("find category: %v", err)
+	}
+	jakarta, _ := period.LoadLocation("Asia/Jakarta")
+	now := time.Now()
+	month := period.Month(now.Year(), now.Month(), jakarta)
+	isExpense := true
+
+	cases := []plannedQuery{
+		{"month", listTransactions, []interface{}{userID, month.Start, month.End}, []string{transactionsByUser, transactionsByUserUpdated}, false},
+		{"recent", listTransactions + " LIMIT ?", []interface{}{userID, month.Start, month.End, defaultRecentLimit}, []string{transactionsByUser}, true},
+	}
+	for _, search := range []struct {
+		name   string
+		filter contracts.TransactionFilter
+		after  *contracts.TransactionCursor
+		keys   []string
+	}{
+		{"search", contracts.TransactionFilter{}, nil, []string{transactionsByUser}},
+		{"search range", contracts.TransactionFilter{From: &month.Start, To: &month.End}, nil, []string{transactionsByUser}},
+		{"search category", contracts.TransactionFilter{CategoryID: &categoryID}, nil, []string{transactionsByCategory}},
+		{"search type", contracts.TransactionFilter{IsExpense: &isExpense}, nil, []string{transactionsByType, transactionsByUser}},
+		{"search cursor", contracts.TransactionFilter{}, &contracts.TransactionCursor{OccurredAt: month.Start, ID: 1 << 62}, []string{transactionsByUser}},
+		{"trash", contracts.TransactionFilter{Trashed: true}, nil, []string{transactionsByUser}},
+	} {
+		search.filter.Limit = defaultPageSize + 1
+		query, args := buildSearchQuery(userID, search.filter, search.after)
+		cases = append(cases, plannedQuery{search.name, query, args, search.keys, true})
+	}
+
+	for _, tc := range cases {
+		plan, ok := dbtest.Explain(t, db, tc.query, tc.args...)["t"]
+		if !ok {
+			t.Fatalf("%s: no plan for transactions", tc.name)
+		}
+		if plan.Type == "ALL" || plan.Type == "index" || !slices.Contains(tc.keys, plan.Key) {
+			t.Errorf("%s: expected a %v lookup, got %+v", tc.name, tc.keys, plan)
+		}
+		if tc.ordered && strings.Contains(plan.Extra, "filesort") {
+			t.Errorf("%s: expected rows in index order, got %+v", tc.name, plan)
+		}
+		if plan.Rows > seedPerUser {
+			t.Errorf("%s: expected at most the user's %d rows examined, got %d", tc.name, seedPerUser, plan.Rows)
+		}
+	}
+}
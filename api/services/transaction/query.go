@@ -4,6 +4,7 @@ const (
 	listTransactions = `
 		SELECT 
 			t.id,
+			t.uuid,
 			t.user_id,
 			t.category_id,
 			c.name AS category_name,
@@ -13,16 +14,64 @@ const (
 			t.occurred_at,
 			t.is_expense,
 			t.created_at,
-			t.updated_at
+			t.updated_at,
+			t.version,
+			t.deleted_at
 		FROM transactions t
 		JOIN categories c ON t.category_id = c.id
-		WHERE t.user_id = ? AND YEAR(t.occurred_at) = ? AND MONTH(t.occurred_at) = ?
+		WHERE t.user_id = ? AND t.occurred_at >= ? AND t.occurred_at < ? AND t.deleted_at IS NULL
 		ORDER BY t.occurred_at DESC, t.id DESC
 	`
 
+	// searchTransactions is completed with filters, keyset conditions and the
+	// ordering by repository.Search, which also picks live or trashed rows.
+	searchTransactions = `
+		SELECT 
+			t.id,
+			t.uuid,
+			t.user_id,
+			t.category_id,
+			c.name AS category_name,
+			t.payload_ciphertext,
+			t.payload_nonce,
+			t.payload_tag,
+			t.occurred_at,
+			t.is_expense,
+			t.created_at,
+			t.updated_at,
+			t.version,
+			t.deleted_at
+		FROM transactions t
+		JOIN categories c ON t.category_id = c.id
+		WHERE t.user_id = ?
+	`
+
 	findTransactionByID = `
 		SELECT 
 			t.id,
+			t.uuid,
+			t.user_id,
+			t.category_id,
+			c.name AS category_name,
+			t.payload_ciphertext,
+			t.payload_nonce,
+			t.payload_tag,
+			t.occurred_at,
+			t.is_expense,
+			t.created_at,
+			t.updated_at,
+			t.version,
+			t.deleted_at
+		FROM transactions t
+		JOIN categories c ON t.category_id = c.id
+		WHERE t.id = ? AND t.user_id = ? AND t.deleted_at IS NULL
+		LIMIT 1
+	`
+
+	findTransactionByUUID = `
+		SELECT 
+			t.id,
+			t.uuid,
 			t.user_id,
 			t.category_id,
 			c.name AS category_name,
@@ -32,25 +81,35 @@ const (
 			t.occurred_at,
 			t.is_expense,
 			t.created_at,
-			t.updated_at
+			t.updated_at,
+			t.version,
+			t.deleted_at
 		FROM transactions t
 		JOIN categories c ON t.category_id = c.id
-		WHERE t.id = ? AND t.user_id = ?
+		WHERE t.uuid = ? AND t.user_id = ?
 		LIMIT 1
 	`
 
 	insertTransaction = `
-	INSERT INTO transactions (user_id, category_id, payload_ciphertext, payload_nonce, payload_tag, occurred_at, is_expense, batch_id)
-	VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	INSERT INTO transactions (uuid, user_id, category_id, payload_ciphertext, payload_nonce, payload_tag, occurred_at, is_expense, batch_id)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 `
 
 	updateTransaction = `
 		UPDATE transactions
-		SET payload_ciphertext = ?, payload_nonce = ?, payload_tag = ?, occurred_at = ?, is_expense = ?, category_id = ?, updated_at = NOW()
-		WHERE id = ? AND user_id = ?
+		SET payload_ciphertext = ?, payload_nonce = ?, payload_tag = ?, occurred_at = ?, is_expense = ?, category_id = ?, updated_at = NOW(), version = version + 1
+		WHERE id = ? AND user_id = ? AND version = ? AND deleted_at IS NULL
 	`
 
 	deleteTransaction = `
-		DELETE FROM transactions WHERE id = ? AND user_id = ?
+		UPDATE transactions
+		SET deleted_at = ?, updated_at = NOW(), version = version + 1
+		WHERE id = ? AND user_id = ? AND deleted_at IS NULL
+	`
+
+	purgeTrash = `
+		DELETE FROM transactions
+		WHERE deleted_at IS NOT NULL AND deleted_at < ?
+		LIMIT ?
 	`
 )
@@ -8,6 +8,8 @@ import (
 	"finlog-api/api/contracts"
 	"finlog-api/api/datasources"
 	"finlog-api/api/entities"
+	"finlog-api/api/period"
+	"finlog-api/api/services/changelog"
 
 	"github.com/jmoiron/sqlx"
 )
@@ -16,10 +18,11 @@ type repository struct {
 	reader *sqlx.DB
 	writer *sqlx.DB
 	stmt   struct {
-		findByID *sqlx.Stmt
-		insert   *sqlx.Stmt
-		update   *sqlx.Stmt
-		delete   *sqlx.Stmt
+		findByID   *sqlx.Stmt
+		findByUUID *sqlx.Stmt
+		insert     *sqlx.Stmt
+		update     *sqlx.Stmt
+		delete     *sqlx.Stmt
 	}
 }
 
@@ -28,30 +31,32 @@ func initRepository(app *contracts.App) contracts.TransactionRepository {
 		reader: app.Ds.ReaderDB,
 		writer: app.Ds.WriterDB,
 		stmt: struct {
-			findByID *sqlx.Stmt
-			insert   *sqlx.Stmt
-			update   *sqlx.Stmt
-			delete   *sqlx.Stmt
+			findByID   *sqlx.Stmt
+			findByUUID *sqlx.Stmt
+			insert     *sqlx.Stmt
+			update     *sqlx.Stmt
+			delete     *sqlx.Stmt
 		}{
-			findByID: datasources.Prepare(app.Ds.ReaderDB, findTransactionByID),
-			insert:   datasources.Prepare(app.Ds.WriterDB, insertTransaction),
-			update:   datasources.Prepare(app.Ds.WriterDB, updateTransaction),
-			delete:   datasources.Prepare(app.Ds.WriterDB, deleteTransaction),
+			findByID:   datasources.Prepare(app.Ds.WriterDB, findTransactionByID),
+			findByUUID: datasources.Prepare(app.Ds.WriterDB, findTransactionByUUID),
+			insert:     datasources.Prepare(app.Ds.WriterDB, insertTransaction),
+			update:     datasources.Prepare(app.Ds.WriterDB, updateTransaction),
+			delete:     datasources.Prepare(app.Ds.WriterDB, deleteTransaction),
 		},
 	}
 }
 
-func (r *repository) List(ctx context.Context, userID int64, year int, month int) ([]entities.Transaction, error) {
+func (r *repository) List(ctx context.Context, userID int64, within period.Range) ([]entities.Transaction, error) {
 	var txs []entities.Transaction
-	if err := r.reader.SelectContext(ctx, &txs, listTransactions, userID, year, month); err != nil {
+	if err := r.reader.SelectContext(ctx, &txs, listTransactions, userID, within.Start, within.End); err != nil {
 		return nil, err
 	}
 	return txs, nil
 }
 
-func (r *repository) ListRecent(ctx context.Context, userID int64, year int, month int, limit int) ([]entities.Transaction, error) {
+func (r *repository) ListRecent(ctx context.Context, userID int64, within period.Range, limit int) ([]entities.Transaction, error) {
 	query := listTransactions + " LIMIT ?"
-	args := []interface{}{userID, year, month, limit}
+	args := []interface{}{userID, within.Start, within.End, limit}
 
 	var txs []entities.Transaction
 	if err := r.reader.SelectContext(ctx, &txs, query, args...); err != nil {
@@ -60,6 +65,60 @@ func (r *repository) ListRecent(ctx context.Context, userID int64, year int, mon
 	return txs, nil
 }
 
+// Search returns transactions ordered by (occurred_at, id) descending, starting
+// after the cursor. The ordering is unique, so pages never skip or repeat rows.
+func (r *repository) Search(ctx context.Context, userID int64, filter contracts.TransactionFilter, after *contracts.TransactionCursor) ([]entities.Transaction, error) {
+	query, args := buildSearchQuery(userID, filter, after)
+	txs := []entities.Transaction{}
+	if err := r.reader.SelectContext(ctx, &txs, query, args...); err != nil {
+		return nil, err
+	}
+	return txs, nil
+}
+
+// buildSearchQuery only adds conditions on columns that lead an index together
+// with user_id, so every combination stays an index range scan.
+func buildSearchQuery(userID int64, filter contracts.TransactionFilter, after *contracts.TransactionCursor) (string, []interface{}) {
+	conditions := []string{}
+	args := []interface{}{userID}
+	if filter.From != nil {
+		conditions = append(conditions, "t.occurred_at >= ?")
+		args = append(args, *filter.From)
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "t.occurred_at < ?")
+		args = append(args, *filter.To)
+	}
+	if filter.CategoryID != nil {
+		conditions = append(conditions, "t.category_id = ?")
+		args = append(args, *filter.CategoryID)
+	}
+	if filter.IsExpense != nil {
+		conditions = append(conditions, "t.is_expense = ?")
+		args = append(args, *filter.IsExpense)
+	}
+	if filter.Trashed {
+		conditions = append(conditions, "t.deleted_at IS NOT NULL")
+	} else {
+		conditions = append(conditions, "t.deleted_at IS NULL")
+	}
+	if after != nil {
+		// The leading bound on occurred_at alone keeps the keyset condition a range.
+		conditions = append(conditions, "t.occurred_at <= ? AND (t.occurred_at < ? OR t.id < ?)")
+		args = append(args, after.OccurredAt, after.OccurredAt, after.ID)
+	}
+
+	query := searchTransactions
+	for _, condition := range conditions {
+		query += " AND " + condition
+	}
+	query += " ORDER BY t.occurred_at DESC, t.id DESC LIMIT ?"
+	args = append(args, filter.Limit)
+	return query, args
+}
+
+// FindByID reads from the writer: its version becomes the ETag a client sends
+// back as If-Match, so it must never trail the client's own last write.
 func (r *repository) FindByID(ctx context.Context, id, userID int64) (*entities.Transaction, error) {
 	tx := new(entities.Transaction)
 	if err := r.stmt.findByID.GetContext(ctx, tx, id, userID); err != nil {
@@ -68,34 +127,57 @@ func (r *repository) FindByID(ctx context.Context, id, userID int64) (*entities.
 	return tx, nil
 }
 
+// FindByUUID reads from the writer: a create replay must see a row committed
+// moments ago, which a lagging replica may not have yet.
+func (r *repository) FindByUUID(ctx context.Context, uuid string, userID int64) (*entities.Transaction, error) {
+	tx := new(entities.Transaction)
+	if err := r.stmt.findByUUID.GetContext(ctx, tx, uuid, userID); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
 func (r *repository) Create(ctx context.Context, tx *entities.Transaction) (int64, error) {
-	res, err := r.stmt.insert.ExecContext(
-		ctx,
-		tx.UserID,
-		tx.CategoryID,
-		tx.Ciphertext,
-		tx.Nonce,
-		tx.Tag,
-		tx.OccurredAt,
-		tx.IsExpense,
-		nil,
-	)
+	var id int64
+	err := r.inTx(ctx, func(dbtx *sqlx.Tx) error {
+		res, err := dbtx.StmtxContext(ctx, r.stmt.insert).ExecContext(
+			ctx,
+			tx.UUID,
+			tx.UserID,
+			tx.CategoryID,
+			tx.Ciphertext,
+			tx.Nonce,
+			tx.Tag,
+			tx.OccurredAt,
+			tx.IsExpense,
+			nil,
+		)
+		if err != nil {
+			return err
+		}
+		if id, err = res.LastInsertId(); err != nil {
+			return err
+		}
+		return changelog.Record(ctx, dbtx, tx.UserID, changelog.EntityTransaction, changelog.OpUpsert, id)
+	})
 	if err != nil {
 		return 0, err
 	}
-	return res.LastInsertId()
+	return id, nil
 }
 
 func (r *repository) Update(ctx context.Context, tx *entities.Transaction) error {
-	res, err := r.stmt.update.ExecContext(ctx, tx.Ciphertext, tx.Nonce, tx.Tag, tx.OccurredAt, tx.IsExpense, tx.CategoryID, tx.ID, tx.UserID)
-	if err != nil {
-		return err
-	}
-	affected, _ := res.RowsAffected()
-	if affected == 0 {
-		return sql.ErrNoRows
-	}
-	return nil
+	return r.inTx(ctx, func(dbtx *sqlx.Tx) error {
+		res, err := dbtx.StmtxContext(ctx, r.stmt.update).ExecContext(ctx, tx.Ciphertext, tx.Nonce, tx.Tag, tx.OccurredAt, tx.IsExpense, tx.CategoryID, tx.ID, tx.UserID, tx.Version)
+		if err != nil {
+			return err
+		}
+		affected, _ := res.RowsAffected()
+		if affected == 0 {
+			return sql.ErrNoRows
+		}
+		return changelog.Record(ctx, dbtx, tx.UserID, changelog.EntityTransaction, changelog.OpUpsert, tx.ID)
+	})
 }
 
 func (r *repository) BulkUpdateNotes(ctx context.Context, userID int64, ids []int64, notes string) error {
@@ -111,56 +193,132 @@ func (r *repository) BulkUpdateDate(ctx context.Context, userID int64, ids []int
 }
 
 func (r *repository) Delete(ctx context.Context, userID int64, id int64) error {
-	res, err := r.stmt.delete.ExecContext(ctx, id, userID)
-	if err != nil {
-		return err
-	}
-	affected, _ := res.RowsAffected()
-	if affected == 0 {
-		return sql.ErrNoRows
-	}
-	return nil
+	return r.inTx(ctx, func(dbtx *sqlx.Tx) error {
+		res, err := dbtx.StmtxContext(ctx, r.stmt.delete).ExecContext(ctx, time.Now().UTC(), id, userID)
+		if err != nil {
+			return err
+		}
+		affected, _ := res.RowsAffected()
+		if affected == 0 {
+			return sql.ErrNoRows
+		}
+		return changelog.Record(ctx, dbtx, userID, changelog.EntityTransaction, changelog.OpDelete, id)
+	})
 }
 
 func (r *repository) BulkDelete(ctx context.Context, userID int64, ids []int64) error {
 	if len(ids) == 0 {
 		return sql.ErrNoRows
 	}
-	query, args, err := sqlx.In("DELETE FROM transactions WHERE user_id = ? AND id IN (?)", userID, ids)
-	if err != nil {
-		return err
-	}
-	query = r.writer.Rebind(query)
-	res, err := r.writer.ExecContext(ctx, query, args...)
-	if err != nil {
-		return err
-	}
-	affected, _ := res.RowsAffected()
-	if affected == 0 {
+	return r.inTx(ctx, func(dbtx *sqlx.Tx) error {
+		// Only ids the user owns may reach the change log.
+		owned, err := r.lockOwned(ctx, dbtx, userID, ids, false)
+		if err != nil {
+			return err
+		}
+		query, args, err := sqlx.In(
+			"UPDATE transactions SET deleted_at = ?, updated_at = NOW(), version = version + 1 WHERE user_id = ? AND id IN (?)",
+			time.Now().UTC(), userID, owned,
+		)
+		if err != nil {
+			return err
+		}
+		query = dbtx.Rebind(query)
+		if _, err := dbtx.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+		return changelog.Record(ctx, dbtx, userID, changelog.EntityTransaction, changelog.OpDelete, owned...)
+	})
+}
+
+func (r *repository) execBulk(ctx context.Context, userID int64, ids []int64, setClause string, value interface{}) error {
+	if len(ids) == 0 {
 		return sql.ErrNoRows
 	}
-	return nil
+	return r.inTx(ctx, func(dbtx *sqlx.Tx) error {
+		owned, err := r.lockOwned(ctx, dbtx, userID, ids, false)
+		if err != nil {
+			return err
+		}
+		query := "UPDATE transactions SET " + setClause + ", updated_at = NOW(), version = version + 1 WHERE user_id = ? AND id IN (?)"
+		params := []interface{}{value, userID, owned}
+
+		q, args, err := sqlx.In(query, params...)
+		if err != nil {
+			return err
+		}
+		q = dbtx.Rebind(q)
+		if _, err := dbtx.ExecContext(ctx, q, args...); err != nil {
+			return err
+		}
+		return changelog.Record(ctx, dbtx, userID, changelog.EntityTransaction, changelog.OpUpsert, owned...)
+	})
 }
 
-func (r *repository) execBulk(ctx context.Context, userID int64, ids []int64, setClause string, value interface{}) error {
+func (r *repository) Restore(ctx context.Context, userID int64, ids []int64) error {
 	if len(ids) == 0 {
 		return sql.ErrNoRows
 	}
-	query := "UPDATE transactions SET " + setClause + ", updated_at = NOW() WHERE user_id = ? AND id IN (?)"
-	params := []interface{}{value, userID, ids}
+	return r.inTx(ctx, func(dbtx *sqlx.Tx) error {
+		trashed, err := r.lockOwned(ctx, dbtx, userID, ids, true)
+		if err != nil {
+			return err
+		}
+		query, args, err := sqlx.In(
+			"UPDATE transactions SET deleted_at = NULL, updated_at = NOW(), version = version + 1 WHERE user_id = ? AND id IN (?)",
+			userID, trashed,
+		)
+		if err != nil {
+			return err
+		}
+		if _, err := dbtx.ExecContext(ctx, dbtx.Rebind(query), args...); err != nil {
+			return err
+		}
+		return changelog.Record(ctx, dbtx, userID, changelog.EntityTransaction, changelog.OpUpsert, trashed...)
+	})
+}
+
+// PurgeTrash needs no change log entry; the trashing already recorded a delete.
+func (r *repository) PurgeTrash(ctx context.Context, before time.Time, limit int) (int64, error) {
+	res, err := r.writer.ExecContext(ctx, purgeTrash, before, limit)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
 
-	q, args, err := sqlx.In(query, params...)
+// lockOwned returns the subset of ids that belong to the user and are live, or
+// trashed when asked, locking the rows until the transaction ends. It fails with
+// sql.ErrNoRows when none qualify.
+func (r *repository) lockOwned(ctx context.Context, dbtx *sqlx.Tx, userID int64, ids []int64, trashed bool) ([]int64, error) {
+	state := "deleted_at IS NULL"
+	if trashed {
+		state = "deleted_at IS NOT NULL"
+	}
+	query, args, err := sqlx.In("SELECT id FROM transactions WHERE user_id = ? AND id IN (?) AND "+state+" FOR UPDATE", userID, ids)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	q = r.writer.Rebind(q)
-	res, err := r.writer.ExecContext(ctx, q, args...)
+	var owned []int64
+	if err := dbtx.SelectContext(ctx, &owned, dbtx.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+	if len(owned) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return owned, nil
+}
+
+// inTx runs fn in a database transaction so the write and its change log entry
+// commit together.
+func (r *repository) inTx(ctx context.Context, fn func(dbtx *sqlx.Tx) error) error {
+	dbtx, err := r.writer.BeginTxx(ctx, nil)
 	if err != nil {
 		return err
 	}
-	affected, _ := res.RowsAffected()
-	if affected == 0 {
-		return sql.ErrNoRows
+	if err := fn(dbtx); err != nil {
+		_ = dbtx.Rollback()
+		return err
 	}
-	return nil
+	return dbtx.Commit()
 }
@@ -8,54 +8,122 @@ import (
 	"strings"
 	"time"
 
+	"finlog-api/api/constants"
 	"finlog-api/api/contracts"
+	"finlog-api/api/datasources"
 	"finlog-api/api/entities"
+	"finlog-api/api/helpers"
 	"finlog-api/api/models/request"
+	"finlog-api/api/period"
 	"finlog-api/api/services/category"
 )
 
-const defaultRecentLimit = 10
+const (
+	defaultRecentLimit    = 10
+	defaultPageSize       = 50
+	maxPageSize           = 200
+	defaultTrashRetention = 30 * 24 * time.Hour
+	purgeBatchSize        = 500
+)
 
 var (
 	errTransactionNotFound = errors.New("transaction not found")
 	errInvalidTransaction  = errors.New("invalid transaction input")
 	errCategoryNotFound    = errors.New("category not found")
 	errUnsupportedBulk     = errors.New("bulk update not supported for encrypted payloads")
+	errInvalidCursor       = errors.New("invalid cursor")
+	errInvalidUUID         = errors.New("invalid transaction uuid")
+	errVersionRequired     = errors.New("transaction version required")
+	errTransactionTrashed  = errors.New("transaction is in the trash; restore it instead")
 )
 
+// VersionConflictError is returned by UpdateTransaction when the transaction
+// changed since the version the client sent.
+type VersionConflictError struct {
+	Current *entities.Transaction
+}
+
+func (e *VersionConflictError) Error() string { return "transaction was changed by another request" }
+
 type Service struct {
-	app          *contracts.App
-	txRepo       contracts.TransactionRepository
-	categoryRepo contracts.CategoryRepository
+	app            *contracts.App
+	txRepo         contracts.TransactionRepository
+	categoryRepo   contracts.CategoryRepository
+	trashRetention time.Duration
 }
 
 func Init(app *contracts.App) contracts.TransactionService {
 	return &Service{
-		app:          app,
-		txRepo:       initRepository(app),
-		categoryRepo: category.NewRepository(app),
+		app:            app,
+		txRepo:         initRepository(app),
+		categoryRepo:   category.NewRepository(app),
+		trashRetention: parseTrashRetention(app.Config),
 	}
 }
 
-func (s *Service) GetTransactions(ctx context.Context, userID int64, year int, month int) ([]entities.Transaction, error) {
-	return s.txRepo.List(ctx, userID, year, month)
+func (s *Service) GetTransactions(ctx context.Context, userID int64, within period.Range) ([]entities.Transaction, error) {
+	return s.txRepo.List(ctx, userID, within)
+}
+
+func (s *Service) GetRecentTransactions(ctx context.Context, userID int64, within period.Range) ([]entities.Transaction, error) {
+	return s.txRepo.ListRecent(ctx, userID, within, defaultRecentLimit)
 }
 
-func (s *Service) GetRecentTransactions(ctx context.Context, userID int64, year int, month int) ([]entities.Transaction, error) {
-	return s.txRepo.ListRecent(ctx, userID, year, month, defaultRecentLimit)
+// SearchTransactions returns one page of transactions and the cursor of the next
+// page, which is empty on the last one.
+func (s *Service) SearchTransactions(ctx context.Context, userID int64, filter contracts.TransactionFilter) ([]entities.Transaction, string, error) {
+	var after *contracts.TransactionCursor
+	if filter.Cursor != "" {
+		decoded, err := decodeCursor(filter.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		after = decoded
+	}
+	size := pageSize(filter.Limit)
+	// One extra row tells whether another page follows.
+	filter.Limit = size + 1
+	txs, err := s.txRepo.Search(ctx, userID, filter, after)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(txs) <= size {
+		return txs, "", nil
+	}
+	txs = txs[:size]
+	last := txs[size-1]
+	return txs, encodeCursor(contracts.TransactionCursor{OccurredAt: last.OccurredAt, ID: last.ID}), nil
 }
 
-func (s *Service) CreateTransaction(ctx context.Context, userID int64, input request.CreateTransaction) (*entities.Transaction, error) {
+// CreateTransaction stores a transaction under the client's UUID, or a new one
+// when none is given. Replaying a UUID returns the stored transaction unchanged,
+// so a client can safely retry a create whose response it never received. A
+// replay of a trashed transaction fails with errTransactionTrashed; it is not
+// brought back implicitly.
+func (s *Service) CreateTransaction(ctx context.Context, userID int64, input request.CreateTransaction) (*entities.Transaction, bool, error) {
 	if err := validateTransactionInput(input); err != nil {
-		return nil, err
+		return nil, false, err
+	}
+
+	uuid, err := s.transactionUUID(input.UUID)
+	if err != nil {
+		return nil, false, err
+	}
+	if input.UUID != "" {
+		if existing, err := s.txRepo.FindByUUID(ctx, uuid, userID); err == nil {
+			return replayed(existing)
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return nil, false, err
+		}
 	}
 
 	cat, err := s.resolveCategory(ctx, userID, input.Category, input.IsExpense)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
 	tx := &entities.Transaction{
+		UUID:       uuid,
 		UserID:     userID,
 		CategoryID: cat.ID,
 		Category:   cat.Name,
@@ -67,22 +135,92 @@ func (s *Service) CreateTransaction(ctx context.Context, userID int64, input req
 	}
 	id, err := s.txRepo.Create(ctx, tx)
 	if err != nil {
-		return nil, err
+		// A concurrent retry won the insert.
+		if datasources.IsDuplicateKey(err) {
+			existing, findErr := s.txRepo.FindByUUID(ctx, uuid, userID)
+			if findErr != nil {
+				return nil, false, findErr
+			}
+			return replayed(existing)
+		}
+		return nil, false, err
 	}
 	tx.ID = id
+	tx.Version = 1
+	return tx, true, nil
+}
+
+func replayed(existing *entities.Transaction) (*entities.Transaction, bool, error) {
+	if existing.DeletedAt != nil {
+		return nil, false, errTransactionTrashed
+	}
+	return existing, false, nil
+}
+
+// ResolveTransactionID returns the id of the transaction addressed by ref, which
+// is either its numeric id or its UUID.
+func (s *Service) ResolveTransactionID(ctx context.Context, userID int64, ref string) (int64, error) {
+	if id, err := strconv.ParseInt(ref, 10, 64); err == nil {
+		if id <= 0 {
+			return 0, errInvalidTransaction
+		}
+		return id, nil
+	}
+	uuid, ok := helpers.NormalizeUUID(ref)
+	if !ok {
+		return 0, errInvalidTransaction
+	}
+	tx, err := s.txRepo.FindByUUID(ctx, uuid, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, errTransactionNotFound
+		}
+		return 0, err
+	}
+	return tx.ID, nil
+}
+
+func (s *Service) transactionUUID(value string) (string, error) {
+	if value == "" {
+		return helpers.NewUUID()
+	}
+	uuid, ok := helpers.NormalizeUUID(value)
+	if !ok {
+		return "", errInvalidUUID
+	}
+	return uuid, nil
+}
+
+func (s *Service) GetTransaction(ctx context.Context, userID int64, id int64) (*entities.Transaction, error) {
+	if id <= 0 {
+		return nil, errInvalidTransaction
+	}
+	tx, err := s.txRepo.FindByID(ctx, id, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errTransactionNotFound
+		}
+		return nil, err
+	}
 	return tx, nil
 }
 
-func (s *Service) UpdateTransaction(ctx context.Context, userID int64, id int64, input request.CreateTransaction) error {
+// UpdateTransaction overwrites the transaction only if it is still at
+// input.Version. Otherwise it returns VersionConflictError with the stored copy
+// for the client to merge.
+func (s *Service) UpdateTransaction(ctx context.Context, userID int64, id int64, input request.CreateTransaction) (*entities.Transaction, error) {
 	if id <= 0 {
-		return errInvalidTransaction
+		return nil, errInvalidTransaction
+	}
+	if input.Version <= 0 {
+		return nil, errVersionRequired
 	}
 	if err := validateTransactionInput(input); err != nil {
-		return err
+		return nil, err
 	}
 	cat, err := s.resolveCategory(ctx, userID, input.Category, input.IsExpense)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	tx := &entities.Transaction{
@@ -94,14 +232,20 @@ func (s *Service) UpdateTransaction(ctx context.Context, userID int64, id int64,
 		Tag:        input.Tag,
 		OccurredAt: input.OccurredAt,
 		IsExpense:  input.IsExpense,
+		Version:    input.Version,
 	}
 	if err := s.txRepo.Update(ctx, tx); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return errTransactionNotFound
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
 		}
-		return err
+		// Either the transaction is gone or another write got there first.
+		current, err := s.GetTransaction(ctx, userID, id)
+		if err != nil {
+			return nil, err
+		}
+		return nil, &VersionConflictError{Current: current}
 	}
-	return nil
+	return s.GetTransaction(ctx, userID, id)
 }
 
 func (s *Service) UpdateNotes(ctx context.Context, userID int64, ids []int64, notes string) error {
@@ -142,6 +286,75 @@ func (s *Service) DeleteTransactions(ctx context.Context, userID int64, ids []in
 	return nil
 }
 
+func (s *Service) RestoreTransaction(ctx context.Context, userID int64, id int64) error {
+	if id <= 0 {
+		return errInvalidTransaction
+	}
+	return s.RestoreTransactions(ctx, userID, []int64{id})
+}
+
+// RestoreTransactions takes transactions out of the trash. Ids that are not in
+// the user's trash are ignored unless none are.
+func (s *Service) RestoreTransactions(ctx context.Context, userID int64, ids []int64) error {
+	if len(ids) == 0 {
+		return errInvalidTransaction
+	}
+	if err := s.txRepo.Restore(ctx, userID, ids); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errTransactionNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// PurgeTrash permanently removes transactions trashed longer than the retention.
+// It works in batches so no single statement holds locks for long.
+func (s *Service) PurgeTrash(ctx context.Context) (int64, error) {
+	cutoff := time.Now().UTC().Add(-s.trashRetention)
+	var purged int64
+	for {
+		n, err := s.txRepo.PurgeTrash(ctx, cutoff, purgeBatchSize)
+		purged += n
+		if err != nil || n < purgeBatchSize {
+			return purged, err
+		}
+	}
+}
+
+// RunTrashPurgeWorker purges the trash every interval until ctx is cancelled.
+// Running it on several containers is safe; a row is only deleted once.
+func (s *Service) RunTrashPurgeWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if purged, err := s.PurgeTrash(ctx); err != nil {
+			s.app.Logger.Error().
+				Err(err).
+				Msg("trash_purge_failed")
+		} else if purged > 0 {
+			s.app.Logger.Info().
+				Int64("purged", purged).
+				Msg("trash_purged")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func parseTrashRetention(config map[string]string) time.Duration {
+	if raw := config[constants.TrashRetention]; raw != "" {
+		if parsed, err := time.ParseDuration(raw); err == nil && parsed > 0 {
+			return parsed
+		}
+	}
+	return defaultTrashRetention
+}
+
 func (s *Service) resolveCategory(ctx context.Context, userID int64, identifier string, isExpense bool) (*entities.Category, error) {
 	identifier = strings.TrimSpace(identifier)
 	if identifier == "" {
@@ -187,3 +400,7 @@ func ErrTransactionNotFound() error { return errTransactionNotFound }
 func ErrInvalidTransaction() error  { return errInvalidTransaction }
 func ErrCategoryNotFound() error    { return errCategoryNotFound }
 func ErrUnsupportedBulk() error     { return errUnsupportedBulk }
+func ErrInvalidCursor() error       { return errInvalidCursor }
+func ErrInvalidUUID() error         { return errInvalidUUID }
+func ErrVersionRequired() error     { return errVersionRequired }
+func ErrTransactionTrashed() error  { return errTransactionTrashed }
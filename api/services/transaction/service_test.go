@@ -0,0 +1,339 @@
+package transaction
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+
+	"finlog-api/api/contracts"
+	"finlog-api/api/entities"
+	"finlog-api/api/models/request"
+)
+
+// fakeRepo implements lookups, Search, Create and Update over an in-memory
+// table; the rest of the repository is not used here.
+type fakeRepo struct {
+	contracts.TransactionRepository
+	txs []entities.Transaction
+	// hideUUIDs makes FindByUUID miss once, as if a concurrent create had not
+	// committed yet when it ran.
+	hideUUIDs int
+}
+
+func (f *fakeRepo) Create(ctx context.Context, tx *entities.Transaction) (int64, error) {
+	for _, stored := range f.txs {
+		if stored.UserID == tx.UserID && stored.UUID == tx.UUID {
+			return 0, &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}
+		}
+	}
+	stored := *tx
+	stored.ID = int64(len(f.txs) + 1)
+	stored.Version = 1
+	f.txs = append(f.txs, stored)
+	return stored.ID, nil
+}
+
+func (f *fakeRepo) FindByUUID(ctx context.Context, uuid string, userID int64) (*entities.Transaction, error) {
+	if f.hideUUIDs > 0 {
+		f.hideUUIDs--
+		return nil, sql.ErrNoRows
+	}
+	for _, stored := range f.txs {
+		if stored.UserID == userID && stored.UUID == uuid {
+			copied := stored
+			return &copied, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeRepo) FindByID(ctx context.Context, id, userID int64) (*entities.Transaction, error) {
+	for _, stored := range f.txs {
+		if stored.UserID == userID && stored.ID == id {
+			copied := stored
+			return &copied, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeRepo) Update(ctx context.Context, tx *entities.Transaction) error {
+	for i, stored := range f.txs {
+		if stored.UserID == tx.UserID && stored.ID == tx.ID && stored.Version == tx.Version {
+			updated := *tx
+			updated.UUID = stored.UUID
+			updated.Version++
+			f.txs[i] = updated
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (f *fakeRepo) Restore(ctx context.Context, userID int64, ids []int64) error {
+	restored := false
+	for i, stored := range f.txs {
+		if stored.UserID == userID && stored.DeletedAt != nil && slices.Contains(ids, stored.ID) {
+			f.txs[i].DeletedAt = nil
+			restored = true
+		}
+	}
+	if !restored {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (f *fakeRepo) PurgeTrash(ctx context.Context, before time.Time, limit int) (int64, error) {
+	var purged int64
+	kept := f.txs[:0]
+	for _, stored := range f.txs {
+		if stored.DeletedAt != nil && stored.DeletedAt.Before(before) && purged < int64(limit) {
+			purged++
+			continue
+		}
+		kept = append(kept, stored)
+	}
+	f.txs = kept
+	return purged, nil
+}
+
+type fakeCategoryRepo struct {
+	contracts.CategoryRepository
+}
+
+func (f *fakeCategoryRepo) FindByName(ctx context.Context, name string, isExpense bool, userID int64) (*entities.Category, error) {
+	return &entities.Category{ID: 1, UserID: userID, Name: name, IsExpense: isExpense}, nil
+}
+
+func (f *fakeRepo) Search(ctx context.Context, userID int64, filter contracts.TransactionFilter, after *contracts.TransactionCursor) ([]entities.Transaction, error) {
+	sorted := slices.Clone(f.txs)
+	slices.SortFunc(sorted, func(a, b entities.Transaction) int {
+		if c := b.OccurredAt.Compare(a.OccurredAt); c != 0 {
+			return c
+		}
+		return int(b.ID - a.ID)
+	})
+	var out []entities.Transaction
+	for _, tx := range sorted {
+		if len(out) == filter.Limit {
+			break
+		}
+		if tx.UserID != userID || (filter.IsExpense != nil && tx.IsExpense != *filter.IsExpense) {
+			continue
+		}
+		if after != nil && !tx.OccurredAt.Before(after.OccurredAt) && !(tx.OccurredAt.Equal(after.OccurredAt) && tx.ID < after.ID) {
+			continue
+		}
+		out = append(out, tx)
+	}
+	return out, nil
+}
+
+func TestSearchTransactionsPagesWithoutGapsOrRepeats(t *testing.T) {
+	base := time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC)
+	repo := &fakeRepo{}
+	for id := int64(1); id <= 7; id++ {
+		// Several transactions share a timestamp, so the id must break ties.
+		repo.txs = append(repo.txs, entities.Transaction{ID: id, UserID: 1, OccurredAt: base.Add(time.Duration(id/3) * time.Hour)})
+	}
+	repo.txs = append(repo.txs, entities.Transaction{ID: 8, UserID: 2, OccurredAt: base})
+	svc := &Service{txRepo: repo}
+
+	var seen []int64
+	cursor := ""
+	for page := 0; ; page++ {
+		txs, next, err := svc.SearchTransactions(context.Background(), 1, contracts.TransactionFilter{Cursor: cursor, Limit: 3})
+		if err != nil {
+			t.Fatalf("page %d: %v", page, err)
+		}
+		for _, tx := range txs {
+			seen = append(seen, tx.ID)
+		}
+		if next == "" {
+			break
+		}
+		if page > 3 {
+			t.Fatalf("expected paging to end")
+		}
+		cursor = next
+	}
+	if want := []int64{7, 6, 5, 4, 3, 2, 1}; !slices.Equal(seen, want) {
+		t.Fatalf("expected %v, got %v", want, seen)
+	}
+}
+
+func TestSearchTransactionsRejectsBadCursor(t *testing.T) {
+	svc := &Service{txRepo: &fakeRepo{}}
+	for _, cursor := range []string{"not base64!", encodeCursor(contracts.TransactionCursor{})[:4], "MTIzNDU2"} {
+		if _, _, err := svc.SearchTransactions(context.Background(), 1, contracts.TransactionFilter{Cursor: cursor}); !errors.Is(err, errInvalidCursor) {
+			t.Errorf("cursor %q: expected errInvalidCursor, got %v", cursor, err)
+		}
+	}
+
+	at := time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC)
+	decoded, err := decodeCursor(encodeCursor(contracts.TransactionCursor{OccurredAt: at, ID: 42}))
+	if err != nil || !decoded.OccurredAt.Equal(at) || decoded.ID != 42 {
+		t.Fatalf("expected the cursor to round-trip, got %+v, %v", decoded, err)
+	}
+}
+
+func TestCreateTransactionReplaysUUID(t *testing.T) {
+	repo := &fakeRepo{}
+	svc := &Service{txRepo: repo, categoryRepo: &fakeCategoryRepo{}}
+	ctx := context.Background()
+	input := request.CreateTransaction{
+		UUID:       "3F2504E0-4F89-41D3-9A0C-0305E82C3301",
+		Ciphertext: "c",
+		Nonce:      "n",
+		Tag:        "t",
+		OccurredAt: time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC),
+		Category:   "Food",
+		IsExpense:  true,
+	}
+
+	first, created, err := svc.CreateTransaction(ctx, 1, input)
+	if err != nil || !created || first.UUID != "3f2504e0-4f89-41d3-9a0c-0305e82c3301" {
+		t.Fatalf("expected a new transaction with a normalised uuid, got %+v, %v, %v", first, created, err)
+	}
+	replay, created, err := svc.CreateTransaction(ctx, 1, input)
+	if err != nil || created || replay.ID != first.ID {
+		t.Fatalf("expected the replay to return transaction %d, got %+v, %v, %v", first.ID, replay, created, err)
+	}
+
+	// The lookup misses but the insert hits the unique key.
+	repo.hideUUIDs = 1
+	raced, created, err := svc.CreateTransaction(ctx, 1, input)
+	if err != nil || created || raced.ID != first.ID {
+		t.Fatalf("expected the racing create to return transaction %d, got %+v, %v, %v", first.ID, raced, created, err)
+	}
+	if len(repo.txs) != 1 {
+		t.Fatalf("expected a single stored transaction, got %d", len(repo.txs))
+	}
+
+	if id, err := svc.ResolveTransactionID(ctx, 1, input.UUID); err != nil || id != first.ID {
+		t.Fatalf("expected the uuid to resolve to %d, got %d, %v", first.ID, id, err)
+	}
+	if _, err := svc.ResolveTransactionID(ctx, 2, input.UUID); !errors.Is(err, errTransactionNotFound) {
+		t.Fatalf("expected another user's uuid to be not found, got %v", err)
+	}
+
+	input.UUID = "not-a-uuid"
+	if _, _, err := svc.CreateTransaction(ctx, 1, input); !errors.Is(err, errInvalidUUID) {
+		t.Fatalf("expected errInvalidUUID, got %v", err)
+	}
+}
+
+func TestCreateTransactionRejectsTrashedUUID(t *testing.T) {
+	deletedAt := time.Date(2024, time.May, 2, 0, 0, 0, 0, time.UTC)
+	repo := &fakeRepo{txs: []entities.Transaction{
+		{ID: 1, UUID: "3f2504e0-4f89-41d3-9a0c-0305e82c3301", UserID: 1, Version: 1, DeletedAt: &deletedAt},
+	}}
+	svc := &Service{txRepo: repo, categoryRepo: &fakeCategoryRepo{}}
+	ctx := context.Background()
+	input := request.CreateTransaction{
+		UUID:       "3f2504e0-4f89-41d3-9a0c-0305e82c3301",
+		Ciphertext: "c",
+		Nonce:      "n",
+		Tag:        "t",
+		OccurredAt: time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC),
+		Category:   "Food",
+		IsExpense:  true,
+	}
+
+	if _, _, err := svc.CreateTransaction(ctx, 1, input); !errors.Is(err, errTransactionTrashed) {
+		t.Fatalf("expected errTransactionTrashed on replay, got %v", err)
+	}
+
+	// The lookup misses but the insert hits the trashed row's unique key.
+	repo.hideUUIDs = 1
+	if _, _, err := svc.CreateTransaction(ctx, 1, input); !errors.Is(err, errTransactionTrashed) {
+		t.Fatalf("expected errTransactionTrashed after the conflict, got %v", err)
+	}
+	if len(repo.txs) != 1 || repo.txs[0].DeletedAt == nil {
+		t.Fatalf("expected the trashed transaction to stay in the trash, got %+v", repo.txs)
+	}
+}
+
+func TestUpdateTransactionDetectsStaleVersion(t *testing.T) {
+	repo := &fakeRepo{}
+	svc := &Service{txRepo: repo, categoryRepo: &fakeCategoryRepo{}}
+	ctx := context.Background()
+	input := request.CreateTransaction{
+		Ciphertext: "c",
+		Nonce:      "n",
+		Tag:        "t",
+		OccurredAt: time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC),
+		Category:   "Food",
+		IsExpense:  true,
+	}
+	created, _, err := svc.CreateTransaction(ctx, 1, input)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	if _, err := svc.UpdateTransaction(ctx, 1, created.ID, input); !errors.Is(err, errVersionRequired) {
+		t.Fatalf("expected errVersionRequired, got %v", err)
+	}
+
+	// Two devices both start from version 1.
+	input.Version = created.Version
+	input.Ciphertext = "first"
+	updated, err := svc.UpdateTransaction(ctx, 1, created.ID, input)
+	if err != nil || updated.Version != 2 || updated.Ciphertext != "first" {
+		t.Fatalf("expected version 2, got %+v, %v", updated, err)
+	}
+
+	input.Ciphertext = "second"
+	var conflict *VersionConflictError
+	if _, err := svc.UpdateTransaction(ctx, 1, created.ID, input); !errors.As(err, &conflict) {
+		t.Fatalf("expected a version conflict, got %v", err)
+	}
+	if conflict.Current.Version != 2 || conflict.Current.Ciphertext != "first" {
+		t.Fatalf("expected the stored copy, got %+v", conflict.Current)
+	}
+
+	if _, err := svc.UpdateTransaction(ctx, 1, created.ID+1, input); !errors.Is(err, errTransactionNotFound) {
+		t.Fatalf("expected errTransactionNotFound, got %v", err)
+	}
+}
+
+func TestTrashIsRestorableUntilPurged(t *testing.T) {
+	now := time.Now().UTC()
+	expired := now.Add(-31 * 24 * time.Hour)
+	recent := now.Add(-time.Hour)
+	repo := &fakeRepo{}
+	for id := int64(1); id <= purgeBatchSize+2; id++ {
+		repo.txs = append(repo.txs, entities.Transaction{ID: id, UserID: 1, DeletedAt: &expired})
+	}
+	repo.txs = append(repo.txs,
+		entities.Transaction{ID: 1000, UserID: 1, DeletedAt: &recent},
+		entities.Transaction{ID: 1001, UserID: 1},
+	)
+	svc := &Service{txRepo: repo, trashRetention: parseTrashRetention(map[string]string{})}
+	ctx := context.Background()
+
+	if err := svc.RestoreTransaction(ctx, 1, 1001); !errors.Is(err, errTransactionNotFound) {
+		t.Fatalf("expected a live transaction not to be restorable, got %v", err)
+	}
+	if err := svc.RestoreTransaction(ctx, 2, 1000); !errors.Is(err, errTransactionNotFound) {
+		t.Fatalf("expected another user's trash not to be restorable, got %v", err)
+	}
+
+	// The purge spans several batches.
+	purged, err := svc.PurgeTrash(ctx)
+	if err != nil || purged != purgeBatchSize+2 {
+		t.Fatalf("expected %d purged, got %d, %v", purgeBatchSize+2, purged, err)
+	}
+	if err := svc.RestoreTransactions(ctx, 1, []int64{1, 1000}); err != nil {
+		t.Fatalf("expected the recent deletion to be restorable, got %v", err)
+	}
+	if len(repo.txs) != 2 || repo.txs[0].DeletedAt != nil {
+		t.Fatalf("expected two live transactions, got %+v", repo.txs)
+	}
+}
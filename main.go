@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -19,6 +20,7 @@ import (
 	"finlog-api/api/datasources"
 	"finlog-api/api/handlers"
 	"finlog-api/api/helpers"
+	"finlog-api/api/jwtkeys"
 	"finlog-api/api/middlewares"
 	"finlog-api/api/routers"
 	"finlog-api/api/services"
@@ -53,9 +55,10 @@ func NewApp() *contracts.App {
 	)
 
 	crs := cors.New(cors.Config{
-		AllowOrigins: "*",
-		AllowMethods: "GET, POST, PUT, DELETE, OPTIONS",
-		AllowHeaders: "Access-Control-Allow-Origin, Accept, content-type, X-Requested-With, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, Screen, X-Forwarded-For, Content-Disposition, X-Content-Lang",
+		AllowOrigins:  "*",
+		AllowMethods:  "GET, POST, PUT, DELETE, OPTIONS",
+		AllowHeaders:  "Access-Control-Allow-Origin, Accept, content-type, X-Requested-With, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, Screen, X-Forwarded-For, Content-Disposition, X-Content-Lang, X-Device-Name, If-Match",
+		ExposeHeaders: "ETag",
 	})
 	fiberApp.Use(crs)
 
@@ -85,6 +88,12 @@ func NewApp() *contracts.App {
 		Logger: &customLogger,
 	}
 
+	keys, err := jwtkeys.Load(app.Config)
+	if err != nil {
+		app.Logger.Panic().Err(err).Msg("Invalid JWT key configuration")
+	}
+	app.JWTKeys = keys
+
 	app.Ds = datasources.Init(app.Config)
 
 	migrator := migrate.NewMigrator(app.Ds.WriterDB.DB, "migrations")
@@ -116,8 +125,24 @@ func main() {
 		return
 	}
 
+	if len(os.Args) > 1 && os.Args[1] == "promote-admin" {
+		if len(os.Args) != 3 {
+			fmt.Fprintln(os.Stderr, "usage: finlog-api promote-admin <email>")
+			os.Exit(2)
+		}
+		app := NewApp()
+		if err := app.Services.Admin.PromoteToAdmin(context.Background(), os.Args[2]); err != nil {
+			app.Logger.Fatal().Err(err).Msg("Promote to admin failed")
+		}
+		fmt.Println("Promoted", os.Args[2], "to admin")
+		return
+	}
+
 	app := NewApp()
 
+	go app.Services.Account.RunPurgeWorker(context.Background(), time.Hour)
+	go app.Services.Transactions.RunTrashPurgeWorker(context.Background(), time.Hour)
+
 	if err := app.Fiber.Listen(":" + app.Config[constants.ServerPort]); err != nil {
 		app.Logger.Fatal().Err(err).Msg("Fiber app error")
 	}
@@ -0,0 +1,4 @@
+ALTER TABLE users
+  ADD COLUMN password_reset_token VARCHAR(128) NULL,
+  ADD COLUMN password_reset_expires_at DATETIME NULL,
+  ADD INDEX idx_users_password_reset_token (password_reset_token);
@@ -0,0 +1,14 @@
+ALTER TABLE users
+  ADD COLUMN mfa_enabled TINYINT(1) NOT NULL DEFAULT 0,
+  ADD COLUMN mfa_secret VARCHAR(64) NULL,
+  ADD COLUMN mfa_last_step BIGINT NULL;
+
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at DATETIME NULL DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uniq_mfa_recovery_codes_user_code (user_id, code_hash),
+    CONSTRAINT fk_mfa_recovery_codes_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB;
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS webauthn_credentials (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    credential_id VARBINARY(255) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    credential TEXT NOT NULL,
+    sign_count INT UNSIGNED NOT NULL DEFAULT 0,
+    last_used_at DATETIME NULL DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uniq_webauthn_credentials_credential_id (credential_id),
+    KEY idx_webauthn_credentials_user (user_id),
+    CONSTRAINT fk_webauthn_credentials_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS webauthn_sessions (
+    id VARCHAR(64) PRIMARY KEY,
+    user_id BIGINT NULL,
+    ceremony VARCHAR(20) NOT NULL,
+    session_data TEXT NOT NULL,
+    expires_at DATETIME NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    KEY idx_webauthn_sessions_expires (expires_at),
+    CONSTRAINT fk_webauthn_sessions_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB;
@@ -0,0 +1,5 @@
+ALTER TABLE users
+  ADD COLUMN deletion_requested_at DATETIME NULL DEFAULT NULL,
+  ADD COLUMN deletion_cancel_token VARCHAR(64) NULL DEFAULT NULL,
+  ADD KEY idx_users_deletion_requested_at (deletion_requested_at),
+  ADD KEY idx_users_deletion_cancel_token (deletion_cancel_token);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS login_throttles (
+    scope VARCHAR(10) NOT NULL,
+    scope_key VARCHAR(64) NOT NULL,
+    failures INT NOT NULL DEFAULT 0,
+    last_failure_at DATETIME NOT NULL,
+    locked_until DATETIME NULL DEFAULT NULL,
+    unlock_token VARCHAR(64) NULL DEFAULT NULL,
+    unlock_expires_at DATETIME NULL DEFAULT NULL,
+    PRIMARY KEY (scope, scope_key),
+    KEY idx_login_throttles_unlock_token (unlock_token)
+) ENGINE=InnoDB;
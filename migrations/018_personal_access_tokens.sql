@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS personal_access_tokens (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    token_prefix VARCHAR(16) NOT NULL,
+    token_hash CHAR(64) NOT NULL,
+    scopes VARCHAR(255) NOT NULL,
+    expires_at DATETIME NULL DEFAULT NULL,
+    last_used_at DATETIME NULL DEFAULT NULL,
+    created_at DATETIME NOT NULL,
+    revoked_at DATETIME NULL DEFAULT NULL,
+    UNIQUE KEY uniq_personal_access_tokens_hash (token_hash),
+    KEY idx_personal_access_tokens_user (user_id, revoked_at),
+    CONSTRAINT fk_personal_access_tokens_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB;
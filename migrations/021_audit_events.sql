@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS audit_events (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NULL DEFAULT NULL,
+    actor_id BIGINT NULL DEFAULT NULL,
+    action VARCHAR(64) NOT NULL,
+    outcome VARCHAR(16) NOT NULL,
+    detail VARCHAR(64) NULL DEFAULT NULL,
+    ip_address VARCHAR(45) NULL DEFAULT NULL,
+    user_agent VARCHAR(255) NULL DEFAULT NULL,
+    created_at DATETIME NOT NULL,
+    KEY idx_audit_events_user (user_id, id),
+    KEY idx_audit_events_action (action, created_at),
+    KEY idx_audit_events_ip (ip_address, created_at)
+) ENGINE=InnoDB;
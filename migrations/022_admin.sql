@@ -0,0 +1,2 @@
+ALTER TABLE users
+  ADD COLUMN disabled_at DATETIME NULL DEFAULT NULL;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS user_preferences (
+    user_id BIGINT PRIMARY KEY,
+    locale VARCHAR(8) NOT NULL DEFAULT 'id',
+    currency CHAR(3) NOT NULL DEFAULT 'IDR',
+    timezone VARCHAR(64) NOT NULL DEFAULT 'Asia/Jakarta',
+    first_day_of_week TINYINT NOT NULL DEFAULT 1,
+    cycle_start_day TINYINT NOT NULL DEFAULT 1,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    CONSTRAINT fk_user_preferences_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB;
@@ -0,0 +1,4 @@
+ALTER TABLE transactions
+    ADD INDEX idx_transactions_user_date_updated (user_id, occurred_at, updated_at),
+    ADD INDEX idx_transactions_user_category_date (user_id, category_id, occurred_at),
+    ADD INDEX idx_transactions_user_type_date (user_id, is_expense, occurred_at);
@@ -0,0 +1,28 @@
+ALTER TABLE users
+    ADD COLUMN change_seq BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS change_log (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    seq BIGINT NOT NULL,
+    entity VARCHAR(32) NOT NULL,
+    entity_id BIGINT NOT NULL,
+    op VARCHAR(16) NOT NULL,
+    created_at DATETIME NOT NULL,
+    UNIQUE KEY uniq_change_log_user_seq (user_id, seq),
+    CONSTRAINT fk_change_log_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB;
+
+INSERT INTO change_log (user_id, seq, entity, entity_id, op, created_at)
+SELECT user_id, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY entity, entity_id), entity, entity_id, 'upsert', UTC_TIMESTAMP()
+FROM (
+    SELECT user_id, 'category' AS entity, id AS entity_id FROM categories WHERE is_active = 1
+    UNION ALL
+    SELECT user_id, 'key_backup', id FROM user_encrypted_data_keys WHERE is_active = 1
+    UNION ALL
+    SELECT user_id, 'transaction', id FROM transactions
+) AS existing;
+
+UPDATE users u
+JOIN (SELECT user_id, MAX(seq) AS seq FROM change_log GROUP BY user_id) c ON c.user_id = u.id
+SET u.change_seq = c.seq, u.updated_at = u.updated_at;
@@ -0,0 +1,8 @@
+ALTER TABLE transactions
+    ADD COLUMN uuid CHAR(36) NULL AFTER id;
+
+UPDATE transactions SET uuid = UUID() WHERE uuid IS NULL;
+
+ALTER TABLE transactions
+    MODIFY COLUMN uuid CHAR(36) NOT NULL DEFAULT (UUID()),
+    ADD UNIQUE KEY uniq_transactions_user_uuid (user_id, uuid);
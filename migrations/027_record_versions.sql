@@ -0,0 +1,5 @@
+ALTER TABLE transactions
+    ADD COLUMN version INT UNSIGNED NOT NULL DEFAULT 1;
+
+ALTER TABLE categories
+    ADD COLUMN version INT UNSIGNED NOT NULL DEFAULT 1;
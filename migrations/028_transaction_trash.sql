@@ -0,0 +1,7 @@
+ALTER TABLE transactions
+    ADD COLUMN deleted_at DATETIME NULL,
+    ADD INDEX idx_transactions_deleted (deleted_at);
+
+ALTER TABLE transactions
+    DROP INDEX idx_transactions_user_date_updated,
+    ADD INDEX idx_transactions_user_date_updated (user_id, occurred_at, deleted_at, updated_at);
@@ -0,0 +1,2 @@
+ALTER TABLE login_throttles
+    ADD COLUMN unlock_user_id BIGINT NULL DEFAULT NULL AFTER unlock_expires_at;
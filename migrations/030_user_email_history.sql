@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS user_email_history (
+    user_id BIGINT NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    recorded_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, email),
+    CONSTRAINT fk_user_email_history_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB;